@@ -2,8 +2,15 @@ package main
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
+	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/Kyutz/aluguel-carros-go/models"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -11,7 +18,9 @@ var db *sql.DB
 
 func SetupDatabase() {
 	var err error
-	db, err = sql.Open("sqlite3", "./aluguel_carros.db")
+	// WAL deixa as leituras longas (exportação em planilha, que percorre a tabela enquanto envia o
+	// arquivo) correrem junto com as escritas; o busy_timeout cobre duas escritas simultâneas
+	db, err = sql.Open("sqlite3", "./aluguel_carros.db?_journal_mode=WAL&_busy_timeout=5000")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -48,7 +57,7 @@ func SetupDatabase() {
         placa TEXT UNIQUE,
         cor TEXT,
         disponibilidade BOOLEAN NOT NULL DEFAULT TRUE, 
-        valor_diaria REAL NOT NULL 
+        valor_diaria INTEGER NOT NULL 
     );`
 
 	createLocacoes := `
@@ -58,7 +67,7 @@ func SetupDatabase() {
         id_carro INTEGER,
         data_inicio DATE,
         data_fim DATE,
-        valor_total REAL,
+        valor_total INTEGER,
         status TEXT,
         FOREIGN KEY (id_cliente) REFERENCES clientes(id_cliente),
         FOREIGN KEY (id_carro) REFERENCES carros(id_carro)
@@ -69,7 +78,7 @@ func SetupDatabase() {
         id_pagamento INTEGER PRIMARY KEY AUTOINCREMENT,
         id_locacao INTEGER,
         data_pagamento DATE,
-        valor_pago REAL,
+        valor_pago INTEGER,
         forma_pagamento TEXT,
         status_pagamento TEXT,
         FOREIGN KEY (id_locacao) REFERENCES locacoes(id_locacao)
@@ -84,12 +93,591 @@ func SetupDatabase() {
     papel TEXT NOT NULL
 );`
 
+	createVerificacoesEmail := `
+    CREATE TABLE IF NOT EXISTS verificacoes_email (
+        token_hash TEXT PRIMARY KEY,
+        usuario TEXT NOT NULL,
+        expira_em DATETIME NOT NULL,
+        usado BOOLEAN NOT NULL DEFAULT FALSE,
+        FOREIGN KEY (usuario) REFERENCES usuarios(usuario)
+    );`
+
+	// Regras avaliadas antes de cada locação; categoria vazia vale para todos os carros
+	createRegrasLocacao := `
+    CREATE TABLE IF NOT EXISTS regras_locacao (
+        id_regra INTEGER PRIMARY KEY AUTOINCREMENT,
+        categoria TEXT NOT NULL DEFAULT '',
+        idade_minima INTEGER NOT NULL DEFAULT 0,
+        tempo_habilitacao_minimo INTEGER NOT NULL DEFAULT 0,
+        idade_condutor_jovem INTEGER NOT NULL DEFAULT 0,
+        sobretaxa_jovem_diaria INTEGER NOT NULL DEFAULT 0,
+        valor_caucao INTEGER NOT NULL DEFAULT 0,
+        ativa BOOLEAN NOT NULL DEFAULT TRUE
+    );`
+
+	createCategorias := `
+    CREATE TABLE IF NOT EXISTS categorias (
+        codigo TEXT PRIMARY KEY,
+        nome TEXT NOT NULL,
+        valor_diaria_base INTEGER NOT NULL
+    );`
+
+	// Tabelas do motor de preços. Categoria vazia vale para todas; a específica tem prioridade.
+	createTarifasSazonais := `
+    CREATE TABLE IF NOT EXISTS tarifas_sazonais (
+        id_tarifa INTEGER PRIMARY KEY AUTOINCREMENT,
+        nome TEXT NOT NULL,
+        categoria TEXT NOT NULL DEFAULT '',
+        data_inicio DATE NOT NULL,
+        data_fim DATE NOT NULL,
+        multiplicador REAL NOT NULL
+    );`
+
+	createFeriados := `
+    CREATE TABLE IF NOT EXISTS feriados (
+        data TEXT PRIMARY KEY,
+        descricao TEXT NOT NULL
+    );`
+
+	createDescontosDuracao := `
+    CREATE TABLE IF NOT EXISTS descontos_duracao (
+        id_desconto INTEGER PRIMARY KEY AUTOINCREMENT,
+        categoria TEXT NOT NULL DEFAULT '',
+        dias_minimos INTEGER NOT NULL,
+        percentual REAL NOT NULL
+    );`
+
+	// estoque NULL = sem limite
+	createExtras := `
+    CREATE TABLE IF NOT EXISTS extras (
+        id_extra INTEGER PRIMARY KEY AUTOINCREMENT,
+        nome TEXT NOT NULL UNIQUE,
+        tipo_cobranca TEXT NOT NULL,
+        preco INTEGER NOT NULL,
+        estoque INTEGER,
+        ativo BOOLEAN NOT NULL DEFAULT TRUE
+    );`
+
+	// Linhas que compõem o saldo da locação (diárias, descontos, sobretaxas, extras...)
+	createLocacaoItens := `
+    CREATE TABLE IF NOT EXISTS locacao_itens (
+        id_item INTEGER PRIMARY KEY AUTOINCREMENT,
+        id_locacao INTEGER NOT NULL,
+        tipo TEXT NOT NULL,
+        id_extra INTEGER,
+        descricao TEXT NOT NULL,
+        quantidade INTEGER NOT NULL DEFAULT 1,
+        valor_unitario INTEGER NOT NULL,
+        valor INTEGER NOT NULL,
+        FOREIGN KEY (id_locacao) REFERENCES locacoes(id_locacao),
+        FOREIGN KEY (id_extra) REFERENCES extras(id_extra)
+    );`
+
+	// Planos de proteção escolhidos na reserva; franquia é o máximo cobrado do cliente por danos
+	createPlanosProtecao := `
+    CREATE TABLE IF NOT EXISTS planos_protecao (
+        id_plano INTEGER PRIMARY KEY AUTOINCREMENT,
+        codigo TEXT NOT NULL UNIQUE,
+        nome TEXT NOT NULL,
+        valor_diaria INTEGER NOT NULL,
+        franquia INTEGER NOT NULL,
+        ativo BOOLEAN NOT NULL DEFAULT TRUE
+    );`
+
+	// Parâmetros operacionais (chave/valor) ajustáveis pelos admins
+	createConfiguracoes := `
+    CREATE TABLE IF NOT EXISTS configuracoes (
+        chave TEXT PRIMARY KEY,
+        valor TEXT NOT NULL
+    );`
+
+	// Leituras de odômetro e combustível (em oitavos) na retirada e na devolução
+	createVistorias := `
+    CREATE TABLE IF NOT EXISTS vistorias (
+        id_vistoria INTEGER PRIMARY KEY AUTOINCREMENT,
+        id_locacao INTEGER NOT NULL,
+        id_carro INTEGER NOT NULL,
+        tipo TEXT NOT NULL,
+        data_hora DATETIME NOT NULL,
+        quilometragem INTEGER NOT NULL,
+        combustivel INTEGER NOT NULL,
+        UNIQUE (id_locacao, tipo),
+        FOREIGN KEY (id_locacao) REFERENCES locacoes(id_locacao),
+        FOREIGN KEY (id_carro) REFERENCES carros(id_carro)
+    );`
+
+	// Manutenção preventiva (por km e/ou meses) e ordens de serviço; ordens abertas bloqueiam o carro
+	createPlanosManutencao := `
+    CREATE TABLE IF NOT EXISTS planos_manutencao (
+        id_plano_manutencao INTEGER PRIMARY KEY AUTOINCREMENT,
+        descricao TEXT NOT NULL UNIQUE,
+        categoria TEXT NOT NULL DEFAULT '',
+        intervalo_km INTEGER NOT NULL DEFAULT 0,
+        intervalo_meses INTEGER NOT NULL DEFAULT 0,
+        ativo BOOLEAN NOT NULL DEFAULT TRUE
+    );`
+
+	createOrdensManutencao := `
+    CREATE TABLE IF NOT EXISTS ordens_manutencao (
+        id_ordem INTEGER PRIMARY KEY AUTOINCREMENT,
+        id_carro INTEGER NOT NULL,
+        id_plano_manutencao INTEGER,
+        descricao TEXT NOT NULL,
+        oficina TEXT NOT NULL DEFAULT '',
+        data_inicio DATE NOT NULL,
+        data_fim DATE NOT NULL,
+        custo INTEGER NOT NULL DEFAULT 0,
+        status TEXT NOT NULL DEFAULT 'agendada',
+        quilometragem INTEGER NOT NULL DEFAULT 0,
+        FOREIGN KEY (id_carro) REFERENCES carros(id_carro),
+        FOREIGN KEY (id_plano_manutencao) REFERENCES planos_manutencao(id_plano_manutencao)
+    );`
+
+	// Avarias encontradas no carro (opcionalmente ligadas à locação em que ocorreram) e suas fotos
+	createAvarias := `
+    CREATE TABLE IF NOT EXISTS avarias (
+        id_avaria INTEGER PRIMARY KEY AUTOINCREMENT,
+        id_carro INTEGER NOT NULL,
+        id_locacao INTEGER,
+        local_veiculo TEXT NOT NULL,
+        gravidade TEXT NOT NULL,
+        descricao TEXT NOT NULL DEFAULT '',
+        estimativa_reparo INTEGER NOT NULL DEFAULT 0,
+        valor_cobrado INTEGER NOT NULL DEFAULT 0,
+        status TEXT NOT NULL DEFAULT 'aberta',
+        registrada_em DATETIME NOT NULL,
+        FOREIGN KEY (id_carro) REFERENCES carros(id_carro),
+        FOREIGN KEY (id_locacao) REFERENCES locacoes(id_locacao)
+    );`
+
+	createAvariaFotos := `
+    CREATE TABLE IF NOT EXISTS avaria_fotos (
+        id_foto INTEGER PRIMARY KEY AUTOINCREMENT,
+        id_avaria INTEGER NOT NULL,
+        url TEXT NOT NULL,
+        enviada_em DATETIME NOT NULL,
+        FOREIGN KEY (id_avaria) REFERENCES avarias(id_avaria)
+    );`
+
+	// Documentos obrigatórios do carro; vale a validade mais recente de cada tipo
+	createDocumentosCarro := `
+    CREATE TABLE IF NOT EXISTS documentos_carro (
+        id_documento INTEGER PRIMARY KEY AUTOINCREMENT,
+        id_carro INTEGER NOT NULL,
+        tipo TEXT NOT NULL,
+        numero TEXT NOT NULL DEFAULT '',
+        validade DATE NOT NULL,
+        arquivo_url TEXT NOT NULL DEFAULT '',
+        enviado_em DATETIME NOT NULL,
+        alertado_em DATETIME,
+        FOREIGN KEY (id_carro) REFERENCES carros(id_carro)
+    );`
+
+	// Filiais (lojas) onde os carros são retirados e devolvidos; horários no formato HH:MM
+	createFiliais := `
+    CREATE TABLE IF NOT EXISTS filiais (
+        id_filial INTEGER PRIMARY KEY AUTOINCREMENT,
+        nome TEXT NOT NULL UNIQUE,
+        endereco TEXT NOT NULL DEFAULT '',
+        cidade TEXT NOT NULL DEFAULT '',
+        uf TEXT NOT NULL DEFAULT '',
+        horario_abertura TEXT NOT NULL DEFAULT '08:00',
+        horario_fechamento TEXT NOT NULL DEFAULT '18:00',
+        ativa BOOLEAN NOT NULL DEFAULT TRUE
+    );`
+
+	// Horários especiais da filial por dia da semana (0 = domingo); sem linha, vale o horário padrão
+	createHorariosFilial := `
+    CREATE TABLE IF NOT EXISTS horarios_filial (
+        id_filial INTEGER NOT NULL,
+        dia_semana INTEGER NOT NULL,
+        abertura TEXT NOT NULL DEFAULT '',
+        fechamento TEXT NOT NULL DEFAULT '',
+        fechado BOOLEAN NOT NULL DEFAULT FALSE,
+        PRIMARY KEY (id_filial, dia_semana),
+        FOREIGN KEY (id_filial) REFERENCES filiais(id_filial)
+    );`
+
+	// Taxa de retorno cobrada quando o carro é devolvido em outra filial (one-way).
+	// Só são aceitas devoluções entre pares de filiais cadastrados aqui.
+	createTaxasRetorno := `
+    CREATE TABLE IF NOT EXISTS taxas_retorno (
+        id_taxa INTEGER PRIMARY KEY AUTOINCREMENT,
+        id_filial_origem INTEGER NOT NULL,
+        id_filial_destino INTEGER NOT NULL,
+        valor INTEGER NOT NULL,
+        UNIQUE (id_filial_origem, id_filial_destino),
+        FOREIGN KEY (id_filial_origem) REFERENCES filiais(id_filial),
+        FOREIGN KEY (id_filial_destino) REFERENCES filiais(id_filial)
+    );`
+
+	// Histórico de mudanças de filial dos carros (transferências e devoluções one-way)
+	createMovimentacoesFrota := `
+    CREATE TABLE IF NOT EXISTS movimentacoes_frota (
+        id_movimentacao INTEGER PRIMARY KEY AUTOINCREMENT,
+        id_carro INTEGER NOT NULL,
+        id_filial_origem INTEGER,
+        id_filial_destino INTEGER NOT NULL,
+        motivo TEXT NOT NULL,
+        id_locacao INTEGER,
+        observacao TEXT NOT NULL DEFAULT '',
+        data_hora DATETIME NOT NULL,
+        FOREIGN KEY (id_carro) REFERENCES carros(id_carro),
+        FOREIGN KEY (id_filial_origem) REFERENCES filiais(id_filial),
+        FOREIGN KEY (id_filial_destino) REFERENCES filiais(id_filial),
+        FOREIGN KEY (id_locacao) REFERENCES locacoes(id_locacao)
+    );`
+
+	// Lista de espera por categoria e período. Quando uma vaga abre, a entrada mais antiga que
+	// couber recebe uma oferta que segura a vaga até oferta_expira_em.
+	createListaEspera := `
+    CREATE TABLE IF NOT EXISTS lista_espera (
+        id_espera INTEGER PRIMARY KEY AUTOINCREMENT,
+        id_cliente INTEGER NOT NULL,
+        categoria TEXT NOT NULL,
+        data_inicio DATETIME NOT NULL,
+        data_fim DATETIME NOT NULL,
+        id_filial_retirada INTEGER NOT NULL,
+        id_filial_devolucao INTEGER NOT NULL,
+        status TEXT NOT NULL DEFAULT 'aguardando',
+        criada_em DATETIME NOT NULL,
+        oferta_expira_em DATETIME,
+        id_locacao INTEGER,
+        FOREIGN KEY (id_cliente) REFERENCES clientes(id_cliente),
+        FOREIGN KEY (id_filial_retirada) REFERENCES filiais(id_filial),
+        FOREIGN KEY (id_filial_devolucao) REFERENCES filiais(id_filial),
+        FOREIGN KEY (id_locacao) REFERENCES locacoes(id_locacao)
+    );`
+
+	// Reservas temporárias (hold) de um carro ou categoria durante o checkout; enquanto
+	// ativas e dentro da validade, ocupam o período como uma locação
+	createReservasTemporarias := `
+    CREATE TABLE IF NOT EXISTS reservas_temporarias (
+        id_reserva INTEGER PRIMARY KEY AUTOINCREMENT,
+        id_cliente INTEGER NOT NULL,
+        id_carro INTEGER,
+        categoria TEXT NOT NULL,
+        data_inicio DATETIME NOT NULL,
+        data_fim DATETIME NOT NULL,
+        id_filial_retirada INTEGER NOT NULL,
+        id_filial_devolucao INTEGER NOT NULL,
+        status TEXT NOT NULL DEFAULT 'ativa',
+        criada_em DATETIME NOT NULL,
+        expira_em DATETIME NOT NULL,
+        id_locacao INTEGER,
+        FOREIGN KEY (id_cliente) REFERENCES clientes(id_cliente),
+        FOREIGN KEY (id_carro) REFERENCES carros(id_carro),
+        FOREIGN KEY (id_filial_retirada) REFERENCES filiais(id_filial),
+        FOREIGN KEY (id_filial_devolucao) REFERENCES filiais(id_filial),
+        FOREIGN KEY (id_locacao) REFERENCES locacoes(id_locacao)
+    );`
+
+	// Aceite do contrato de locação (data e IP) e o PDF da versão aceita
+	createContratos := `
+    CREATE TABLE IF NOT EXISTS contratos (
+        id_locacao INTEGER PRIMARY KEY,
+        aceito_em DATETIME,
+        ip_aceite TEXT NOT NULL DEFAULT '',
+        arquivo TEXT NOT NULL DEFAULT '',
+        hash_sha256 TEXT NOT NULL DEFAULT '',
+        gerado_em DATETIME,
+        FOREIGN KEY (id_locacao) REFERENCES locacoes(id_locacao)
+    );`
+
+	// Todas as versões do PDF do contrato de cada locação; arquivo vazio enquanto a versão é gerada
+	createContratoVersoes := `
+    CREATE TABLE IF NOT EXISTS contrato_versoes (
+        id_locacao INTEGER NOT NULL,
+        versao INTEGER NOT NULL,
+        arquivo TEXT NOT NULL DEFAULT '',
+        hash_sha256 TEXT NOT NULL DEFAULT '',
+        gerado_em DATETIME,
+        PRIMARY KEY (id_locacao, versao),
+        FOREIGN KEY (id_locacao) REFERENCES locacoes(id_locacao)
+    );`
+
+	// Numeração sequencial dos recibos de pagamento
+	createRecibos := `
+    CREATE TABLE IF NOT EXISTS recibos (
+        numero INTEGER PRIMARY KEY AUTOINCREMENT,
+        id_pagamento INTEGER NOT NULL UNIQUE,
+        emitido_em DATETIME NOT NULL,
+        FOREIGN KEY (id_pagamento) REFERENCES pagamentos(id_pagamento)
+    );`
+
+	// Alíquota de ISS e item da lista de serviços por município (código IBGE)
+	createAliquotasISS := `
+    CREATE TABLE IF NOT EXISTS aliquotas_iss (
+        codigo_municipio TEXT PRIMARY KEY,
+        cidade TEXT NOT NULL,
+        uf TEXT NOT NULL,
+        aliquota REAL NOT NULL,
+        item_lista_servico TEXT NOT NULL,
+        codigo_tributacao TEXT NOT NULL DEFAULT ''
+    );`
+
+	// RPS das locações finalizadas (numeração sequencial) e o retorno da NFS-e
+	createNotasFiscais := `
+    CREATE TABLE IF NOT EXISTS notas_fiscais (
+        numero_rps INTEGER PRIMARY KEY AUTOINCREMENT,
+        serie_rps TEXT NOT NULL,
+        id_locacao INTEGER NOT NULL UNIQUE,
+        data_emissao DATETIME NOT NULL,
+        valor_servicos INTEGER NOT NULL,
+        aliquota REAL NOT NULL,
+        valor_iss INTEGER NOT NULL,
+        codigo_municipio TEXT NOT NULL,
+        status TEXT NOT NULL,
+        numero_nfse TEXT NOT NULL DEFAULT '',
+        codigo_verificacao TEXT NOT NULL DEFAULT '',
+        protocolo TEXT NOT NULL DEFAULT '',
+        mensagem TEXT NOT NULL DEFAULT '',
+        arquivo TEXT NOT NULL DEFAULT '',
+        FOREIGN KEY (id_locacao) REFERENCES locacoes(id_locacao)
+    );`
+
 	for _, query := range []string{
 		createClientes, createCarros, createLocacoes, createPagamentos, createUsuarios, createUsuarios,
+		createVerificacoesEmail, createRegrasLocacao, createCategorias,
+		createTarifasSazonais, createFeriados, createDescontosDuracao, createExtras, createLocacaoItens,
+		createPlanosProtecao, createConfiguracoes, createVistorias, createPlanosManutencao, createOrdensManutencao,
+		createAvarias, createAvariaFotos, createDocumentosCarro, createFiliais, createTaxasRetorno, createMovimentacoesFrota,
+		createHorariosFilial, createListaEspera, createReservasTemporarias, createContratos, createContratoVersoes,
+		createRecibos, createAliquotasISS, createNotasFiscais,
 	} {
 		_, err = db.Exec(query)
 		if err != nil {
 			log.Fatal("Erro criando tabela:", err)
 		}
 	}
+
+	createMigracoes := `
+    CREATE TABLE IF NOT EXISTS schema_migracoes (
+        nome TEXT PRIMARY KEY,
+        aplicada_em DATETIME NOT NULL
+    );`
+	if _, err = db.Exec(createMigracoes); err != nil {
+		log.Fatal("Erro criando tabela:", err)
+	}
+
+	// Colunas incluídas depois da criação das tabelas originais.
+	// Usuários já existentes continuam podendo entrar (email_verificado = TRUE).
+	adicionarColuna("usuarios", "email_verificado", "BOOLEAN NOT NULL DEFAULT TRUE")
+	adicionarColuna("clientes", "cpf_cnpj", "TEXT NOT NULL DEFAULT ''")
+	adicionarColuna("clientes", "cnh_numero", "TEXT NOT NULL DEFAULT ''")
+	adicionarColuna("clientes", "cnh_categoria", "TEXT NOT NULL DEFAULT ''")
+	adicionarColuna("clientes", "cnh_validade", "TEXT NOT NULL DEFAULT ''")
+	adicionarColuna("clientes", "data_nascimento", "TEXT NOT NULL DEFAULT ''")
+	adicionarColuna("clientes", "cnh_primeira_habilitacao", "TEXT NOT NULL DEFAULT ''")
+	adicionarColuna("clientes", "bloqueado", "BOOLEAN NOT NULL DEFAULT FALSE")
+	adicionarColuna("clientes", "motivo_bloqueio", "TEXT NOT NULL DEFAULT ''")
+	adicionarColuna("carros", "categoria", "TEXT NOT NULL DEFAULT ''")
+	adicionarColuna("locacoes", "valor_caucao", "INTEGER NOT NULL DEFAULT 0")
+	adicionarColuna("locacoes", "categoria", "TEXT NOT NULL DEFAULT ''")
+	adicionarColuna("categorias", "multiplicador_fim_semana", "REAL NOT NULL DEFAULT 1")
+	adicionarColuna("categorias", "multiplicador_feriado", "REAL NOT NULL DEFAULT 1")
+	adicionarColuna("locacoes", "id_plano", "INTEGER REFERENCES planos_protecao(id_plano)")
+	adicionarColuna("locacoes", "franquia", "INTEGER NOT NULL DEFAULT 0")
+	adicionarColuna("pagamentos", "tipo", "TEXT NOT NULL DEFAULT 'locacao'")
+	adicionarColuna("pagamentos", "referencia_gateway", "TEXT NOT NULL DEFAULT ''")
+	adicionarColuna("carros", "quilometragem", "INTEGER NOT NULL DEFAULT 0")
+	adicionarColuna("carros", "nivel_combustivel", "INTEGER NOT NULL DEFAULT 8")
+	adicionarColuna("carros", "tipo_combustivel", "TEXT NOT NULL DEFAULT 'flex'")
+	adicionarColuna("carros", "capacidade_tanque", "INTEGER NOT NULL DEFAULT 0")
+	adicionarColuna("carros", "transmissao", "TEXT NOT NULL DEFAULT 'manual'")
+	adicionarColuna("carros", "lugares", "INTEGER NOT NULL DEFAULT 5")
+	adicionarColuna("carros", "id_filial", "INTEGER REFERENCES filiais(id_filial)")
+	adicionarColuna("locacoes", "id_filial_retirada", "INTEGER REFERENCES filiais(id_filial)")
+	adicionarColuna("locacoes", "id_filial_devolucao", "INTEGER REFERENCES filiais(id_filial)")
+	adicionarColuna("filiais", "abre_feriados", "BOOLEAN NOT NULL DEFAULT FALSE")
+
+	// Valores monetários passam a ser centavos inteiros (models.Dinheiro)
+	aplicarMigracao("dinheiro_em_centavos",
+		"UPDATE carros SET valor_diaria = CAST(ROUND(valor_diaria * 100) AS INTEGER)",
+		"UPDATE locacoes SET valor_total = CAST(ROUND(valor_total * 100) AS INTEGER), valor_caucao = CAST(ROUND(valor_caucao * 100) AS INTEGER)",
+		"UPDATE pagamentos SET valor_pago = CAST(ROUND(valor_pago * 100) AS INTEGER)",
+		"UPDATE categorias SET valor_diaria_base = CAST(ROUND(valor_diaria_base * 100) AS INTEGER)",
+		"UPDATE regras_locacao SET sobretaxa_jovem_diaria = CAST(ROUND(sobretaxa_jovem_diaria * 100) AS INTEGER), valor_caucao = CAST(ROUND(valor_caucao * 100) AS INTEGER)",
+	)
+
+	// O extra "Seguro total" foi substituído pelo plano de proteção total
+	aplicarMigracao("seguro_total_vira_plano",
+		"UPDATE extras SET ativo = FALSE WHERE nome = 'Seguro total'",
+	)
+
+	// Até aqui havia uma única loja: frota e locações existentes passam para a filial Matriz
+	aplicarMigracao("filial_matriz",
+		"INSERT OR IGNORE INTO filiais (nome) VALUES ('Matriz')",
+		"UPDATE carros SET id_filial = (SELECT id_filial FROM filiais WHERE nome = 'Matriz') WHERE id_filial IS NULL",
+		`UPDATE locacoes SET id_filial_retirada = (SELECT id_filial FROM filiais WHERE nome = 'Matriz'),
+			id_filial_devolucao = (SELECT id_filial FROM filiais WHERE nome = 'Matriz')
+			WHERE id_filial_retirada IS NULL`,
+	)
+
+	// Locações passam de datas inclusivas para instantes (UTC) com fim exclusivo: o período
+	// antigo vira da meia-noite do primeiro dia à meia-noite seguinte ao último, em horário
+	// de Brasília (UTC-3, sem horário de verão desde 2019)
+	aplicarMigracao("locacoes_data_hora",
+		"UPDATE locacoes SET data_inicio = datetime(data_inicio, '+3 hours'), data_fim = datetime(data_fim, '+1 day', '+3 hours')",
+	)
+
+	// Unicidade que CampoDuplicado confere antes do cadastro, para valer também entre dois
+	// cadastros simultâneos. Um banco com repetições antigas segue funcionando sem o índice.
+	for _, indice := range []string{
+		"CREATE UNIQUE INDEX IF NOT EXISTS clientes_email_unico ON clientes (lower(email)) WHERE email <> ''",
+		"CREATE UNIQUE INDEX IF NOT EXISTS clientes_documento_unico ON clientes (documento_identidade) WHERE documento_identidade <> ''",
+		"CREATE UNIQUE INDEX IF NOT EXISTS clientes_cpf_cnpj_unico ON clientes (cpf_cnpj) WHERE cpf_cnpj <> ''",
+	} {
+		if _, err := db.Exec(indice); err != nil {
+			log.Printf("Índice de unicidade de clientes não criado (há cadastros repetidos?): %v", err)
+		}
+	}
+
+	// Fotos de avarias e documentos dos carros saem do diretório público static/uploads para
+	// o armazenamento privado
+	moverParaArquivos("avarias")
+	aplicarMigracao("fotos_avarias_privadas",
+		"UPDATE avaria_fotos SET url = '/arquivos/' || substr(url, length('/static/uploads/') + 1) WHERE url LIKE '/static/uploads/%'",
+	)
+	moverParaArquivos("documentos")
+	aplicarMigracao("documentos_carro_privados",
+		"UPDATE documentos_carro SET arquivo_url = '/arquivos/' || substr(arquivo_url, length('/static/uploads/') + 1) WHERE arquivo_url LIKE '/static/uploads/%'",
+	)
+
+	// O PDF já gravado de cada contrato vira a versão 1; até aqui ele era sobrescrito a cada
+	// mudança na locação, então o hash guardado é o da última geração
+	aplicarMigracao("contratos_versionados",
+		`INSERT OR IGNORE INTO contrato_versoes (id_locacao, versao, arquivo, hash_sha256, gerado_em)
+			SELECT id_locacao, 1, arquivo, hash_sha256, gerado_em FROM contratos WHERE arquivo <> ''`,
+	)
+
+	// Categorias padrão (valores em centavos); admins podem alterar os preços depois
+	_, err = db.Exec(`
+    INSERT OR IGNORE INTO categorias (codigo, nome, valor_diaria_base) VALUES
+        ('economico', 'Econômico', 12000),
+        ('intermediario', 'Intermediário', 16000),
+        ('suv', 'SUV', 25000),
+        ('executivo', 'Executivo', 35000);`)
+	if err != nil {
+		log.Fatal("Erro inserindo categorias padrão:", err)
+	}
+
+	// Extras padrão (preços em centavos)
+	_, err = db.Exec(`
+    INSERT OR IGNORE INTO extras (nome, tipo_cobranca, preco, estoque) VALUES
+        ('GPS', 'diaria', 1500, 10),
+        ('Cadeirinha infantil', 'diaria', 2000, 8),
+        ('Condutor adicional', 'diaria', 2500, NULL),
+        ('Tag de pedágio', 'fixa', 3000, 15);`)
+	if err != nil {
+		log.Fatal("Erro inserindo extras padrão:", err)
+	}
+
+	_, err = db.Exec(`
+    INSERT OR IGNORE INTO planos_protecao (codigo, nome, valor_diaria, franquia) VALUES
+        ('basica', 'Básica', 0, 500000),
+        ('parcial', 'Parcial', 3500, 250000),
+        ('total', 'Total', 6500, 0);`)
+	if err != nil {
+		log.Fatal("Erro inserindo planos de proteção padrão:", err)
+	}
+
+	_, err = db.Exec(`
+    INSERT OR IGNORE INTO planos_manutencao (descricao, intervalo_km, intervalo_meses) VALUES
+        ('Troca de óleo e filtro', 10000, 6),
+        ('Revisão geral', 20000, 12);`)
+	if err != nil {
+		log.Fatal("Erro inserindo planos de manutenção padrão:", err)
+	}
+
+	for chave, valor := range models.ConfiguracoesPadrao {
+		if _, err := db.Exec("INSERT OR IGNORE INTO configuracoes (chave, valor) VALUES (?, ?)", chave, valor); err != nil {
+			log.Fatal("Erro inserindo configurações padrão:", err)
+		}
+	}
+}
+
+// aplicarMigracao executa os comandos uma única vez, numa transação,
+// registrando o nome em schema_migracoes
+func aplicarMigracao(nome string, comandos ...string) {
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migracoes WHERE nome = ?", nome).Scan(&n); err != nil {
+		log.Fatal("Erro verificando migração:", err)
+	}
+	if n > 0 {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal("Erro iniciando migração:", err)
+	}
+	for _, comando := range comandos {
+		if _, err := tx.Exec(comando); err != nil {
+			tx.Rollback()
+			log.Fatalf("Erro aplicando migração %s: %v", nome, err)
+		}
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migracoes (nome, aplicada_em) VALUES (?, ?)", nome, time.Now()); err != nil {
+		tx.Rollback()
+		log.Fatalf("Erro registrando migração %s: %v", nome, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Erro aplicando migração %s: %v", nome, err)
+	}
+	log.Println("Migração aplicada:", nome)
+}
+
+// moverParaArquivos leva static/uploads/<pasta> para arquivos/<pasta>, arquivo a arquivo
+// (as URLs gravadas são corrigidas por migração)
+func moverParaArquivos(pasta string) {
+	origem := filepath.Join("static", "uploads", pasta)
+	err := filepath.WalkDir(origem, func(caminho string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		relativo, err := filepath.Rel(filepath.Join("static", "uploads"), caminho)
+		if err != nil {
+			return err
+		}
+		destino := filepath.Join("arquivos", relativo)
+		if err := os.MkdirAll(filepath.Dir(destino), 0o755); err != nil {
+			return err
+		}
+		return os.Rename(caminho, destino)
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("Erro movendo %s para o armazenamento privado: %v", origem, err)
+	}
+	os.RemoveAll(origem)
+}
+
+// adicionarColuna cria a coluna apenas se ela ainda não existir,
+// para que bancos criados com versões anteriores continuem funcionando
+func adicionarColuna(tabela, coluna, definicao string) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", tabela))
+	if err != nil {
+		log.Fatal("Erro lendo estrutura da tabela:", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			nome, tipo       string
+			padrao           sql.NullString
+		)
+		if err := rows.Scan(&cid, &nome, &tipo, &notNull, &padrao, &pk); err != nil {
+			log.Fatal("Erro lendo estrutura da tabela:", err)
+		}
+		if nome == coluna {
+			return
+		}
+	}
+	rows.Close()
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tabela, coluna, definicao))
+	if err != nil {
+		log.Fatal("Erro adicionando coluna:", err)
+	}
 }
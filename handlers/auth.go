@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/Kyutz/aluguel-carros-go/models"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -49,6 +50,23 @@ func AuthMiddleware(db *sql.DB, allowedRoles []string, next http.HandlerFunc) ht
 	}
 }
 
+// acessoDoCliente confere se a sessão é de um admin ou do próprio cliente
+func acessoDoCliente(db *sql.DB, r *http.Request, idCliente int) bool {
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		return false
+	}
+	papel, err := GetUserRole(db, cookie.Value)
+	if err != nil {
+		return false
+	}
+	if papel == "admin" {
+		return true
+	}
+	cliente, err := models.GetClienteByID(db, idCliente)
+	return err == nil && cliente.Username == cookie.Value
+}
+
 // Funções auxiliares para senhas
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
@@ -79,8 +97,10 @@ func LoginJSONHandler(db *sql.DB) http.HandlerFunc {
 		}
 
 		var hashedPassword string
+		var emailVerificado bool
 		// Corrigindo a consulta SQL para buscar na coluna "senha_hash"
-		err := db.QueryRow("SELECT senha_hash FROM usuarios WHERE usuario = ?", creds.Username).Scan(&hashedPassword)
+		err := db.QueryRow("SELECT senha_hash, email_verificado FROM usuarios WHERE usuario = ?", creds.Username).
+			Scan(&hashedPassword, &emailVerificado)
 		if err != nil {
 			http.Error(w, "Usuário ou senha inválidos", http.StatusUnauthorized)
 			return
@@ -91,6 +111,12 @@ func LoginJSONHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		// Cadastros feitos pelo /registro só entram depois de confirmar o e-mail
+		if !emailVerificado {
+			http.Error(w, "E-mail ainda não verificado", http.StatusForbidden)
+			return
+		}
+
 		// Simula a criação de um cookie de sessão
 		http.SetCookie(w, &http.Cookie{
 			Name:  "session",
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+// Tamanho máximo de um envio de fotos (todas as fotos do formulário juntas)
+const limiteEnvioFotos = 20 << 20
+
+// GET /avarias[?id_carro=1][&id_locacao=2] - avarias registradas, com fotos (admin)
+func ListarAvariasHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		idCarro, _ := strconv.Atoi(r.URL.Query().Get("id_carro"))
+		idLocacao, _ := strconv.Atoi(r.URL.Query().Get("id_locacao"))
+		avarias, err := models.GetAvarias(db, idCarro, idLocacao)
+		if err != nil {
+			log.Printf("Erro ao buscar avarias: %v", err)
+			http.Error(w, "Erro ao buscar avarias", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(avarias)
+	})
+}
+
+// POST /avarias/criar - registrar avaria (admin)
+// Body: {"id_carro": 1, "id_locacao": 2, "local_veiculo": "porta traseira esquerda",
+// "gravidade": "moderada", "descricao": "...", "estimativa_reparo": "850.00"}
+func CriarAvariaHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		var a models.Avaria
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if a.LocalVeiculo == "" || a.EstimativaReparo < 0 {
+			http.Error(w, "Informe o local da avaria no veículo; a estimativa não pode ser negativa", http.StatusBadRequest)
+			return
+		}
+		switch a.Gravidade {
+		case models.GravidadeLeve, models.GravidadeModerada, models.GravidadeGrave:
+		default:
+			http.Error(w, "gravidade deve ser leve, moderada ou grave", http.StatusBadRequest)
+			return
+		}
+		if _, err := models.GetCarroByID(db, a.IDCarro); err != nil {
+			http.Error(w, "Carro não encontrado", http.StatusBadRequest)
+			return
+		}
+		if a.IDLocacao != 0 {
+			l, err := models.GetLocacaoByID(db, a.IDLocacao)
+			if err != nil || l.IDCarro != a.IDCarro {
+				http.Error(w, "Locação não encontrada para este carro", http.StatusBadRequest)
+				return
+			}
+		}
+
+		id, err := models.CreateAvaria(db, a)
+		if err != nil {
+			log.Printf("Erro ao registrar avaria: %v", err)
+			http.Error(w, "Erro ao registrar avaria", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"message": "Avaria registrada", "id_avaria": id})
+	})
+}
+
+// POST /avarias/fotos?id=1 - envia fotos da avaria (admin)
+// multipart/form-data com um ou mais arquivos no campo "fotos" (JPEG, PNG ou WebP)
+func EnviarFotosAvariaHandler(db *sql.DB, armazenamento servicos.Armazenamento) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if _, err := models.GetAvariaByID(db, id); err != nil {
+			http.Error(w, "Avaria não encontrada", http.StatusNotFound)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limiteEnvioFotos)
+		if err := r.ParseMultipartForm(limiteEnvioFotos); err != nil {
+			http.Error(w, "Envio inválido ou acima de 20 MB", http.StatusBadRequest)
+			return
+		}
+		arquivos := r.MultipartForm.File["fotos"]
+		if len(arquivos) == 0 {
+			http.Error(w, "Nenhuma foto enviada no campo 'fotos'", http.StatusBadRequest)
+			return
+		}
+
+		var urls []string
+		for _, cabecalho := range arquivos {
+			url, err := salvarUpload(armazenamento, cabecalho, fmt.Sprintf("avarias/%d", id), extensoesImagem)
+			if err == errFormatoNaoAceito {
+				http.Error(w, "Formato não aceito (use JPEG, PNG ou WebP): "+cabecalho.Filename, http.StatusUnsupportedMediaType)
+				return
+			}
+			if err != nil {
+				log.Printf("Erro ao salvar foto da avaria %d: %v", id, err)
+				http.Error(w, "Erro interno ao salvar foto", http.StatusInternalServerError)
+				return
+			}
+			if err := models.AdicionarFotoAvaria(db, id, url); err != nil {
+				log.Printf("Erro ao registrar foto da avaria %d: %v", id, err)
+				http.Error(w, "Erro interno ao registrar foto", http.StatusInternalServerError)
+				return
+			}
+			urls = append(urls, url)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"fotos": urls})
+	})
+}
+
+// POST /avarias/reparada?id=1 - marca a avaria como reparada (admin)
+func AvariaReparadaHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if err := models.MarcarAvariaReparada(db, id); err != nil {
+			log.Printf("Erro ao atualizar avaria %d: %v", id, err)
+			http.Error(w, "Erro ao atualizar avaria", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// POST /avarias/cobrar?id=1 - cobra a avaria do cliente da locação (admin)
+// Body opcional: {"valor": "600.00", "usar_caucao": true}. Sem valor, usa a estimativa de reparo.
+// A cobrança é limitada pela franquia do plano de proteção, somando as outras avarias da
+// mesma locação, e entra como item da locação (saldo a pagar). Com usar_caucao, o valor é
+// capturado da caução em aberto e o restante dela é liberado.
+func CobrarAvariaHandler(db *sql.DB, gateway servicos.GatewayPagamento) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		var input struct {
+			Valor      *models.Dinheiro `json:"valor"`
+			UsarCaucao bool             `json:"usar_caucao"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+
+		avaria, err := models.GetAvariaByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Avaria não encontrada", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar avaria %d: %v", id, err)
+			http.Error(w, "Erro ao buscar avaria", http.StatusInternalServerError)
+			return
+		}
+		if avaria.IDLocacao == 0 {
+			http.Error(w, "Avaria sem locação vinculada não pode ser cobrada de um cliente", http.StatusConflict)
+			return
+		}
+		if avaria.Status != models.AvariaAberta {
+			http.Error(w, "Avaria já cobrada ou reparada", http.StatusConflict)
+			return
+		}
+		dano := avaria.EstimativaReparo
+		if input.Valor != nil {
+			dano = *input.Valor
+		}
+		if dano < 0 {
+			http.Error(w, "Valor não pode ser negativo", http.StatusBadRequest)
+			return
+		}
+
+		locacao, err := models.GetLocacaoByID(db, avaria.IDLocacao)
+		if err != nil {
+			log.Printf("Erro ao buscar locação %d: %v", avaria.IDLocacao, err)
+			http.Error(w, "Erro ao buscar locação da avaria", http.StatusInternalServerError)
+			return
+		}
+		jaCobrado, err := models.DanosCobrados(db, locacao.ID)
+		if err != nil {
+			log.Printf("Erro ao somar danos da locação %d: %v", locacao.ID, err)
+			http.Error(w, "Erro ao calcular cobrança", http.StatusInternalServerError)
+			return
+		}
+		valor := locacao.ValorCobravelDanos(jaCobrado+dano) - jaCobrado
+		if valor < 0 {
+			valor = 0
+		}
+
+		if err := models.CobrarAvaria(db, avaria, valor); err != nil {
+			log.Printf("Erro ao cobrar avaria %d: %v", id, err)
+			http.Error(w, "Erro ao registrar cobrança da avaria", http.StatusInternalServerError)
+			return
+		}
+
+		resposta := map[string]any{
+			"message":       "Avaria cobrada",
+			"valor_dano":    dano,
+			"valor_cobrado": valor,
+			"franquia":      locacao.Franquia,
+		}
+
+		if input.UsarCaucao && valor > 0 {
+			bloqueio, err := models.GetCaucaoAberta(db, locacao.ID)
+			if err == nil {
+				capturar := min(valor, bloqueio.ValorPago)
+				if err := gateway.Capturar(bloqueio.ReferenciaGateway, capturar); err != nil {
+					log.Printf("Erro ao capturar caução %s: %v", bloqueio.ReferenciaGateway, err)
+					resposta["aviso_caucao"] = "Avaria cobrada no saldo, mas o gateway recusou a captura da caução: " + err.Error()
+				} else if movimentos, err := models.EncerrarCaucao(db, bloqueio, capturar); err != nil {
+					log.Printf("ATENÇÃO: caução %s capturada (%s) mas não registrada: %v", bloqueio.ReferenciaGateway, capturar, err)
+					resposta["aviso_caucao"] = "Caução capturada no gateway, mas houve erro ao registrá-la."
+				} else {
+					resposta["caucao"] = movimentos
+				}
+			} else if err == sql.ErrNoRows {
+				resposta["aviso_caucao"] = "Locação sem caução em aberto; o valor ficou no saldo."
+			} else {
+				log.Printf("Erro ao buscar caução da locação %d: %v", locacao.ID, err)
+				resposta["aviso_caucao"] = "Erro ao buscar caução; o valor ficou no saldo."
+			}
+		}
+
+		if saldo, err := models.SaldoLocacao(db, locacao.ID); err == nil {
+			resposta["saldo"] = saldo
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resposta)
+	})
+}
+
+// GET /arquivos/avarias/{id}/{arquivo} - foto de uma avaria (admin ou cliente da locação)
+func FotoAvariaHandler(db *sql.DB, arquivos servicos.Armazenamento) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		avaria, err := models.GetAvariaByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Avaria não encontrada", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar avaria %d: %v", id, err)
+			http.Error(w, "Erro interno ao buscar avaria", http.StatusInternalServerError)
+			return
+		}
+
+		// Avaria sem locação (encontrada no pátio) só é vista pelos admins
+		idCliente := 0
+		if avaria.IDLocacao != 0 {
+			locacao, err := models.GetLocacaoByID(db, avaria.IDLocacao)
+			if err != nil {
+				log.Printf("Erro ao buscar locação %d da avaria %d: %v", avaria.IDLocacao, id, err)
+				http.Error(w, "Erro interno ao buscar locação", http.StatusInternalServerError)
+				return
+			}
+			idCliente = locacao.IDCliente
+		}
+		if !acessoDoCliente(db, r, idCliente) {
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
+
+		caminho := fmt.Sprintf("avarias/%d/%s", id, r.PathValue("arquivo"))
+		for _, f := range avaria.Fotos {
+			if f.URL == "/arquivos/"+caminho {
+				enviarArquivo(w, arquivos, caminho)
+				return
+			}
+		}
+		http.Error(w, "Foto não encontrada", http.StatusNotFound)
+	})
+}
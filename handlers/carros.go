@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -29,7 +30,13 @@ func CriarCarroHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		var c models.Carro
+		// Valores padrão da ficha técnica, sobrescritos pelo que vier no JSON
+		c := models.Carro{
+			NivelCombustivel: models.TanqueCheio,
+			TipoCombustivel:  "flex",
+			Transmissao:      "manual",
+			Lugares:          5,
+		}
 		err := json.NewDecoder(r.Body).Decode(&c)
 		if err != nil {
 			http.Error(w, "JSON inválido: "+err.Error(), 400)
@@ -37,6 +44,20 @@ func CriarCarroHandler(db *sql.DB) http.HandlerFunc {
 		}
 
 		c.Disponibilidade = true // default
+		if !categoriaExiste(db, w, c.Categoria) || !carroValido(w, c) {
+			return
+		}
+		// Sem filial informada, o carro entra na frota da filial padrão
+		if c.IDFilial == 0 {
+			filial, err := models.GetFilialPadrao(db)
+			if err != nil {
+				http.Error(w, "Nenhuma filial ativa para receber o carro", 400)
+				return
+			}
+			c.IDFilial = filial.ID
+		} else if !filialAtiva(db, w, c.IDFilial) {
+			return
+		}
 
 		err = models.CreateCarro(db, c)
 		if err != nil {
@@ -63,13 +84,25 @@ func AtualizarCarroHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		var c models.Carro
+		// Campos ausentes no JSON mantêm o valor atual (ex.: quilometragem vinda das vistorias)
+		c, err := models.GetCarroByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Carro não encontrado", 404)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Erro ao buscar carro", 500)
+			return
+		}
 		err = json.NewDecoder(r.Body).Decode(&c)
 		if err != nil {
 			http.Error(w, "JSON inválido", 400)
 			return
 		}
 		c.ID = id
+		if !categoriaExiste(db, w, c.Categoria) || !carroValido(w, c) {
+			return
+		}
 
 		err = models.UpdateCarro(db, c)
 		if err != nil {
@@ -101,3 +134,47 @@ func DeletarCarroHandler(db *sql.DB) http.HandlerFunc {
 		w.WriteHeader(http.StatusOK)
 	})
 }
+
+// GET /carros/{id}/historico - leituras de odômetro e combustível de cada retirada e devolução (admin)
+func HistoricoCarroHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", 405)
+			return
+		}
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", 400)
+			return
+		}
+		carro, err := models.GetCarroByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Carro não encontrado", 404)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar carro %d: %v", id, err)
+			http.Error(w, "Erro ao buscar carro", 500)
+			return
+		}
+		historico, err := models.GetHistoricoCarro(db, id)
+		if err != nil {
+			log.Printf("Erro ao buscar histórico do carro %d: %v", id, err)
+			http.Error(w, "Erro ao buscar histórico", 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"carro":     carro,
+			"historico": historico,
+		})
+	})
+}
+
+func carroValido(w http.ResponseWriter, c models.Carro) bool {
+	if err := models.ValidarCarro(c); err != nil {
+		http.Error(w, err.Error(), 400)
+		return false
+	}
+	return true
+}
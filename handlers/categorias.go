@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// GET /categorias - listar categorias e preços base (cliente e admin)
+func ListarCategoriasHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		categorias, err := models.GetAllCategorias(db)
+		if err != nil {
+			log.Printf("Erro ao buscar categorias: %v", err)
+			http.Error(w, "Erro ao buscar categorias", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(categorias)
+	})
+}
+
+// POST /categorias/criar - criar categoria (admin)
+func CriarCategoriaHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		c := models.Categoria{MultiplicadorFimSemana: 1, MultiplicadorFeriado: 1}
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.Codigo = strings.ToLower(strings.TrimSpace(c.Codigo))
+		if !categoriaValida(w, c) {
+			return
+		}
+
+		if err := models.CreateCategoria(db, c); err != nil {
+			log.Printf("Erro ao criar categoria '%s': %v", c.Codigo, err)
+			http.Error(w, "Erro ao criar categoria", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"Categoria criada com sucesso"}`))
+	})
+}
+
+// PUT /categorias/atualizar?codigo=suv - atualizar nome e preço base (admin)
+func AtualizarCategoriaHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		c := models.Categoria{MultiplicadorFimSemana: 1, MultiplicadorFeriado: 1}
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		c.Codigo = r.URL.Query().Get("codigo")
+		if !categoriaValida(w, c) {
+			return
+		}
+
+		if err := models.UpdateCategoria(db, c); err != nil {
+			log.Printf("Erro ao atualizar categoria '%s': %v", c.Codigo, err)
+			http.Error(w, "Erro ao atualizar categoria", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// POST /categorias/deletar?codigo=suv - remover categoria sem carros (admin)
+func DeletarCategoriaHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		codigo := r.URL.Query().Get("codigo")
+
+		n, err := models.ContarCarrosCategoria(db, codigo)
+		if err != nil {
+			log.Printf("Erro ao contar carros da categoria '%s': %v", codigo, err)
+			http.Error(w, "Erro ao deletar categoria", http.StatusInternalServerError)
+			return
+		}
+		if n > 0 {
+			http.Error(w, "Categoria possui carros vinculados", http.StatusConflict)
+			return
+		}
+
+		if err := models.DeleteCategoria(db, codigo); err != nil {
+			log.Printf("Erro ao deletar categoria '%s': %v", codigo, err)
+			http.Error(w, "Erro ao deletar categoria", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func categoriaValida(w http.ResponseWriter, c models.Categoria) bool {
+	if c.Codigo == "" || c.Nome == "" || c.ValorDiariaBase <= 0 {
+		http.Error(w, "Código, nome e valor da diária base são obrigatórios", http.StatusBadRequest)
+		return false
+	}
+	if c.MultiplicadorFimSemana <= 0 || c.MultiplicadorFeriado <= 0 {
+		http.Error(w, "Os multiplicadores devem ser maiores que zero", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// categoriaExiste responde 400 quando o carro aponta para uma categoria desconhecida
+func categoriaExiste(db *sql.DB, w http.ResponseWriter, codigo string) bool {
+	if codigo == "" {
+		return true
+	}
+	_, err := models.GetCategoriaByCodigo(db, codigo)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Categoria inexistente: "+codigo, http.StatusBadRequest)
+		return false
+	}
+	if err != nil {
+		log.Printf("Erro ao buscar categoria '%s': %v", codigo, err)
+		http.Error(w, "Erro ao validar categoria", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+// POST /locacoes/caucao/capturar?id=1 - captura total ou parcial da caução (admin)
+// Body: {"valor": "150.00"}. O restante do bloqueio é liberado no mesmo momento.
+func CapturarCaucaoHandler(db *sql.DB, gateway servicos.GatewayPagamento) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		var input struct {
+			Valor models.Dinheiro `json:"valor"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		bloqueio, ok := caucaoAberta(db, w, r)
+		if !ok {
+			return
+		}
+		if input.Valor <= 0 || input.Valor > bloqueio.ValorPago {
+			http.Error(w, "O valor capturado deve ser maior que zero e no máximo "+bloqueio.ValorPago.Formatar()+".", http.StatusBadRequest)
+			return
+		}
+
+		if err := gateway.Capturar(bloqueio.ReferenciaGateway, input.Valor); err != nil {
+			log.Printf("Erro ao capturar caução %s: %v", bloqueio.ReferenciaGateway, err)
+			http.Error(w, "Gateway recusou a captura da caução: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		encerrarCaucao(db, w, bloqueio, input.Valor)
+	})
+}
+
+// POST /locacoes/caucao/liberar?id=1 - libera a caução inteira (admin)
+func LiberarCaucaoHandler(db *sql.DB, gateway servicos.GatewayPagamento) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		bloqueio, ok := caucaoAberta(db, w, r)
+		if !ok {
+			return
+		}
+
+		if err := gateway.Liberar(bloqueio.ReferenciaGateway); err != nil {
+			log.Printf("Erro ao liberar caução %s: %v", bloqueio.ReferenciaGateway, err)
+			http.Error(w, "Gateway recusou a liberação da caução: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		encerrarCaucao(db, w, bloqueio, 0)
+	})
+}
+
+// caucaoAberta busca o bloqueio de caução da locação indicada em ?id=
+func caucaoAberta(db *sql.DB, w http.ResponseWriter, r *http.Request) (models.Pagamento, bool) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return models.Pagamento{}, false
+	}
+	bloqueio, err := models.GetCaucaoAberta(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Locação sem caução em aberto.", http.StatusNotFound)
+		return bloqueio, false
+	}
+	if err != nil {
+		log.Printf("Erro ao buscar caução da locação %d: %v", id, err)
+		http.Error(w, "Erro interno ao buscar caução.", http.StatusInternalServerError)
+		return bloqueio, false
+	}
+	return bloqueio, true
+}
+
+// encerrarCaucao grava os movimentos depois que o gateway confirmou a operação
+func encerrarCaucao(db *sql.DB, w http.ResponseWriter, bloqueio models.Pagamento, capturado models.Dinheiro) {
+	movimentos, err := models.EncerrarCaucao(db, bloqueio, capturado)
+	if err == models.ErrCaucaoEncerrada {
+		log.Printf("ATENÇÃO: caução %s processada no gateway (capturado %s) depois de já ter sido encerrada",
+			bloqueio.ReferenciaGateway, capturado)
+		http.Error(w, "A caução já foi encerrada por outra operação.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		// O gateway já processou: o registro precisa ser corrigido manualmente a partir do log
+		log.Printf("ATENÇÃO: caução %s processada no gateway (capturado %s) mas não registrada: %v",
+			bloqueio.ReferenciaGateway, capturado, err)
+		http.Error(w, "Erro interno ao registrar a caução.", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movimentos)
+}
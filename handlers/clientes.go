@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -21,6 +22,25 @@ func checkSession(w http.ResponseWriter, r *http.Request) bool {
 	return true
 }
 
+// validarDocumentos normaliza CPF/CNPJ e CNH e responde 400 se algum for inválido
+func validarDocumentos(w http.ResponseWriter, c *models.Cliente, exigirCPFCNPJ bool) bool {
+	models.NormalizarDocumentos(c)
+	err := models.ValidarDocumentosCliente(*c, exigirCPFCNPJ)
+	if err == nil {
+		return true
+	}
+
+	var ev models.ErroValidacao
+	if errors.As(err, &ev) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": ev.Mensagem, "campo": ev.Campo})
+		return false
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	return false
+}
+
 // Listar todos clientes (GET /clientes)
 func ClientesHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -79,7 +99,16 @@ func ClienteCreateHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		if !validarDocumentos(w, &input.Cliente, true) {
+			return
+		}
+
 		err = models.CreateCliente(db, input.Cliente, input.Senha)
+		if campo := models.CampoViolado(err); campo != "" {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Já existe um cadastro com este " + campo, "campo": campo})
+			return
+		}
 		if err != nil {
 			log.Println("Erro criando cliente:", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -168,7 +197,16 @@ func ClienteEditHandler(db *sql.DB) http.HandlerFunc {
 
 		c.ID = id
 
+		if !validarDocumentos(w, &c, false) {
+			return
+		}
+
 		err = models.UpdateCliente(db, c)
+		if campo := models.CampoViolado(err); campo != "" {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Já existe um cadastro com este " + campo, "campo": campo})
+			return
+		}
 		if err != nil {
 			log.Println("Erro atualizando cliente:", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -180,3 +218,54 @@ func ClienteEditHandler(db *sql.DB) http.HandlerFunc {
 		json.NewEncoder(w).Encode(map[string]string{"message": "Cliente atualizado com sucesso"})
 	}
 }
+
+// POST /clientes/bloquear?id=1 - incluir cliente na lista de bloqueio (admin)
+func ClienteBloquearHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+
+		var input struct {
+			Motivo string `json:"motivo"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Motivo == "" {
+			http.Error(w, "Informe o motivo do bloqueio", http.StatusBadRequest)
+			return
+		}
+
+		if err := models.BloquearCliente(db, id, input.Motivo); err != nil {
+			log.Println("Erro bloqueando cliente:", err)
+			http.Error(w, "Erro ao bloquear cliente", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// POST /clientes/desbloquear?id=1 - retirar cliente da lista de bloqueio (admin)
+func ClienteDesbloquearHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if err := models.DesbloquearCliente(db, id); err != nil {
+			log.Println("Erro desbloqueando cliente:", err)
+			http.Error(w, "Erro ao desbloquear cliente", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
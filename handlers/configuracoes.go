@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// GET /configuracoes - parâmetros operacionais (admin)
+func ListarConfiguracoesHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		config, err := models.GetConfiguracoes(db)
+		if err != nil {
+			log.Printf("Erro ao buscar configurações: %v", err)
+			http.Error(w, "Erro ao buscar configurações", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(config)
+	})
+}
+
+// PUT /configuracoes/atualizar - altera um ou mais parâmetros (admin)
+// Body: {"atraso_tolerancia_minutos": "30", "combustivel_valor_litro": "6.90"}
+func AtualizarConfiguracoesHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		var valores map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&valores); err != nil {
+			http.Error(w, "JSON inválido: os valores devem ser strings", http.StatusBadRequest)
+			return
+		}
+		for chave, valor := range valores {
+			if err := models.ValidarConfiguracao(chave, valor); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		for chave, valor := range valores {
+			if err := models.SetConfiguracao(db, chave, valor); err != nil {
+				log.Printf("Erro ao gravar configuração %s: %v", chave, err)
+				http.Error(w, "Erro ao gravar configurações", http.StatusInternalServerError)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+// Modelo do contrato; é lido a cada geração, então pode ser ajustado sem reiniciar o servidor
+const modeloContrato = "templates/contrato.txt"
+
+// Funções dos modelos de documentos (contrato, recibo)
+var funcoesDocumentos = template.FuncMap{
+	// data formata instantes no horário local das filiais
+	"data": func(v any) string {
+		switch t := v.(type) {
+		case time.Time:
+			return t.In(models.Fuso).Format("02/01/2006 15:04")
+		case *time.Time:
+			if t != nil {
+				return t.In(models.Fuso).Format("02/01/2006 15:04")
+			}
+		}
+		return ""
+	},
+}
+
+// gerarContrato renderiza o contrato com os dados atuais da locação como uma versão nova,
+// grava o PDF no armazenamento privado e registra o hash do arquivo. Versões anteriores (entre
+// elas a aceita pelo cliente) continuam guardadas.
+func gerarContrato(db *sql.DB, arquivos servicos.Armazenamento, idLocacao int) ([]byte, int, error) {
+	versao, err := models.NovaVersaoContrato(db, idLocacao)
+	if err != nil {
+		return nil, 0, err
+	}
+	dados, err := models.CarregarDadosContrato(db, idLocacao)
+	if err != nil {
+		return nil, 0, err
+	}
+	dados.Versao = versao
+	modelo, err := template.New("contrato.txt").Funcs(funcoesDocumentos).ParseFiles(modeloContrato)
+	if err != nil {
+		return nil, 0, err
+	}
+	var texto bytes.Buffer
+	if err := modelo.Execute(&texto, dados); err != nil {
+		return nil, 0, err
+	}
+
+	pdf := servicos.NovoPDF()
+	pdf.Escrever(texto.String())
+	conteudo := pdf.Bytes()
+
+	caminho := fmt.Sprintf("contratos/locacao-%d-v%d.pdf", idLocacao, versao)
+	if _, err := arquivos.Salvar(caminho, bytes.NewReader(conteudo)); err != nil {
+		return nil, 0, err
+	}
+	hash := sha256.Sum256(conteudo)
+	if err := models.SalvarArquivoContrato(db, idLocacao, versao, caminho, hex.EncodeToString(hash[:])); err != nil {
+		return nil, 0, err
+	}
+	return conteudo, versao, nil
+}
+
+// atualizarContrato gera uma versão nova do contrato depois de uma mudança na locação (carro
+// atribuído, prorrogação). Falhas só são registradas: a versão fica sem arquivo e o PDF é
+// refeito no próximo download.
+func atualizarContrato(db *sql.DB, arquivos servicos.Armazenamento, idLocacao int) {
+	if _, _, err := gerarContrato(db, arquivos, idLocacao); err != nil {
+		log.Printf("Erro ao gerar contrato da locação %d: %v", idLocacao, err)
+	}
+}
+
+// GET /locacoes/{id}/contrato.pdf - contrato de locação em PDF (o próprio cliente ou admin)
+// Devolve a última versão gravada; locações sem contrato gerado (ou com o arquivo perdido)
+// têm uma versão nova gerada na hora. ?versao=N devolve uma versão anterior, como a aceita.
+func ContratoLocacaoHandler(db *sql.DB, arquivos servicos.Armazenamento) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		locacao, err := models.GetLocacaoByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Locação não encontrada.", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao buscar locação.", http.StatusInternalServerError)
+			return
+		}
+		if !acessoDoCliente(db, r, locacao.IDCliente) {
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
+
+		var pedida int
+		if v := r.URL.Query().Get("versao"); v != "" {
+			if pedida, err = strconv.Atoi(v); err != nil || pedida <= 0 {
+				http.Error(w, "Versão inválida", http.StatusBadRequest)
+				return
+			}
+		}
+		versao, err := models.GetVersaoContrato(db, id, pedida)
+		if err == sql.ErrNoRows && pedida != 0 {
+			http.Error(w, "Versão do contrato não encontrada.", http.StatusNotFound)
+			return
+		}
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("Erro ao buscar contrato da locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao buscar contrato.", http.StatusInternalServerError)
+			return
+		}
+		var conteudo []byte
+		if versao.Arquivo != "" {
+			f, err := arquivos.Abrir(versao.Arquivo)
+			if err == nil {
+				conteudo, err = io.ReadAll(f)
+				f.Close()
+			}
+			if err != nil && !os.IsNotExist(err) {
+				log.Printf("Erro ao ler contrato da locação %d (%s): %v", id, versao.Arquivo, err)
+				http.Error(w, "Erro interno ao ler contrato.", http.StatusInternalServerError)
+				return
+			}
+		}
+		if conteudo == nil {
+			// Uma versão anterior perdida não pode ser refeita com os dados de hoje
+			if pedida != 0 {
+				http.Error(w, "Arquivo desta versão do contrato não está disponível.", http.StatusNotFound)
+				return
+			}
+			if conteudo, versao.Versao, err = gerarContrato(db, arquivos, id); err != nil {
+				log.Printf("Erro ao gerar contrato da locação %d: %v", id, err)
+				http.Error(w, "Erro interno ao gerar contrato.", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="contrato-locacao-%d-v%d.pdf"`, id, versao.Versao))
+		w.Write(conteudo)
+	})
+}
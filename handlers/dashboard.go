@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
 )
 
 // DashboardHandler retorna dados básicos do usuário autenticado
@@ -21,3 +27,60 @@ func DashboardHandler(w http.ResponseWriter, r *http.Request) {
 		"msg":     "Bem-vindo ao dashboard",
 	})
 }
+
+// GET /dashboard/indicadores?data_inicio=2026-10-01&data_fim=2026-10-31&agrupar=dia|semana|mes&top=10
+// KPIs da frota para a gerência (admin). As datas são dias locais, com o fim incluído; sem
+// período, vale os últimos 30 dias.
+func IndicadoresHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		hoje := time.Now().In(models.Fuso).Format("2006-01-02")
+		dataInicio, dataFim := q.Get("data_inicio"), q.Get("data_fim")
+		if dataFim == "" {
+			dataFim = hoje
+		}
+		if dataInicio == "" {
+			if fim, err := time.Parse("2006-01-02", dataFim); err == nil {
+				dataInicio = fim.AddDate(0, 0, -29).Format("2006-01-02")
+			}
+		}
+		inicio, fim, ok := lerPeriodo(w, dataInicio, dataFim)
+		if !ok {
+			return
+		}
+
+		agrupamento := q.Get("agrupar")
+		if agrupamento == "" {
+			agrupamento = models.AgruparDia
+		}
+		if agrupamento != models.AgruparDia && agrupamento != models.AgruparSemana && agrupamento != models.AgruparMes {
+			http.Error(w, "Agrupamento inválido. Use dia, semana ou mes.", http.StatusBadRequest)
+			return
+		}
+		top := 10
+		if v := q.Get("top"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 100 {
+				http.Error(w, "top deve ser um número entre 1 e 100", http.StatusBadRequest)
+				return
+			}
+			top = n
+		}
+
+		// Do início do primeiro dia ao fim do último, no horário das filiais
+		inicio = time.Date(inicio.Year(), inicio.Month(), inicio.Day(), 0, 0, 0, 0, models.Fuso)
+		fim = time.Date(fim.Year(), fim.Month(), fim.Day()+1, 0, 0, 0, 0, models.Fuso)
+		indicadores, err := models.CalcularIndicadores(db, inicio, fim, agrupamento, top)
+		if err != nil {
+			log.Printf("Erro ao calcular indicadores de %s a %s: %v", dataInicio, dataFim, err)
+			http.Error(w, "Erro ao calcular indicadores", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(indicadores)
+	})
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+// leituraPainel é o que o atendente anota do painel na retirada e na devolução
+type leituraPainel struct {
+	Quilometragem *int `json:"quilometragem"`
+	Combustivel   *int `json:"combustivel"` // oitavos do tanque (0 a 8)
+}
+
+func (l leituraPainel) valida(w http.ResponseWriter) bool {
+	if l.Quilometragem != nil && *l.Quilometragem < 0 {
+		http.Error(w, "Quilometragem inválida.", http.StatusBadRequest)
+		return false
+	}
+	if l.Combustivel != nil && (*l.Combustivel < 0 || *l.Combustivel > models.TanqueCheio) {
+		http.Error(w, "Combustível deve ser informado em oitavos do tanque (0 a 8).", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// lerDataHora aceita AAAA-MM-DDTHH:MM, AAAA-MM-DD HH:MM (horário local das filiais) ou RFC 3339
+func lerDataHora(s string) (time.Time, error) {
+	var err error
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", time.RFC3339} {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, s, models.Fuso); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// POST /locacoes/devolucao?id=123 - check-in do carro (admin)
+// Body: {"data_devolucao": "2026-12-04T10:30", "quilometragem": 15320, "combustivel": 6}
+// Sem data_devolucao, vale o horário atual. Atraso, combustível e quilometragem excedente
+// viram itens da locação e são somados ao ValorTotal. Uma devolução antes do prazo gera
+// crédito pelas diárias não usadas (política em configuracoes) e libera o resto do período
+// para a lista de espera.
+func DevolucaoLocacaoHandler(db *sql.DB, notificador servicos.Notificador) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+
+		var input struct {
+			DataDevolucao string `json:"data_devolucao"`
+			leituraPainel
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		if input.Quilometragem == nil || input.Combustivel == nil {
+			http.Error(w, "Informe quilometragem e combustível da devolução.", http.StatusBadRequest)
+			return
+		}
+		if !input.valida(w) {
+			return
+		}
+		dataDevolucao := time.Now().UTC()
+		if input.DataDevolucao != "" {
+			if dataDevolucao, err = lerDataHora(input.DataDevolucao); err != nil {
+				http.Error(w, "Data de devolução inválida. Use AAAA-MM-DDTHH:MM.", http.StatusBadRequest)
+				return
+			}
+		}
+
+		locacao, err := models.GetLocacaoByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Locação não encontrada.", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao buscar locação.", http.StatusInternalServerError)
+			return
+		}
+		if locacao.Status != "em_andamento" {
+			http.Error(w, "Só é possível devolver locações em andamento. Status atual: "+locacao.Status, http.StatusConflict)
+			return
+		}
+
+		var retirada *models.Vistoria
+		v, err := models.GetVistoria(db, id, models.VistoriaRetirada)
+		if err == nil {
+			retirada = &v
+		} else if err != sql.ErrNoRows {
+			log.Printf("Erro ao buscar vistoria de retirada da locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao buscar vistoria de retirada.", http.StatusInternalServerError)
+			return
+		}
+		if retirada != nil && *input.Quilometragem < retirada.Quilometragem {
+			http.Error(w, "Quilometragem da devolução menor que a da retirada.", http.StatusBadRequest)
+			return
+		}
+
+		_, diaria, err := models.DiariaBase(db, locacao.IDCarro, locacao.Categoria)
+		if err != nil {
+			log.Printf("Erro ao buscar diária da locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao calcular cobranças.", http.StatusInternalServerError)
+			return
+		}
+		carro, err := models.GetCarroByID(db, locacao.IDCarro)
+		if err != nil {
+			log.Printf("Erro ao buscar carro %d da locação %d: %v", locacao.IDCarro, id, err)
+			http.Error(w, "Erro interno ao calcular cobranças.", http.StatusInternalServerError)
+			return
+		}
+		politica, err := models.CarregarPoliticaDevolucao(db)
+		if err != nil {
+			log.Printf("Erro ao carregar política de devolução: %v", err)
+			http.Error(w, "Erro interno ao calcular cobranças.", http.StatusInternalServerError)
+			return
+		}
+
+		devolucao := models.Vistoria{
+			IDLocacao:     id,
+			IDCarro:       locacao.IDCarro,
+			Tipo:          models.VistoriaDevolucao,
+			DataHora:      dataDevolucao,
+			Quilometragem: *input.Quilometragem,
+			Combustivel:   *input.Combustivel,
+		}
+		itens := politica.Cobrancas(locacao, diaria, retirada, devolucao, carro.CapacidadeTanque)
+		credito, err := politica.CreditoDevolucaoAntecipada(db, locacao, dataDevolucao)
+		if err != nil {
+			log.Printf("Erro ao recalcular a locação %d para a devolução antecipada: %v", id, err)
+			http.Error(w, "Erro interno ao calcular cobranças.", http.StatusInternalServerError)
+			return
+		}
+		if credito != nil {
+			itens = append(itens, *credito)
+		}
+
+		err = models.RegistrarDevolucao(db, locacao, devolucao, itens)
+		if err == models.ErrLocacaoNaoEmAndamento {
+			http.Error(w, "A locação já foi devolvida.", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao registrar devolução da locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao registrar devolução.", http.StatusInternalServerError)
+			return
+		}
+
+		if dataDevolucao.Before(locacao.PrazoDevolucao()) {
+			OferecerVagasEspera(db, notificador, carro.Categoria)
+		}
+
+		saldo, err := models.SaldoLocacao(db, id)
+		if err != nil {
+			log.Printf("Erro ao calcular saldo da locação %d: %v", id, err)
+			http.Error(w, "Devolução registrada, mas houve erro ao calcular o saldo.", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":   "Devolução registrada",
+			"cobrancas": itens,
+			"saldo":     saldo,
+		})
+	})
+}
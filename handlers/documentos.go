@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+// Tamanho máximo do arquivo de um documento (PDF ou foto)
+const limiteEnvioDocumento = 10 << 20
+
+// GET  /carros/{id}/documentos - documentos do carro (admin)
+// POST /carros/{id}/documentos - cadastra documento (admin)
+// multipart/form-data com tipo (crlv, ipva, seguro_obrigatorio, inspecao), numero,
+// validade (AAAA-MM-DD) e, opcionalmente, o arquivo digitalizado no campo "arquivo" (PDF, JPEG ou PNG).
+// Um documento novo do mesmo tipo substitui o anterior (vale o de validade mais recente).
+func DocumentosCarroHandler(db *sql.DB, armazenamento servicos.Armazenamento) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if _, err := models.GetCarroByID(db, id); err != nil {
+			http.Error(w, "Carro não encontrado", http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			documentos, err := models.GetDocumentosCarro(db, id)
+			if err != nil {
+				log.Printf("Erro ao buscar documentos do carro %d: %v", id, err)
+				http.Error(w, "Erro ao buscar documentos", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(documentos)
+
+		case http.MethodPost:
+			r.Body = http.MaxBytesReader(w, r.Body, limiteEnvioDocumento)
+			if err := r.ParseMultipartForm(limiteEnvioDocumento); err != nil {
+				http.Error(w, "Envio inválido ou acima de 10 MB", http.StatusBadRequest)
+				return
+			}
+			d := models.DocumentoCarro{IDCarro: id, Tipo: r.FormValue("tipo"), Numero: r.FormValue("numero")}
+			if !slices.Contains(models.TiposDocumento, d.Tipo) {
+				http.Error(w, "tipo deve ser crlv, ipva, seguro_obrigatorio ou inspecao", http.StatusBadRequest)
+				return
+			}
+			d.Validade, err = time.Parse("2006-01-02", r.FormValue("validade"))
+			if err != nil {
+				http.Error(w, "validade deve estar no formato AAAA-MM-DD", http.StatusBadRequest)
+				return
+			}
+
+			if arquivos := r.MultipartForm.File["arquivo"]; len(arquivos) > 0 {
+				d.ArquivoURL, err = salvarUpload(armazenamento, arquivos[0], fmt.Sprintf("documentos/%d", id), extensoesDocumento)
+				if err == errFormatoNaoAceito {
+					http.Error(w, "Formato não aceito (use PDF, JPEG ou PNG)", http.StatusUnsupportedMediaType)
+					return
+				}
+				if err != nil {
+					log.Printf("Erro ao salvar documento do carro %d: %v", id, err)
+					http.Error(w, "Erro interno ao salvar arquivo", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			if err := models.CreateDocumentoCarro(db, d); err != nil {
+				log.Printf("Erro ao cadastrar documento do carro %d: %v", id, err)
+				http.Error(w, "Erro ao cadastrar documento", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]any{"message": "Documento cadastrado", "arquivo_url": d.ArquivoURL})
+
+		default:
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// GET /arquivos/documentos/{id}/{arquivo} - arquivo digitalizado de um documento do carro (admin)
+func ArquivoDocumentoHandler(db *sql.DB, arquivos servicos.Armazenamento) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		documentos, err := models.GetDocumentosCarro(db, id)
+		if err != nil {
+			log.Printf("Erro ao buscar documentos do carro %d: %v", id, err)
+			http.Error(w, "Erro ao buscar documentos", http.StatusInternalServerError)
+			return
+		}
+		caminho := fmt.Sprintf("documentos/%d/%s", id, r.PathValue("arquivo"))
+		for _, d := range documentos {
+			if d.ArquivoURL == "/arquivos/"+caminho {
+				enviarArquivo(w, arquivos, caminho)
+				return
+			}
+		}
+		http.Error(w, "Arquivo não encontrado", http.StatusNotFound)
+	})
+}
+
+// GET /documentos/vencimentos[?dias=30] - documentos vigentes vencidos ou a vencer (admin)
+// Sem dias, usa a antecedência configurada em documentos_alerta_dias.
+func VencimentosDocumentosHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		var dias int
+		var err error
+		if v := r.URL.Query().Get("dias"); v != "" {
+			dias, err = strconv.Atoi(v)
+			if err != nil || dias < 0 {
+				http.Error(w, "dias deve ser um inteiro não negativo", http.StatusBadRequest)
+				return
+			}
+		} else if dias, err = models.ConfigInt(db, models.ConfigDocumentosMargemDias); err != nil {
+			log.Printf("Erro ao ler configuração de documentos: %v", err)
+			http.Error(w, "Erro ao ler configurações", http.StatusInternalServerError)
+			return
+		}
+
+		vencimentos, err := models.GetVencimentos(db, time.Now(), dias)
+		if err != nil {
+			log.Printf("Erro ao buscar vencimentos de documentos: %v", err)
+			http.Error(w, "Erro ao buscar vencimentos", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(vencimentos)
+	})
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+// GET /lista-espera?id_cliente=123 - entradas da lista de espera do cliente (admin pode omitir id_cliente)
+func ListarEsperaHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		idCliente, _ := strconv.Atoi(r.URL.Query().Get("id_cliente"))
+		if !acessoDoCliente(db, r, idCliente) {
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
+		esperas, err := models.GetListaEspera(db, idCliente)
+		if err != nil {
+			log.Printf("Erro ao buscar lista de espera (cliente %d): %v", idCliente, err)
+			http.Error(w, "Erro ao buscar lista de espera", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(esperas)
+	})
+}
+
+// POST /lista-espera/criar - entra na lista de espera de uma categoria (cliente)
+// Body: {"id_cliente": 1, "categoria": "suv", "data_inicio": "2026-12-20T10:00", "data_fim": "2026-12-27T10:00",
+// "id_filial_retirada": 1, "id_filial_devolucao": 2}
+// Só é aceita quando a categoria está sem vaga no período; senão, a reserva deve ser feita direto.
+func CriarEsperaHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		var input struct {
+			IDCliente         int    `json:"id_cliente"`
+			Categoria         string `json:"categoria"`
+			DataInicio        string `json:"data_inicio"`
+			DataFim           string `json:"data_fim"`
+			IDFilialRetirada  int    `json:"id_filial_retirada"`
+			IDFilialDevolucao int    `json:"id_filial_devolucao"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		if !acessoDoCliente(db, r, input.IDCliente) {
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
+
+		inicio, fim, ok := lerPeriodoLocacao(w, input.DataInicio, input.DataFim)
+		if !ok {
+			return
+		}
+		if !inicio.After(time.Now()) {
+			http.Error(w, "A retirada precisa ser no futuro.", http.StatusBadRequest)
+			return
+		}
+		if _, err := models.GetCategoriaByCodigo(db, input.Categoria); err != nil {
+			http.Error(w, "Categoria não encontrada.", http.StatusBadRequest)
+			return
+		}
+		cliente, err := models.GetClienteByID(db, input.IDCliente)
+		if err != nil {
+			http.Error(w, "Cliente não encontrado.", http.StatusBadRequest)
+			return
+		}
+		if err := models.VerificarCNHParaLocacao(cliente, fim); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		trajeto, ok := trajetoLocacao(db, w, input.IDFilialRetirada, input.IDFilialDevolucao, 0)
+		if !ok || !horarioAtendimento(db, w, trajeto, inicio, fim) {
+			return
+		}
+
+		livre, err := models.CategoriaDisponivel(db, input.Categoria, inicio, fim, trajeto, models.Ignorar{})
+		if err != nil {
+			log.Printf("Erro ao verificar disponibilidade da categoria '%s': %v", input.Categoria, err)
+			http.Error(w, "Erro interno ao verificar disponibilidade.", http.StatusInternalServerError)
+			return
+		}
+		if livre {
+			http.Error(w, "Há disponibilidade para o período; faça a reserva diretamente.", http.StatusConflict)
+			return
+		}
+
+		id, err := models.CreateEspera(db, models.EsperaLocacao{
+			IDCliente:         input.IDCliente,
+			Categoria:         input.Categoria,
+			DataInicio:        inicio,
+			DataFim:           fim,
+			IDFilialRetirada:  trajeto.Retirada,
+			IDFilialDevolucao: trajeto.Devolucao,
+		})
+		if err != nil {
+			log.Printf("Erro ao criar entrada na lista de espera: %v", err)
+			http.Error(w, "Erro ao entrar na lista de espera", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"message": "Você entrou na lista de espera", "id_espera": id})
+	})
+}
+
+// POST /lista-espera/cancelar?id=123 - sai da lista de espera (o próprio cliente ou admin)
+func CancelarEsperaHandler(db *sql.DB, notificador servicos.Notificador) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		espera, err := models.GetEsperaByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Entrada da lista de espera não encontrada.", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar entrada %d da lista de espera: %v", id, err)
+			http.Error(w, "Erro interno ao buscar a lista de espera.", http.StatusInternalServerError)
+			return
+		}
+		if !acessoDoCliente(db, r, espera.IDCliente) {
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
+
+		err = models.CancelarEspera(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "A entrada já foi atendida, expirou ou foi cancelada.", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao cancelar entrada %d da lista de espera: %v", id, err)
+			http.Error(w, "Erro ao cancelar", http.StatusInternalServerError)
+			return
+		}
+		// Quem recusa uma oferta devolve a vaga para o próximo da fila
+		if espera.Status == models.EsperaOferecida {
+			OferecerVagasEspera(db, notificador, espera.Categoria)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// ofertaDoCliente confere a oferta que o cliente está aceitando ao reservar
+func ofertaDoCliente(db *sql.DB, w http.ResponseWriter, id, idCliente int) (models.EsperaLocacao, bool) {
+	espera, err := models.GetEsperaByID(db, id)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Erro ao buscar entrada %d da lista de espera: %v", id, err)
+		http.Error(w, "Erro interno ao buscar a oferta.", http.StatusInternalServerError)
+		return espera, false
+	}
+	if err == sql.ErrNoRows || espera.IDCliente != idCliente || !espera.OfertaValida(time.Now()) {
+		http.Error(w, models.ErrOfertaIndisponivel.Error(), http.StatusConflict)
+		return espera, false
+	}
+	return espera, true
+}
+
+// OferecerVagasEspera repassa as vagas abertas (cancelamento, devolução antecipada, oferta
+// recusada ou vencida) para a lista de espera e avisa os clientes contemplados. Categoria vazia
+// revisa a fila inteira. Erros só são registrados: quem liberou a vaga não deve falhar por isso.
+func OferecerVagasEspera(db *sql.DB, notificador servicos.Notificador, categoria string) {
+	minutos, err := models.ConfigInt(db, models.ConfigEsperaOfertaMin)
+	if err != nil {
+		log.Printf("Erro ao ler validade das ofertas da lista de espera: %v", err)
+		return
+	}
+	ofertas, err := models.OferecerVagas(db, categoria, time.Duration(minutos)*time.Minute)
+	if err != nil {
+		log.Printf("Erro ao oferecer vagas da lista de espera (categoria '%s'): %v", categoria, err)
+	}
+
+	for _, e := range ofertas {
+		cliente, err := models.GetClienteByID(db, e.IDCliente)
+		if err != nil {
+			log.Printf("Erro ao buscar cliente %d da lista de espera: %v", e.IDCliente, err)
+			continue
+		}
+		mensagem := fmt.Sprintf("Olá, %s!\n\nAbriu uma vaga na categoria %s de %s a %s.\n"+
+			"Ela fica reservada para você até %s. Para confirmar, faça a reserva informando id_espera %d.",
+			cliente.Nome, e.Categoria,
+			e.DataInicio.In(models.Fuso).Format("02/01/2006 15:04"), e.DataFim.In(models.Fuso).Format("02/01/2006 15:04"),
+			e.OfertaExpiraEm.In(models.Fuso).Format("02/01/2006 15:04"), e.ID)
+		if err := notificador.Notificar(cliente.Email, "Vaga disponível na lista de espera", mensagem); err != nil {
+			log.Printf("Erro ao avisar cliente %d da oferta %d: %v", e.IDCliente, e.ID, err)
+		}
+	}
+}
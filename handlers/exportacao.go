@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+// respostaPlanilha só escreve os cabeçalhos HTTP e abre a planilha quando a consulta já deu
+// certo, para que uma falha inicial ainda possa responder 500
+type respostaPlanilha struct {
+	w        http.ResponseWriter
+	formato  string
+	arquivo  string
+	entidade string
+	planilha servicos.Planilha
+}
+
+func (p *respostaPlanilha) Cabecalho(colunas []string) error {
+	var err error
+	if p.formato == "xlsx" {
+		p.w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		p.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, p.arquivo))
+		p.planilha, err = servicos.NovaPlanilhaXLSX(p.w, p.entidade)
+	} else {
+		p.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		p.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, p.arquivo))
+		p.planilha, err = servicos.NovaPlanilhaCSV(p.w)
+	}
+	if err != nil {
+		return err
+	}
+	return p.planilha.Cabecalho(colunas)
+}
+
+func (p *respostaPlanilha) Linha(celulas []any) error {
+	return p.planilha.Linha(celulas)
+}
+
+// GET /export/{entidade}.csv ou .xlsx - planilha de locacoes, pagamentos, clientes ou carros (admin)
+// Filtros opcionais: id_cliente (locações e pagamentos), status (locações) e data_inicio/data_fim
+// (AAAA-MM-DD, fim incluído; data de início da locação ou data do pagamento).
+// As linhas são lidas e enviadas uma a uma.
+func ExportarHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		arquivo := r.PathValue("arquivo")
+		formato := strings.TrimPrefix(path.Ext(arquivo), ".")
+		entidade := strings.TrimSuffix(arquivo, path.Ext(arquivo))
+		if formato != "csv" && formato != "xlsx" {
+			http.Error(w, "Formato inválido. Use .csv ou .xlsx.", http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(models.EntidadesExportaveis, entidade) {
+			http.Error(w, "Entidade inválida. Use "+strings.Join(models.EntidadesExportaveis, ", ")+".", http.StatusNotFound)
+			return
+		}
+
+		q := r.URL.Query()
+		var filtro models.FiltroExportacao
+		if v := q.Get("id_cliente"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "id_cliente inválido", http.StatusBadRequest)
+				return
+			}
+			filtro.IDCliente = id
+		}
+		filtro.Status = q.Get("status")
+		if v := q.Get("data_inicio"); v != "" {
+			d, err := time.ParseInLocation("2006-01-02", v, models.Fuso)
+			if err != nil {
+				http.Error(w, "Data de início inválida. Use o formato AAAA-MM-DD.", http.StatusBadRequest)
+				return
+			}
+			filtro.Inicio = d
+		}
+		if v := q.Get("data_fim"); v != "" {
+			d, err := time.ParseInLocation("2006-01-02", v, models.Fuso)
+			if err != nil {
+				http.Error(w, "Data de fim inválida. Use o formato AAAA-MM-DD.", http.StatusBadRequest)
+				return
+			}
+			filtro.Fim = d.AddDate(0, 0, 1)
+		}
+
+		resposta := &respostaPlanilha{w: w, formato: formato, entidade: entidade,
+			arquivo: fmt.Sprintf("%s-%s.%s", entidade, time.Now().In(models.Fuso).Format("2006-01-02"), formato)}
+		err := models.Exportar(db, entidade, filtro, resposta)
+		if resposta.planilha == nil {
+			log.Printf("Erro ao exportar %s: %v", entidade, err)
+			http.Error(w, "Erro ao exportar "+entidade, http.StatusInternalServerError)
+			return
+		}
+		if err != nil {
+			// O arquivo já está sendo enviado; o cliente recebe uma planilha incompleta
+			log.Printf("Erro durante a exportação de %s: %v", entidade, err)
+			return
+		}
+		if err := resposta.planilha.Fechar(); err != nil {
+			log.Printf("Erro ao concluir a exportação de %s: %v", entidade, err)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// GET /extras - listar opcionais (cliente e admin)
+func ListarExtrasHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		extras, err := models.GetAllExtras(db)
+		if err != nil {
+			log.Printf("Erro ao buscar extras: %v", err)
+			http.Error(w, "Erro ao buscar extras", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(extras)
+	})
+}
+
+// POST /extras/criar - criar opcional (admin)
+func CriarExtraHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		e := models.Extra{Ativo: true}
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !extraValido(w, e) {
+			return
+		}
+
+		if err := models.CreateExtra(db, e); err != nil {
+			log.Printf("Erro ao criar extra: %v", err)
+			http.Error(w, "Erro ao criar extra", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"Extra criado com sucesso"}`))
+	})
+}
+
+// PUT /extras/atualizar?id=1 - atualizar opcional (admin)
+func AtualizarExtraHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+
+		var e models.Extra
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		e.ID = id
+		if !extraValido(w, e) {
+			return
+		}
+
+		if err := models.UpdateExtra(db, e); err != nil {
+			log.Printf("Erro ao atualizar extra %d: %v", id, err)
+			http.Error(w, "Erro ao atualizar extra", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// POST /extras/deletar?id=1 - remover opcional (admin)
+func DeletarExtraHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if err := models.DeleteExtra(db, id); err != nil {
+			// Extras já usados em locações ficam presos pela foreign key; desative-os em vez de remover
+			log.Printf("Erro ao deletar extra %d: %v", id, err)
+			http.Error(w, "Erro ao deletar extra (se já foi usado em locações, desative-o)", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func extraValido(w http.ResponseWriter, e models.Extra) bool {
+	if e.Nome == "" || e.Preco < 0 {
+		http.Error(w, "Nome e preço são obrigatórios", http.StatusBadRequest)
+		return false
+	}
+	if e.TipoCobranca != models.CobrancaDiaria && e.TipoCobranca != models.CobrancaFixa {
+		http.Error(w, "tipo_cobranca deve ser 'diaria' ou 'fixa'", http.StatusBadRequest)
+		return false
+	}
+	if e.Estoque != nil && *e.Estoque < 0 {
+		http.Error(w, "Estoque não pode ser negativo", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// validarExtras confere se os extras existem, estão ativos e têm estoque no período
+func validarExtras(db *sql.DB, w http.ResponseWriter, extras []models.ExtraSolicitado, inicio, fim time.Time, ignorarLocacao int) bool {
+	for _, pedido := range extras {
+		if pedido.Quantidade <= 0 {
+			http.Error(w, "A quantidade de cada extra deve ser maior que zero.", http.StatusBadRequest)
+			return false
+		}
+		extra, err := models.GetExtraByID(db, pedido.IDExtra)
+		if err == sql.ErrNoRows {
+			http.Error(w, fmt.Sprintf("Extra %d não encontrado.", pedido.IDExtra), http.StatusBadRequest)
+			return false
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar extra %d: %v", pedido.IDExtra, err)
+			http.Error(w, "Erro interno ao verificar extras.", http.StatusInternalServerError)
+			return false
+		}
+		livre, err := models.ExtraDisponivel(db, extra, pedido.Quantidade, inicio, fim, ignorarLocacao)
+		if err != nil {
+			log.Printf("Erro ao verificar estoque do extra %d: %v", extra.ID, err)
+			http.Error(w, "Erro interno ao verificar extras.", http.StatusInternalServerError)
+			return false
+		}
+		if !livre {
+			http.Error(w, "Extra sem estoque para o período: "+extra.Nome, http.StatusConflict)
+			return false
+		}
+	}
+	return true
+}
+
+// lerExtras interpreta "1:2,4:1" (id_extra:quantidade); quantidade omitida vale 1
+func lerExtras(s string) ([]models.ExtraSolicitado, error) {
+	var extras []models.ExtraSolicitado
+	if s == "" {
+		return extras, nil
+	}
+	for _, parte := range strings.Split(s, ",") {
+		idStr, qtdStr, temQtd := strings.Cut(parte, ":")
+		id, err := strconv.Atoi(strings.TrimSpace(idStr))
+		if err != nil {
+			return nil, err
+		}
+		qtd := 1
+		if temQtd {
+			if qtd, err = strconv.Atoi(strings.TrimSpace(qtdStr)); err != nil {
+				return nil, err
+			}
+		}
+		extras = append(extras, models.ExtraSolicitado{IDExtra: id, Quantidade: qtd})
+	}
+	return extras, nil
+}
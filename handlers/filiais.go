@@ -0,0 +1,451 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// GET /filiais - listar filiais (cliente e admin)
+func ListarFiliaisHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		filiais, err := models.GetAllFiliais(db)
+		if err != nil {
+			log.Printf("Erro ao buscar filiais: %v", err)
+			http.Error(w, "Erro ao buscar filiais", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(filiais)
+	})
+}
+
+// POST /filiais/criar - criar filial (admin)
+// Body: {"nome": "Aeroporto", "endereco": "...", "cidade": "Campinas", "uf": "SP",
+// "horario_abertura": "06:00", "horario_fechamento": "23:00"}
+func CriarFilialHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		f := models.Filial{HorarioAbertura: "08:00", HorarioFechamento: "18:00", Ativa: true}
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !filialValida(w, f) {
+			return
+		}
+
+		if err := models.CreateFilial(db, f); err != nil {
+			log.Printf("Erro ao criar filial: %v", err)
+			http.Error(w, "Erro ao criar filial (o nome já existe?)", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"Filial criada com sucesso"}`))
+	})
+}
+
+// PUT /filiais/atualizar?id=1 - atualizar filial (admin)
+// Uma filial inativa não recebe novas locações, mas as já reservadas continuam valendo.
+func AtualizarFilialHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+
+		// Campos ausentes no JSON mantêm o valor atual (ex.: ativa e abre_feriados)
+		f, err := models.GetFilialByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Filial não encontrada", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar filial %d: %v", id, err)
+			http.Error(w, "Erro ao buscar filial", http.StatusInternalServerError)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		f.ID = id
+		if !filialValida(w, f) {
+			return
+		}
+
+		if err := models.UpdateFilial(db, f); err != nil {
+			log.Printf("Erro ao atualizar filial %d: %v", id, err)
+			http.Error(w, "Erro ao atualizar filial", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// POST /filiais/deletar?id=1 - remover filial (admin)
+func DeletarFilialHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if err := models.DeleteFilial(db, id); err != nil {
+			log.Printf("Erro ao deletar filial %d: %v", id, err)
+			http.Error(w, "Erro ao deletar filial (se já tem carros ou locações, desative-a)", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func filialValida(w http.ResponseWriter, f models.Filial) bool {
+	if f.Nome == "" {
+		http.Error(w, "Nome é obrigatório", http.StatusBadRequest)
+		return false
+	}
+	abertura, err1 := time.Parse("15:04", f.HorarioAbertura)
+	fechamento, err2 := time.Parse("15:04", f.HorarioFechamento)
+	if err1 != nil || err2 != nil || !abertura.Before(fechamento) {
+		http.Error(w, "Horários devem estar no formato HH:MM, com a abertura antes do fechamento", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// horarioValido confere um horário especial de dia da semana; dias fechados dispensam o horário
+func horarioValido(w http.ResponseWriter, h models.HorarioFilial) bool {
+	if h.DiaSemana < 0 || h.DiaSemana > 6 {
+		http.Error(w, "dia_semana deve ir de 0 (domingo) a 6 (sábado)", http.StatusBadRequest)
+		return false
+	}
+	if h.Fechado {
+		return true
+	}
+	abertura, err1 := time.Parse("15:04", h.Abertura)
+	fechamento, err2 := time.Parse("15:04", h.Fechamento)
+	if err1 != nil || err2 != nil || !abertura.Before(fechamento) {
+		http.Error(w, "Horários devem estar no formato HH:MM, com a abertura antes do fechamento", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// filialAtiva confere a filial escolhida para retirada ou devolução
+func filialAtiva(db *sql.DB, w http.ResponseWriter, id int) bool {
+	filial, err := models.GetFilialByID(db, id)
+	if err == sql.ErrNoRows || (err == nil && !filial.Ativa) {
+		http.Error(w, "Filial não encontrada ou inativa.", http.StatusBadRequest)
+		return false
+	}
+	if err != nil {
+		log.Printf("Erro ao buscar filial %d: %v", id, err)
+		http.Error(w, "Erro interno ao verificar a filial.", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// trajetoLocacao completa e valida as filiais pedidas: sem retirada, usa padrao (a filial do
+// carro ou a filial padrão); sem devolução, devolve onde retirou. Uma locação one-way só é
+// aceita entre filiais com taxa de retorno cadastrada.
+func trajetoLocacao(db *sql.DB, w http.ResponseWriter, retirada, devolucao, padrao int) (models.Trajeto, bool) {
+	t := models.Trajeto{Retirada: retirada, Devolucao: devolucao}
+	if t.Retirada == 0 {
+		t.Retirada = padrao
+	}
+	if t.Retirada == 0 {
+		filial, err := models.GetFilialPadrao(db)
+		if err != nil {
+			log.Printf("Erro ao buscar filial padrão: %v", err)
+			http.Error(w, "Nenhuma filial ativa para retirada.", http.StatusInternalServerError)
+			return t, false
+		}
+		t.Retirada = filial.ID
+	}
+	if t.Devolucao == 0 {
+		t.Devolucao = t.Retirada
+	}
+
+	if !filialAtiva(db, w, t.Retirada) || (t.OneWay() && !filialAtiva(db, w, t.Devolucao)) {
+		return t, false
+	}
+	if t.OneWay() {
+		_, err := models.GetTaxaRetorno(db, t.Retirada, t.Devolucao)
+		if err == models.ErrRetornoNaoOferecido {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return t, false
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar taxa de retorno %d -> %d: %v", t.Retirada, t.Devolucao, err)
+			http.Error(w, "Erro interno ao verificar a taxa de retorno.", http.StatusInternalServerError)
+			return t, false
+		}
+	}
+	return t, true
+}
+
+// horarioAtendimento confere se a retirada e a devolução caem no expediente das filiais do
+// trajeto, respondendo 422 com o campo recusado. inicio zero confere só a devolução (prorrogação).
+func horarioAtendimento(db *sql.DB, w http.ResponseWriter, t models.Trajeto, inicio, fim time.Time) bool {
+	intervalo, err := models.ConfigInt(db, models.ConfigIntervaloHorarioMin)
+	if err == nil {
+		var retirada, devolucao models.Filial
+		if retirada, err = models.GetFilialByID(db, t.Retirada); err == nil {
+			if devolucao, err = models.GetFilialByID(db, t.Devolucao); err == nil {
+				passo := time.Duration(intervalo) * time.Minute
+				if !inicio.IsZero() {
+					err = models.ValidarHorarioAtendimento(db, retirada, inicio, passo, "data_inicio")
+				}
+				if err == nil {
+					err = models.ValidarHorarioAtendimento(db, devolucao, fim, passo, "data_fim")
+				}
+			}
+		}
+	}
+	if err == nil {
+		return true
+	}
+
+	var ev models.ErroValidacao
+	if errors.As(err, &ev) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"error": ev.Mensagem, "campo": ev.Campo})
+		return false
+	}
+	log.Printf("Erro ao verificar o expediente das filiais %d -> %d: %v", t.Retirada, t.Devolucao, err)
+	http.Error(w, "Erro interno ao verificar o horário de atendimento.", http.StatusInternalServerError)
+	return false
+}
+
+// --- Horários especiais ---
+
+// GET /filiais/{id}/horarios - horários por dia da semana que substituem o padrão da filial (cliente e admin)
+// PUT /filiais/{id}/horarios - substitui todos (admin)
+// Body: [{"dia_semana": 6, "abertura": "09:00", "fechamento": "13:00"}, {"dia_semana": 0, "fechado": true}]
+func HorariosFilialHandler(db *sql.DB) http.HandlerFunc {
+	listar := AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		horarios, err := models.GetHorariosFilial(db, id)
+		if err != nil {
+			log.Printf("Erro ao buscar horários da filial %d: %v", id, err)
+			http.Error(w, "Erro ao buscar horários", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(horarios)
+	})
+
+	salvar := AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if _, err := models.GetFilialByID(db, id); err != nil {
+			http.Error(w, "Filial não encontrada", http.StatusNotFound)
+			return
+		}
+		var horarios []models.HorarioFilial
+		if err := json.NewDecoder(r.Body).Decode(&horarios); err != nil {
+			http.Error(w, "Dados inválidos", http.StatusBadRequest)
+			return
+		}
+		vistos := map[int]bool{}
+		for i, h := range horarios {
+			if !horarioValido(w, h) {
+				return
+			}
+			if vistos[h.DiaSemana] {
+				http.Error(w, "Cada dia da semana pode aparecer uma vez", http.StatusBadRequest)
+				return
+			}
+			vistos[h.DiaSemana] = true
+			if h.Fechado {
+				horarios[i].Abertura, horarios[i].Fechamento = "", ""
+			}
+		}
+		if err := models.SalvarHorariosFilial(db, id, horarios); err != nil {
+			log.Printf("Erro ao salvar horários da filial %d: %v", id, err)
+			http.Error(w, "Erro ao salvar horários", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			listar(w, r)
+		case http.MethodPut:
+			salvar(w, r)
+		default:
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// --- Taxas de retorno (one-way) ---
+
+// GET /filiais/taxas - taxas de retorno entre filiais (cliente e admin)
+func ListarTaxasRetornoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		taxas, err := models.GetAllTaxasRetorno(db)
+		if err != nil {
+			log.Printf("Erro ao buscar taxas de retorno: %v", err)
+			http.Error(w, "Erro ao buscar taxas de retorno", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(taxas)
+	})
+}
+
+// POST /filiais/taxas/salvar - cria ou altera a taxa de um par de filiais (admin)
+// Body: {"id_filial_origem": 1, "id_filial_destino": 2, "valor": "150.00"}.
+// A taxa vale num sentido só; cadastre o par inverso se a volta também for oferecida.
+func SalvarTaxaRetornoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		var t models.TaxaRetorno
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if t.IDFilialOrigem == t.IDFilialDestino || t.Valor < 0 {
+			http.Error(w, "Origem e destino devem ser diferentes e o valor não pode ser negativo", http.StatusBadRequest)
+			return
+		}
+		for _, id := range []int{t.IDFilialOrigem, t.IDFilialDestino} {
+			if _, err := models.GetFilialByID(db, id); err != nil {
+				http.Error(w, "Filial não encontrada", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := models.SalvarTaxaRetorno(db, t); err != nil {
+			log.Printf("Erro ao salvar taxa de retorno: %v", err)
+			http.Error(w, "Erro ao salvar taxa de retorno", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// POST /filiais/taxas/deletar?id=1 - deixa de oferecer a devolução entre o par de filiais (admin)
+func DeletarTaxaRetornoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if err := models.DeleteTaxaRetorno(db, id); err != nil {
+			log.Printf("Erro ao deletar taxa de retorno %d: %v", id, err)
+			http.Error(w, "Erro ao deletar taxa de retorno", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// --- Movimentação da frota ---
+
+// GET /filiais/movimentacoes[?id_carro=1][&id_filial=2] - histórico de movimentações (admin)
+func ListarMovimentacoesHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		idCarro, _ := strconv.Atoi(r.URL.Query().Get("id_carro"))
+		idFilial, _ := strconv.Atoi(r.URL.Query().Get("id_filial"))
+		movimentacoes, err := models.GetMovimentacoes(db, idCarro, idFilial)
+		if err != nil {
+			log.Printf("Erro ao buscar movimentações da frota: %v", err)
+			http.Error(w, "Erro ao buscar movimentações", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(movimentacoes)
+	})
+}
+
+// POST /filiais/transferir - transfere um carro para outra filial (admin)
+// Body: {"id_carro": 1, "id_filial_destino": 2, "observacao": "reforço de frota no feriado"}
+func TransferirCarroHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		var input struct {
+			IDCarro         int    `json:"id_carro"`
+			IDFilialDestino int    `json:"id_filial_destino"`
+			Observacao      string `json:"observacao"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		if _, err := models.GetCarroByID(db, input.IDCarro); err != nil {
+			http.Error(w, "Carro não encontrado", http.StatusBadRequest)
+			return
+		}
+		if !filialAtiva(db, w, input.IDFilialDestino) {
+			return
+		}
+
+		err := models.TransferirCarro(db, input.IDCarro, input.IDFilialDestino, input.Observacao)
+		if err == models.ErrCarroComLocacao || err == models.ErrFilialDesfalcada {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao transferir carro %d para a filial %d: %v", input.IDCarro, input.IDFilialDestino, err)
+			http.Error(w, "Erro interno ao transferir carro", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
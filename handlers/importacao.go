@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// tamanhoMaximoImportacao limita o corpo aceito em /importar (10 MiB, dezenas de milhares de linhas)
+const tamanhoMaximoImportacao = 10 << 20
+
+// POST /importar/carros ou /importar/clientes - cadastro em massa (admin)
+// O corpo é CSV (colunas como na exportação, separador "," ou ";") ou uma lista JSON; o formato
+// vem de ?formato=csv|json ou do Content-Type. Com ?simular=1 nada é gravado. Se qualquer
+// linha tiver erro, nenhuma é importada e a resposta 422 lista os erros por linha.
+func ImportarHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		importar := models.ImportarCarros
+		entidade := r.PathValue("entidade")
+		switch entidade {
+		case "carros":
+		case "clientes":
+			importar = models.ImportarClientes
+		default:
+			http.Error(w, "Entidade inválida. Use carros ou clientes.", http.StatusNotFound)
+			return
+		}
+
+		formato := r.URL.Query().Get("formato")
+		if formato == "" {
+			tipo, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			switch tipo {
+			case "application/json":
+				formato = "json"
+			case "text/csv", "text/plain":
+				formato = "csv"
+			}
+		}
+		simular := r.URL.Query().Get("simular") == "1" || r.URL.Query().Get("simular") == "true"
+
+		res, err := importar(db, http.MaxBytesReader(w, r.Body, tamanhoMaximoImportacao), formato, simular)
+		var excedido *http.MaxBytesError
+		switch {
+		case errors.As(err, &excedido):
+			http.Error(w, "Arquivo maior que o permitido (10 MiB)", http.StatusRequestEntityTooLarge)
+			return
+		case errors.Is(err, models.ErrFormatoImportacao):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			log.Printf("Erro ao importar %s: %v", entidade, err)
+			http.Error(w, "Erro ao importar "+entidade, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(res.Erros) > 0 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		json.NewEncoder(w).Encode(res)
+	})
+}
@@ -3,15 +3,21 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log" // Certifique-se de que 'log' está importado
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
 )
 
 // GET /carros/disponiveis - carros disponíveis (cliente)
+// Filtros opcionais: ?categoria=suv&data_inicio=AAAA-MM-DDTHH:MM&data_fim=AAAA-MM-DDTHH:MM&id_filial=1&id_filial_devolucao=2.
+// Com período, id_filial é a filial de retirada (sem ela, cada carro é considerado na própria filial).
+// Se nenhum carro servir, o cliente pode entrar na lista de espera (POST /lista-espera/criar).
 func CarrosDisponiveisHandler(db *sql.DB) http.HandlerFunc {
 	return AuthMiddleware(db, []string{"cliente"}, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet { // Adicionando verificação de método para consistência
@@ -19,6 +25,18 @@ func CarrosDisponiveisHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		q := r.URL.Query()
+		idFilial, _ := strconv.Atoi(q.Get("id_filial"))
+		idDevolucao, _ := strconv.Atoi(q.Get("id_filial_devolucao"))
+		var inicio, fim time.Time
+		porPeriodo := q.Get("data_inicio") != "" || q.Get("data_fim") != ""
+		if porPeriodo {
+			var ok bool
+			if inicio, fim, ok = lerPeriodoLocacao(w, q.Get("data_inicio"), q.Get("data_fim")); !ok {
+				return
+			}
+		}
+
 		carros, err := models.GetAllCarros(db)
 		if err != nil {
 			log.Printf("Erro ao buscar todos os carros para disponibilidade: %v", err)
@@ -28,9 +46,30 @@ func CarrosDisponiveisHandler(db *sql.DB) http.HandlerFunc {
 
 		var disponiveis []models.Carro
 		for _, c := range carros {
-			if c.Disponibilidade {
-				disponiveis = append(disponiveis, c)
+			if !c.Disponibilidade || (q.Get("categoria") != "" && c.Categoria != q.Get("categoria")) {
+				continue
 			}
+			if porPeriodo {
+				trajeto := models.Trajeto{Retirada: idFilial, Devolucao: idDevolucao}
+				if trajeto.Retirada == 0 {
+					trajeto.Retirada = c.IDFilial
+				}
+				if trajeto.Devolucao == 0 {
+					trajeto.Devolucao = trajeto.Retirada
+				}
+				livre, err := models.PeriodoDisponivel(db, c.ID, c.Categoria, inicio, fim, trajeto, models.Ignorar{})
+				if err != nil {
+					log.Printf("Erro ao verificar disponibilidade do carro %d: %v", c.ID, err)
+					http.Error(w, "Erro interno ao buscar carros disponíveis", http.StatusInternalServerError)
+					return
+				}
+				if !livre {
+					continue
+				}
+			} else if idFilial != 0 && c.IDFilial != idFilial {
+				continue
+			}
+			disponiveis = append(disponiveis, c)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -39,18 +78,34 @@ func CarrosDisponiveisHandler(db *sql.DB) http.HandlerFunc {
 }
 
 // POST /aluguel - criar locação (cliente)
-func CriarLocacaoHandler(db *sql.DB) http.HandlerFunc {
+func CriarLocacaoHandler(db *sql.DB, arquivos servicos.Armazenamento) http.HandlerFunc {
 	return AuthMiddleware(db, []string{"cliente"}, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
 			return
 		}
 
+		// Informe id_carro para um carro específico ou categoria para reservar a categoria
+		// (o carro é atribuído na retirada). Sem filiais, o carro é retirado e devolvido na filial
+		// onde está (ou na filial padrão, para reservas por categoria). Retirada e devolução têm
+		// data e hora locais e precisam cair no expediente das filiais. Com id_espera, o cliente
+		// aceita a vaga oferecida pela lista de espera: categoria, período e filiais vêm da oferta.
+		// Com id_reserva, confirma a reserva temporária (POST /reservas/hold) da mesma forma.
+		// aceite_contrato é obrigatório: o aceite fica registrado com data, hora e IP.
 		var l struct {
-			IDCarro    int    `json:"id_carro"`
-			IDCliente  int    `json:"id_cliente"`  // Reavaliar: idealmente, IDCliente viria da sessão
-			DataInicio string `json:"data_inicio"` // formato AAAA-mm-dd
-			DataFim    string `json:"data_fim"`
+			IDCarro    int                      `json:"id_carro"`
+			Categoria  string                   `json:"categoria"`
+			IDCliente  int                      `json:"id_cliente"`  // precisa ser o cliente da sessão
+			DataInicio string                   `json:"data_inicio"` // formato AAAA-MM-DDTHH:MM
+			DataFim    string                   `json:"data_fim"`
+			Extras     []models.ExtraSolicitado `json:"extras"`
+			IDPlano    int                      `json:"id_plano"` // opcional: plano de proteção
+			// Opcionais: devolver em outra filial cobra a taxa de retorno
+			IDFilialRetirada  int  `json:"id_filial_retirada"`
+			IDFilialDevolucao int  `json:"id_filial_devolucao"`
+			IDEspera          int  `json:"id_espera"`
+			IDReserva         int  `json:"id_reserva"`
+			AceiteContrato    bool `json:"aceite_contrato"`
 		}
 		err := json.NewDecoder(r.Body).Decode(&l)
 		if err != nil {
@@ -58,70 +113,379 @@ func CriarLocacaoHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "JSON inválido. Certifique-se de que todos os campos estão corretos.", http.StatusBadRequest)
 			return
 		}
+		if !l.AceiteContrato {
+			http.Error(w, "É preciso aceitar o contrato de locação (aceite_contrato).", http.StatusBadRequest)
+			return
+		}
+		// O aceite do contrato, a reserva temporária e a oferta da lista de espera são do cliente logado
+		if !acessoDoCliente(db, r, l.IDCliente) {
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
 
-		inicio, err := time.Parse("2006-01-02", l.DataInicio)
-		if err != nil {
-			log.Printf("Erro ao fazer parse da data de início '%s': %v", l.DataInicio, err)
-			http.Error(w, "Data de início inválida. Use o formato AAAA-MM-DD.", http.StatusBadRequest)
+		// Da verificação da disponibilidade até a gravação ninguém mais pode ocupar o período
+		models.TravarReservas()
+		defer models.DestravarReservas()
+
+		if l.IDEspera != 0 && l.IDReserva != 0 {
+			http.Error(w, "Informe id_espera ou id_reserva, não os dois.", http.StatusBadRequest)
+			return
+		}
+		if l.IDReserva != 0 {
+			reserva, ok := reservaDoCliente(db, w, l.IDReserva, l.IDCliente)
+			if !ok {
+				return
+			}
+			l.IDCarro, l.Categoria = reserva.IDCarro, reserva.Categoria
+			l.DataInicio = reserva.DataInicio.In(models.Fuso).Format("2006-01-02T15:04")
+			l.DataFim = reserva.DataFim.In(models.Fuso).Format("2006-01-02T15:04")
+			l.IDFilialRetirada, l.IDFilialDevolucao = reserva.IDFilialRetirada, reserva.IDFilialDevolucao
+		}
+		if l.IDEspera != 0 {
+			espera, ok := ofertaDoCliente(db, w, l.IDEspera, l.IDCliente)
+			if !ok {
+				return
+			}
+			l.IDCarro, l.Categoria = 0, espera.Categoria
+			l.DataInicio = espera.DataInicio.In(models.Fuso).Format("2006-01-02T15:04")
+			l.DataFim = espera.DataFim.In(models.Fuso).Format("2006-01-02T15:04")
+			l.IDFilialRetirada, l.IDFilialDevolucao = espera.IDFilialRetirada, espera.IDFilialDevolucao
+		}
+
+		inicio, fim, ok := lerPeriodoLocacao(w, l.DataInicio, l.DataFim)
+		if !ok {
 			return
 		}
-		fim, err := time.Parse("2006-01-02", l.DataFim)
+
+		cliente, err := models.GetClienteByID(db, l.IDCliente)
 		if err != nil {
-			log.Printf("Erro ao fazer parse da data de fim '%s': %v", l.DataFim, err)
-			http.Error(w, "Data de fim inválida. Use o formato AAAA-MM-DD.", http.StatusBadRequest)
+			log.Printf("Erro ao buscar cliente ID %d: %v", l.IDCliente, err)
+			http.Error(w, "Cliente não encontrado ou erro ao buscar.", http.StatusBadRequest)
+			return
+		}
+		if err := models.VerificarCNHParaLocacao(cliente, fim); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 			return
 		}
 
-		if inicio.After(fim) {
-			http.Error(w, "A data de início não pode ser depois da data de fim.", http.StatusBadRequest)
+		categoria := l.Categoria
+		filialCarro := 0
+		if l.IDCarro != 0 {
+			carro, err := models.GetCarroByID(db, l.IDCarro)
+			if err != nil {
+				log.Printf("Erro ao buscar carro ID %d: %v", l.IDCarro, err)
+				http.Error(w, "Carro não encontrado ou erro ao buscar.", http.StatusBadRequest)
+				return
+			}
+			if !carro.Disponibilidade {
+				http.Error(w, "Carro atualmente indisponível para locação.", http.StatusConflict) // Status 409 Conflict
+				return
+			}
+			categoria = carro.Categoria
+			filialCarro = carro.IDFilial
+		} else if categoria != "" {
+			if _, err := models.GetCategoriaByCodigo(db, categoria); err != nil {
+				log.Printf("Erro ao buscar categoria '%s': %v", categoria, err)
+				http.Error(w, "Categoria não encontrada.", http.StatusBadRequest)
+				return
+			}
+		} else {
+			http.Error(w, "Informe o carro (id_carro) ou a categoria desejada.", http.StatusBadRequest)
 			return
 		}
 
-		carro, err := models.GetCarroByID(db, l.IDCarro)
+		trajeto, ok := trajetoLocacao(db, w, l.IDFilialRetirada, l.IDFilialDevolucao, filialCarro)
+		if !ok || !horarioAtendimento(db, w, trajeto, inicio, fim) {
+			return
+		}
+
+		livre, err := models.PeriodoDisponivel(db, l.IDCarro, categoria, inicio, fim, trajeto, models.Ignorar{Espera: l.IDEspera, Reserva: l.IDReserva})
+		if err != nil {
+			log.Printf("Erro ao verificar disponibilidade (carro %d, categoria '%s'): %v", l.IDCarro, categoria, err)
+			http.Error(w, "Erro interno ao verificar disponibilidade.", http.StatusInternalServerError)
+			return
+		}
+		if !livre {
+			http.Error(w, "Não há disponibilidade para o período solicitado.", http.StatusConflict)
+			return
+		}
+
+		// Regras de elegibilidade (idade, tempo de habilitação, bloqueio, sobretaxa e caução)
+		regras, err := models.GetRegrasAplicaveis(db, categoria)
 		if err != nil {
-			log.Printf("Erro ao buscar carro ID %d: %v", l.IDCarro, err)
-			http.Error(w, "Carro não encontrado ou erro ao buscar.", http.StatusBadRequest)
+			log.Printf("Erro ao buscar regras de locação para categoria '%s': %v", categoria, err)
+			http.Error(w, "Erro interno ao avaliar regras de locação.", http.StatusInternalServerError)
 			return
 		}
-		if !carro.Disponibilidade {
-			http.Error(w, "Carro atualmente indisponível para locação.", http.StatusConflict) // Status 409 Conflict
+		avaliacao := models.AvaliarRegras(regras, cliente, inicio)
+		if !avaliacao.Aprovada {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]any{
+				"error":   "Locação recusada pelas regras de elegibilidade.",
+				"motivos": avaliacao.Motivos,
+			})
 			return
 		}
 
-		dias := int(fim.Sub(inicio).Hours()/24) + 1
-		if dias <= 0 { // Garantir que a duração seja positiva
-			http.Error(w, "Duração da locação inválida. Deve ser de pelo menos um dia.", http.StatusBadRequest)
+		if !validarExtras(db, w, l.Extras, inicio, fim, 0) {
+			return
+		}
+		if !planoSelecionavel(db, w, l.IDPlano) {
+			return
+		}
+
+		cotacao, err := models.CotarLocacao(db, models.PedidoCotacao{
+			IDCarro:         l.IDCarro,
+			Categoria:       categoria,
+			Inicio:          inicio,
+			Fim:             fim,
+			SobretaxaDiaria: avaliacao.SobretaxaDiaria,
+			Extras:          l.Extras,
+			IDPlano:         l.IDPlano,
+			Trajeto:         trajeto,
+		})
+		if err != nil {
+			log.Printf("Erro ao calcular preço da locação (carro %d, categoria '%s'): %v", l.IDCarro, categoria, err)
+			http.Error(w, "Erro interno ao calcular o valor da locação.", http.StatusInternalServerError)
 			return
 		}
-		valor := float64(dias) * carro.ValorDiaria
 
 		locacao := models.Locacao{
-			IDCliente:  l.IDCliente, // Lembrete: Idealmente, IDCliente viria da sessão
-			IDCarro:    l.IDCarro,
-			DataInicio: inicio,
-			DataFim:    fim,
-			ValorTotal: valor,
-			Status:     "pendente",
+			IDCliente:         l.IDCliente, // Lembrete: Idealmente, IDCliente viria da sessão
+			IDCarro:           l.IDCarro,
+			DataInicio:        inicio,
+			DataFim:           fim,
+			ValorTotal:        cotacao.Total,
+			Status:            "pendente",
+			ValorCaucao:       avaliacao.ValorCaucao,
+			Categoria:         categoria,
+			IDFilialRetirada:  trajeto.Retirada,
+			IDFilialDevolucao: trajeto.Devolucao,
+		}
+		// A franquia fica gravada na locação: mudanças futuras no plano não afetam reservas feitas
+		if cotacao.Plano != nil {
+			locacao.IDPlano = cotacao.Plano.ID
+			locacao.Franquia = cotacao.Plano.Franquia
 		}
-		err = models.CreateLocacao(db, locacao)
+		aceitoEm := time.Now().UTC()
+		idLocacao, err := models.CreateLocacaoComItens(db, locacao, cotacao.Itens, models.Contrato{AceitoEm: &aceitoEm, IPAceite: ipCliente(r)})
 		if err != nil {
 			log.Printf("Erro ao criar locação no banco de dados para carro %d, cliente %d: %v", l.IDCarro, l.IDCliente, err)
 			http.Error(w, "Erro interno ao registrar locação. Tente novamente.", http.StatusInternalServerError)
 			return
 		}
 
-		// Atualiza carro para indisponível
-		carro.Disponibilidade = false
-		err = models.UpdateCarro(db, carro) // Capture o erro do UpdateCarro
-		if err != nil {
-			log.Printf("Atenção: Erro ao atualizar disponibilidade do carro %d após locação: %v", carro.ID, err)
-			// Decide se isso deve ser um erro fatal para o cliente ou apenas um log
-			// Por enquanto, não vamos retornar erro HTTP para o cliente se a locação foi criada.
+		// O carro continua "disponível" (em operação): a ocupação é controlada pelo período das locações
+
+		if l.IDEspera != 0 {
+			if err := models.AtenderEspera(db, l.IDEspera, idLocacao); err != nil {
+				log.Printf("Erro ao marcar oferta %d da lista de espera como atendida: %v", l.IDEspera, err)
+			}
 		}
+		if l.IDReserva != 0 {
+			if err := models.ConsumirReservaTemporaria(db, l.IDReserva, idLocacao); err != nil {
+				log.Printf("Erro ao marcar reserva temporária %d como consumida: %v", l.IDReserva, err)
+			}
+		}
+		atualizarContrato(db, arquivos, idLocacao)
 
 		w.WriteHeader(http.StatusCreated)
 		w.Header().Set("Content-Type", "application/json") // Garante que a resposta é JSON
-		json.NewEncoder(w).Encode(map[string]string{"message": "Locação criada com sucesso!"})
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":     "Locação criada com sucesso!",
+			"id_locacao":  idLocacao,
+			"valor_total": cotacao.Total,
+		})
+	})
+}
+
+// POST /locacoes/cancelar?id=123 - cancela a reserva antes da retirada (o próprio cliente ou admin).
+// A vaga liberada é oferecida à lista de espera.
+func CancelarLocacaoHandler(db *sql.DB, notificador servicos.Notificador) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+
+		locacao, err := models.GetLocacaoByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Locação não encontrada.", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao buscar locação.", http.StatusInternalServerError)
+			return
+		}
+		if !acessoDoCliente(db, r, locacao.IDCliente) {
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
+
+		err = models.CancelarLocacao(db, id)
+		if err == models.ErrLocacaoNaoCancelavel {
+			http.Error(w, err.Error()+". Status atual: "+locacao.Status, http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao cancelar locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao cancelar locação.", http.StatusInternalServerError)
+			return
+		}
+
+		OferecerVagasEspera(db, notificador, locacao.Categoria)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Locação cancelada"})
+	})
+}
+
+// POST /locacoes/{id}/prorrogar - estende a devolução (o próprio cliente ou admin)
+// Body: {"data_fim": "2026-12-30T10:00"}
+// O período estendido precisa estar livre (carro, vaga da categoria e extras) e a nova devolução
+// no expediente da filial. Só o período acrescentado é cobrado (cotação até a nova data menos a
+// cotação do período contratado, ambas com as tarifas de hoje) e fica registrado como item de
+// prorrogação e ajuste; o que faltar pagar aparece no saldo.
+func ProrrogarLocacaoHandler(db *sql.DB, arquivos servicos.Armazenamento) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		var input struct {
+			DataFim string `json:"data_fim"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		novoFim, err := lerDataHora(input.DataFim)
+		if err != nil {
+			http.Error(w, "Data de devolução inválida. Use AAAA-MM-DDTHH:MM.", http.StatusBadRequest)
+			return
+		}
+		novoFim = novoFim.UTC()
+
+		models.TravarReservas()
+		defer models.DestravarReservas()
+
+		locacao, err := models.GetLocacaoByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Locação não encontrada.", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao buscar locação.", http.StatusInternalServerError)
+			return
+		}
+		if !acessoDoCliente(db, r, locacao.IDCliente) {
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
+		if locacao.Status != "pendente" && locacao.Status != "pago" && locacao.Status != "em_andamento" {
+			http.Error(w, models.ErrLocacaoNaoProrrogavel.Error()+". Status atual: "+locacao.Status, http.StatusConflict)
+			return
+		}
+		if !novoFim.After(locacao.DataFim) {
+			http.Error(w, "A nova devolução precisa ser depois da atual.", http.StatusBadRequest)
+			return
+		}
+
+		cliente, err := models.GetClienteByID(db, locacao.IDCliente)
+		if err != nil {
+			log.Printf("Erro ao buscar cliente %d da locação %d: %v", locacao.IDCliente, id, err)
+			http.Error(w, "Erro interno ao buscar cliente.", http.StatusInternalServerError)
+			return
+		}
+		if err := models.VerificarCNHParaLocacao(cliente, novoFim); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		trajeto := locacao.Trajeto()
+		if !horarioAtendimento(db, w, trajeto, time.Time{}, novoFim) {
+			return
+		}
+
+		livre, err := models.PeriodoDisponivel(db, locacao.IDCarro, locacao.Categoria, locacao.DataInicio, novoFim, trajeto, models.Ignorar{Locacao: id})
+		if err != nil {
+			log.Printf("Erro ao verificar disponibilidade da prorrogação da locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao verificar disponibilidade.", http.StatusInternalServerError)
+			return
+		}
+		if !livre {
+			http.Error(w, "Não há disponibilidade para estender a locação até a data pedida.", http.StatusConflict)
+			return
+		}
+
+		pedido, err := models.PedidoDaLocacao(db, locacao, locacao.DataInicio, locacao.DataFim)
+		if err != nil {
+			log.Printf("Erro ao buscar itens da locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao calcular o valor da locação.", http.StatusInternalServerError)
+			return
+		}
+		if !validarExtras(db, w, pedido.Extras, locacao.DataInicio, novoFim, id) {
+			return
+		}
+		// Os dois períodos são cotados com as tarifas de hoje e só a diferença é cobrada: o que
+		// já foi acertado na reserva não muda
+		original, err := models.CotarLocacao(db, pedido)
+		if err != nil {
+			log.Printf("Erro ao recotar a locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao calcular o valor da locação.", http.StatusInternalServerError)
+			return
+		}
+		pedido.Fim = novoFim
+		estendida, err := models.CotarLocacao(db, pedido)
+		if err != nil {
+			log.Printf("Erro ao recotar a locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao calcular o valor da locação.", http.StatusInternalServerError)
+			return
+		}
+
+		item, err := models.ProrrogarLocacao(db, locacao, novoFim, original, estendida)
+		if err == models.ErrLocacaoNaoProrrogavel {
+			http.Error(w, "A locação foi alterada durante a prorrogação. Tente novamente.", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao prorrogar locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao prorrogar locação.", http.StatusInternalServerError)
+			return
+		}
+		var diferenca models.Dinheiro
+		if item != nil {
+			diferenca = item.Valor
+		}
+		saldo, err := models.SaldoLocacao(db, id)
+		if err != nil {
+			log.Printf("Erro ao calcular saldo da locação %d: %v", id, err)
+			http.Error(w, "Locação prorrogada, mas houve erro ao calcular o saldo.", http.StatusInternalServerError)
+			return
+		}
+		atualizarContrato(db, arquivos, id)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":     "Locação prorrogada",
+			"data_fim":    novoFim,
+			"valor_total": locacao.ValorTotal + diferenca,
+			"diferenca":   diferenca,
+			"item":        item,
+			"saldo":       saldo,
+		})
 	})
 }
 
@@ -144,6 +508,10 @@ func MinhasLocacoesHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "ID do cliente inválido. Deve ser um número.", http.StatusBadRequest)
 			return
 		}
+		if !acessoDoCliente(db, r, id) {
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
 
 		// Melhoria futura: filtrar direto no banco de dados, não aqui em memória
 		todas, err := models.GetAllLocacoes(db)
@@ -152,10 +520,21 @@ func MinhasLocacoesHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "Erro interno ao buscar suas locações.", http.StatusInternalServerError)
 			return
 		}
-		var minhas []models.Locacao
+		// Cada locação vai acompanhada dos seus itens (diárias, extras, descontos...)
+		type locacaoComItens struct {
+			models.Locacao
+			Itens []models.ItemLocacao
+		}
+		var minhas []locacaoComItens
 		for _, l := range todas {
 			if l.IDCliente == id {
-				minhas = append(minhas, l)
+				itens, err := models.GetItensLocacao(db, l.ID)
+				if err != nil {
+					log.Printf("Erro ao buscar itens da locação %d: %v", l.ID, err)
+					http.Error(w, "Erro interno ao buscar suas locações.", http.StatusInternalServerError)
+					return
+				}
+				minhas = append(minhas, locacaoComItens{l, itens})
 			}
 		}
 
@@ -163,3 +542,129 @@ func MinhasLocacoesHandler(db *sql.DB) http.HandlerFunc {
 		json.NewEncoder(w).Encode(minhas)
 	})
 }
+
+// POST /locacoes/retirada?id=123 - registra a retirada do carro (admin).
+// Locações feitas por categoria recebem aqui o carro específico.
+// Body opcional com a leitura do painel: {"quilometragem": 15000, "combustivel": 8}
+func RetiradaLocacaoHandler(db *sql.DB, gateway servicos.GatewayPagamento, arquivos servicos.Armazenamento) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+
+		locacao, err := models.GetLocacaoByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Locação não encontrada.", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar locação %d: %v", id, err)
+			http.Error(w, "Erro interno ao buscar locação.", http.StatusInternalServerError)
+			return
+		}
+		if locacao.Status != "pendente" && locacao.Status != "pago" {
+			http.Error(w, "Locação não pode ser retirada no status atual: "+locacao.Status, http.StatusConflict)
+			return
+		}
+
+		var leitura leituraPainel
+		if err := json.NewDecoder(r.Body).Decode(&leitura); err != nil && err != io.EOF {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		if !leitura.valida(w) {
+			return
+		}
+
+		if locacao.IDCarro == 0 {
+			// Escolha e gravação sob a trava, para que duas retiradas simultâneas da categoria não
+			// recebam o mesmo carro; a pré-autorização da caução fica fora dela
+			models.TravarReservas()
+			idCarro, err := models.EscolherCarro(db, locacao.Categoria, locacao.DataInicio, locacao.DataFim, locacao.Trajeto(), models.Ignorar{Locacao: locacao.ID})
+			if err == nil {
+				err = models.AtribuirCarro(db, locacao.ID, idCarro)
+			}
+			models.DestravarReservas()
+			if err == models.ErrSemCarroDisponivel {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if err != nil {
+				log.Printf("Erro ao atribuir carro à locação %d: %v", id, err)
+				http.Error(w, "Erro interno ao atribuir carro.", http.StatusInternalServerError)
+				return
+			}
+			locacao.IDCarro = idCarro
+		}
+
+		// Sem leitura no body, vale o último estado registrado do carro
+		carro, err := models.GetCarroByID(db, locacao.IDCarro)
+		if err != nil {
+			log.Printf("Erro ao buscar carro %d da locação %d: %v", locacao.IDCarro, id, err)
+			http.Error(w, "Erro interno ao buscar carro.", http.StatusInternalServerError)
+			return
+		}
+		vistoria := &models.Vistoria{
+			IDLocacao:     locacao.ID,
+			IDCarro:       locacao.IDCarro,
+			Tipo:          models.VistoriaRetirada,
+			DataHora:      time.Now().UTC(),
+			Quilometragem: carro.Quilometragem,
+			Combustivel:   carro.NivelCombustivel,
+		}
+		if leitura.Quilometragem != nil {
+			if *leitura.Quilometragem < carro.Quilometragem {
+				http.Error(w, fmt.Sprintf("Quilometragem menor que a última registrada para o carro (%d km).", carro.Quilometragem), http.StatusBadRequest)
+				return
+			}
+			vistoria.Quilometragem = *leitura.Quilometragem
+		}
+		if leitura.Combustivel != nil {
+			vistoria.Combustivel = *leitura.Combustivel
+		}
+
+		// A caução é pré-autorizada no cartão na retirada e só é capturada ou liberada na devolução.
+		// Fica por último: daqui em diante toda falha precisa liberar a pré-autorização.
+		var caucao *models.Pagamento
+		if locacao.ValorCaucao > 0 {
+			ref, err := gateway.PreAutorizar(locacao.ValorCaucao, fmt.Sprintf("Caução da locação %d", locacao.ID))
+			if err != nil {
+				log.Printf("Erro ao pré-autorizar caução da locação %d: %v", id, err)
+				http.Error(w, "Não foi possível pré-autorizar a caução no cartão.", http.StatusPaymentRequired)
+				return
+			}
+			caucao = &models.Pagamento{
+				IDLocacao:         locacao.ID,
+				DataPagamento:     time.Now(),
+				ValorPago:         locacao.ValorCaucao,
+				FormaPagamento:    "cartao",
+				StatusPagamento:   models.CaucaoAutorizada,
+				Tipo:              models.PagamentoCaucaoBloqueio,
+				ReferenciaGateway: ref,
+			}
+		}
+
+		locacao.Status = "em_andamento"
+		if err := models.RegistrarRetirada(db, locacao, vistoria, caucao); err != nil {
+			log.Printf("Erro ao registrar retirada da locação %d: %v", id, err)
+			if caucao != nil {
+				if err := gateway.Liberar(caucao.ReferenciaGateway); err != nil {
+					log.Printf("Erro ao desfazer pré-autorização %s: %v", caucao.ReferenciaGateway, err)
+				}
+			}
+			http.Error(w, "Erro interno ao registrar retirada.", http.StatusInternalServerError)
+			return
+		}
+		// A via do contrato passa a trazer o carro e a placa entregues
+		atualizarContrato(db, arquivos, id)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"message": "Retirada registrada", "id_carro": locacao.IDCarro, "caucao": locacao.ValorCaucao})
+	})
+}
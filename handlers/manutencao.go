@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// GET /manutencao/planos - planos de manutenção preventiva (admin)
+func ListarPlanosManutencaoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		planos, err := models.GetAllPlanosManutencao(db)
+		if err != nil {
+			log.Printf("Erro ao buscar planos de manutenção: %v", err)
+			http.Error(w, "Erro ao buscar planos de manutenção", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(planos)
+	})
+}
+
+// POST /manutencao/planos/criar - criar plano de manutenção (admin)
+// Body: {"descricao": "Troca de óleo", "intervalo_km": 10000, "intervalo_meses": 6, "categoria": ""}
+func CriarPlanoManutencaoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		p := models.PlanoManutencao{Ativo: true}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !planoManutencaoValido(db, w, p) {
+			return
+		}
+		if err := models.CreatePlanoManutencao(db, p); err != nil {
+			log.Printf("Erro ao criar plano de manutenção: %v", err)
+			http.Error(w, "Erro ao criar plano de manutenção (a descrição já existe?)", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"Plano de manutenção criado com sucesso"}`))
+	})
+}
+
+// PUT /manutencao/planos/atualizar?id=1 - atualizar plano de manutenção (admin)
+func AtualizarPlanoManutencaoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		var p models.PlanoManutencao
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		p.ID = id
+		if !planoManutencaoValido(db, w, p) {
+			return
+		}
+		if err := models.UpdatePlanoManutencao(db, p); err != nil {
+			log.Printf("Erro ao atualizar plano de manutenção %d: %v", id, err)
+			http.Error(w, "Erro ao atualizar plano de manutenção", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// POST /manutencao/planos/deletar?id=1 - remover plano de manutenção (admin)
+func DeletarPlanoManutencaoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if err := models.DeletePlanoManutencao(db, id); err != nil {
+			log.Printf("Erro ao deletar plano de manutenção %d: %v", id, err)
+			http.Error(w, "Erro ao deletar plano de manutenção (se já tem ordens, desative-o)", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func planoManutencaoValido(db *sql.DB, w http.ResponseWriter, p models.PlanoManutencao) bool {
+	if p.Descricao == "" {
+		http.Error(w, "Descrição é obrigatória", http.StatusBadRequest)
+		return false
+	}
+	if p.IntervaloKm < 0 || p.IntervaloMeses < 0 || (p.IntervaloKm == 0 && p.IntervaloMeses == 0) {
+		http.Error(w, "Informe intervalo_km e/ou intervalo_meses (positivos)", http.StatusBadRequest)
+		return false
+	}
+	return p.Categoria == "" || categoriaExiste(db, w, p.Categoria)
+}
+
+// GET /manutencao/ordens[?id_carro=1] - ordens de manutenção (admin)
+func ListarOrdensManutencaoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		idCarro, _ := strconv.Atoi(r.URL.Query().Get("id_carro"))
+		ordens, err := models.GetOrdensManutencao(db, idCarro)
+		if err != nil {
+			log.Printf("Erro ao buscar ordens de manutenção: %v", err)
+			http.Error(w, "Erro ao buscar ordens de manutenção", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ordens)
+	})
+}
+
+// POST /manutencao/ordens/criar - agenda uma manutenção (admin)
+// Body: {"id_carro": 1, "id_plano": 1, "descricao": "...", "oficina": "...",
+// "data_inicio": "AAAA-MM-DD", "data_fim": "AAAA-MM-DD", "custo": "350.00"}
+// O carro fica indisponível para locação no período enquanto a ordem estiver aberta.
+func CriarOrdemManutencaoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		var input struct {
+			IDCarro    int             `json:"id_carro"`
+			IDPlano    int             `json:"id_plano"`
+			Descricao  string          `json:"descricao"`
+			Oficina    string          `json:"oficina"`
+			DataInicio string          `json:"data_inicio"`
+			DataFim    string          `json:"data_fim"`
+			Custo      models.Dinheiro `json:"custo"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		inicio, fim, ok := lerPeriodo(w, input.DataInicio, input.DataFim)
+		if !ok {
+			return
+		}
+		if input.Descricao == "" || input.Custo < 0 {
+			http.Error(w, "Descrição é obrigatória e o custo não pode ser negativo", http.StatusBadRequest)
+			return
+		}
+		if _, err := models.GetCarroByID(db, input.IDCarro); err != nil {
+			http.Error(w, "Carro não encontrado", http.StatusBadRequest)
+			return
+		}
+		if input.IDPlano != 0 {
+			if _, err := models.GetPlanoManutencaoByID(db, input.IDPlano); err != nil {
+				http.Error(w, "Plano de manutenção não encontrado", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Ninguém reserva o carro ou a categoria entre a verificação e a gravação da ordem
+		models.TravarReservas()
+		defer models.DestravarReservas()
+
+		possivel, err := models.ManutencaoPossivel(db, input.IDCarro, inicio, fim)
+		if err != nil {
+			log.Printf("Erro ao verificar agenda do carro %d: %v", input.IDCarro, err)
+			http.Error(w, "Erro interno ao verificar agenda do carro", http.StatusInternalServerError)
+			return
+		}
+		if !possivel {
+			http.Error(w, "O período conflita com locações ou reservas do carro, ou deixaria reservas da categoria sem carro", http.StatusConflict)
+			return
+		}
+
+		ordem := models.OrdemManutencao{
+			IDCarro:    input.IDCarro,
+			IDPlano:    input.IDPlano,
+			Descricao:  input.Descricao,
+			Oficina:    input.Oficina,
+			DataInicio: inicio,
+			DataFim:    fim,
+			Custo:      input.Custo,
+			Status:     models.OrdemAgendada,
+		}
+		if err := models.CreateOrdem(db, ordem); err != nil {
+			log.Printf("Erro ao criar ordem de manutenção: %v", err)
+			http.Error(w, "Erro ao criar ordem de manutenção", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"Manutenção agendada com sucesso"}`))
+	})
+}
+
+// PUT /manutencao/ordens/atualizar?id=1 - andamento da ordem (admin)
+// Aceita descricao, oficina, custo, status e quilometragem; as datas não mudam
+// (para remarcar, cancele e agende outra). Ao concluir sem quilometragem, usa o odômetro atual do carro.
+func AtualizarOrdemManutencaoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		ordem, err := models.GetOrdemByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Ordem não encontrada", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar ordem de manutenção %d: %v", id, err)
+			http.Error(w, "Erro ao buscar ordem de manutenção", http.StatusInternalServerError)
+			return
+		}
+		if ordem.Status == models.OrdemConcluida || ordem.Status == models.OrdemCancelada {
+			http.Error(w, "Ordem já encerrada", http.StatusConflict)
+			return
+		}
+
+		var input struct {
+			Descricao     *string          `json:"descricao"`
+			Oficina       *string          `json:"oficina"`
+			Custo         *models.Dinheiro `json:"custo"`
+			Status        *string          `json:"status"`
+			Quilometragem *int             `json:"quilometragem"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		if input.Descricao != nil {
+			ordem.Descricao = *input.Descricao
+		}
+		if input.Oficina != nil {
+			ordem.Oficina = *input.Oficina
+		}
+		if input.Custo != nil {
+			ordem.Custo = *input.Custo
+		}
+		if input.Quilometragem != nil {
+			ordem.Quilometragem = *input.Quilometragem
+		}
+		if input.Status != nil {
+			switch *input.Status {
+			case models.OrdemAgendada, models.OrdemEmExecucao, models.OrdemConcluida, models.OrdemCancelada:
+				ordem.Status = *input.Status
+			default:
+				http.Error(w, "status deve ser agendada, em_execucao, concluida ou cancelada", http.StatusBadRequest)
+				return
+			}
+		}
+		if ordem.Custo < 0 || ordem.Quilometragem < 0 {
+			http.Error(w, "Custo e quilometragem não podem ser negativos", http.StatusBadRequest)
+			return
+		}
+
+		if ordem.Status == models.OrdemConcluida && ordem.Quilometragem == 0 {
+			carro, err := models.GetCarroByID(db, ordem.IDCarro)
+			if err != nil {
+				log.Printf("Erro ao buscar carro %d: %v", ordem.IDCarro, err)
+				http.Error(w, "Erro ao buscar carro da ordem", http.StatusInternalServerError)
+				return
+			}
+			ordem.Quilometragem = carro.Quilometragem
+		}
+
+		if err := models.UpdateOrdem(db, ordem); err != nil {
+			log.Printf("Erro ao atualizar ordem de manutenção %d: %v", id, err)
+			http.Error(w, "Erro ao atualizar ordem de manutenção", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ordem)
+	})
+}
+
+// GET /manutencao/alertas - carros com manutenção preventiva vencida ou próxima (admin)
+func AlertasManutencaoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		margemKm, errKm := models.ConfigInt(db, models.ConfigManutencaoMargemKm)
+		margemDias, errDias := models.ConfigInt(db, models.ConfigManutencaoMargemDias)
+		if errKm != nil || errDias != nil {
+			log.Printf("Erro ao ler margens de alerta de manutenção: %v %v", errKm, errDias)
+			http.Error(w, "Erro ao calcular alertas de manutenção", http.StatusInternalServerError)
+			return
+		}
+		alertas, err := models.AlertasManutencao(db, time.Now(), margemKm, margemDias)
+		if err != nil {
+			log.Printf("Erro ao calcular alertas de manutenção: %v", err)
+			http.Error(w, "Erro ao calcular alertas de manutenção", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(alertas)
+	})
+}
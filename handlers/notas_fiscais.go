@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+var errPrestadorIncompleto = errors.New("configure CNPJ, inscrição municipal e razão social do prestador (nfse_*) antes de emitir notas")
+
+// emitirNotaFiscal gera o RPS da locação finalizada, grava o XML assinado no armazenamento
+// privado e transmite para a prefeitura. Uma rejeição fica registrada na nota e volta como erro.
+func emitirNotaFiscal(db *sql.DB, arquivos servicos.Armazenamento, autoridade servicos.AutoridadeFiscal,
+	certificado servicos.Certificado, idLocacao int) (models.NotaFiscal, error) {
+	prestador, err := models.CarregarPrestadorNFSe(db)
+	if err != nil {
+		return models.NotaFiscal{}, err
+	}
+	if !prestador.Completo() {
+		return models.NotaFiscal{}, errPrestadorIncompleto
+	}
+	locacao, err := models.GetLocacaoByID(db, idLocacao)
+	if err != nil {
+		return models.NotaFiscal{}, err
+	}
+	nota, aliquota, err := models.NovaNotaFiscal(db, locacao, prestador.SerieRPS)
+	if err != nil || nota.Status == models.NotaAutorizada {
+		return nota, err
+	}
+	cliente, err := models.GetClienteByID(db, locacao.IDCliente)
+	if err != nil {
+		return nota, err
+	}
+	itens, err := models.GetItensLocacao(db, locacao.ID)
+	if err != nil {
+		return nota, err
+	}
+
+	rps := servicos.RPS{Nota: nota, Aliquota: aliquota, Prestador: prestador, Tomador: cliente,
+		Discriminacao: discriminacaoServico(locacao, itens)}
+	xml, err := rps.XML(certificado)
+	if err != nil {
+		return nota, err
+	}
+	nota.Arquivo = fmt.Sprintf("nfse/rps-%s-%06d.xml", nota.SerieRPS, nota.NumeroRPS)
+	if _, err := arquivos.Salvar(nota.Arquivo, bytes.NewReader(xml)); err != nil {
+		return nota, err
+	}
+
+	retorno, err := autoridade.Transmitir(xml)
+	var rejeicao servicos.RejeicaoNFSe
+	switch {
+	case err == nil:
+		nota.Status, nota.Mensagem = models.NotaAutorizada, ""
+		nota.NumeroNFSe, nota.CodigoVerificacao, nota.Protocolo = retorno.Numero, retorno.CodigoVerificacao, retorno.Protocolo
+	case errors.As(err, &rejeicao):
+		nota.Status, nota.Mensagem = models.NotaRejeitada, rejeicao.Error()
+	default:
+		// Falha de comunicação: o RPS continua pendente e é reenviado na próxima emissão
+		nota.Mensagem = err.Error()
+	}
+	if errAtualizar := models.AtualizarNotaFiscal(db, nota); errAtualizar != nil {
+		return nota, errAtualizar
+	}
+	return nota, err
+}
+
+// discriminacaoServico descreve a locação e cada item cobrado, como sai impresso na nota
+func discriminacaoServico(l models.Locacao, itens []models.ItemLocacao) string {
+	partes := []string{fmt.Sprintf("Locação de veículo nº %d de %s a %s",
+		l.ID, l.DataInicio.In(models.Fuso).Format("02/01/2006"), l.DataFim.In(models.Fuso).Format("02/01/2006"))}
+	for _, item := range itens {
+		partes = append(partes, fmt.Sprintf("%s (%d x %s): %s", item.Descricao, item.Quantidade, item.ValorUnitario.Formatar(), item.Valor.Formatar()))
+	}
+	return strings.Join(partes, "; ")
+}
+
+// EmitirNotasPendentes emite as notas de todas as locações finalizadas ainda sem NFS-e
+// autorizada. Devolve quantas foram autorizadas e os erros por locação.
+func EmitirNotasPendentes(db *sql.DB, arquivos servicos.Armazenamento, autoridade servicos.AutoridadeFiscal,
+	certificado servicos.Certificado) (int, map[int]string) {
+	erros := map[int]string{}
+	ids, err := models.LocacoesSemNotaAutorizada(db)
+	if err != nil {
+		log.Printf("Erro ao buscar locações sem nota fiscal: %v", err)
+		erros[0] = "erro interno ao buscar locações"
+		return 0, erros
+	}
+	autorizadas := 0
+	for _, id := range ids {
+		if _, err := emitirNotaFiscal(db, arquivos, autoridade, certificado, id); err != nil {
+			log.Printf("Erro ao emitir nota fiscal da locação %d: %v", id, err)
+			erros[id] = err.Error()
+			if err == errPrestadorIncompleto {
+				break
+			}
+			continue
+		}
+		autorizadas++
+	}
+	return autorizadas, erros
+}
+
+// POST /notas-fiscais/emitir?id_locacao=12 - emite a NFS-e de uma locação finalizada (admin)
+// Sem id_locacao, emite para todas as locações finalizadas ainda sem nota autorizada.
+func EmitirNotaFiscalHandler(db *sql.DB, arquivos servicos.Armazenamento, autoridade servicos.AutoridadeFiscal,
+	certificado servicos.Certificado) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.URL.Query().Get("id_locacao") == "" {
+			autorizadas, erros := EmitirNotasPendentes(db, arquivos, autoridade, certificado)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"autorizadas": autorizadas, "erros": erros})
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id_locacao"))
+		if err != nil {
+			http.Error(w, "id_locacao inválido", http.StatusBadRequest)
+			return
+		}
+
+		nota, err := emitirNotaFiscal(db, arquivos, autoridade, certificado, id)
+		var rejeicao servicos.RejeicaoNFSe
+		switch {
+		case err == sql.ErrNoRows:
+			http.Error(w, "Locação não encontrada.", http.StatusNotFound)
+			return
+		case err == errPrestadorIncompleto:
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case errors.Is(err, models.ErrLocacaoNaoFinalizada), errors.Is(err, models.ErrSemAliquotaISS):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case errors.As(err, &rejeicao):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(nota)
+			return
+		case err != nil:
+			log.Printf("Erro ao emitir nota fiscal da locação %d: %v", id, err)
+			http.Error(w, "Erro ao emitir nota fiscal; o RPS fica pendente para reenvio.", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(nota)
+	})
+}
+
+// GET /notas-fiscais?status=pendente|autorizada|rejeitada - lista as notas (admin)
+func ListarNotasFiscaisHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		status := r.URL.Query().Get("status")
+		if status != "" && status != models.NotaPendente && status != models.NotaAutorizada && status != models.NotaRejeitada {
+			http.Error(w, "Status inválido. Use pendente, autorizada ou rejeitada.", http.StatusBadRequest)
+			return
+		}
+		notas, err := models.GetNotasFiscais(db, status)
+		if err != nil {
+			log.Printf("Erro ao buscar notas fiscais: %v", err)
+			http.Error(w, "Erro ao buscar notas fiscais", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(notas)
+	})
+}
+
+// GET /notas-fiscais/{numero}/xml - XML assinado do RPS, para a contabilidade (admin)
+func XMLNotaFiscalHandler(db *sql.DB, arquivos servicos.Armazenamento) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		numero, err := strconv.Atoi(r.PathValue("numero"))
+		if err != nil {
+			http.Error(w, "Número inválido", http.StatusBadRequest)
+			return
+		}
+		nota, err := models.GetNotaFiscal(db, numero)
+		if err == sql.ErrNoRows || (err == nil && nota.Arquivo == "") {
+			http.Error(w, "Nota fiscal não encontrada.", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar nota fiscal %d: %v", numero, err)
+			http.Error(w, "Erro interno ao buscar nota fiscal.", http.StatusInternalServerError)
+			return
+		}
+		f, err := arquivos.Abrir(nota.Arquivo)
+		if err != nil {
+			log.Printf("Erro ao abrir XML da nota fiscal %d (%s): %v", numero, nota.Arquivo, err)
+			http.Error(w, "Erro interno ao ler o XML da nota fiscal.", http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		w.Header().Set("Content-Type", "application/xml")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="rps-%s-%06d.xml"`, nota.SerieRPS, nota.NumeroRPS))
+		io.Copy(w, f)
+	})
+}
+
+// GET /aliquotas-iss - alíquotas cadastradas
+// PUT /aliquotas-iss - cria ou altera a alíquota de um município (admin)
+// Body: {"codigo_municipio": "3509502", "cidade": "Campinas", "uf": "SP", "aliquota": 5, "item_lista_servico": "3.01"}
+func AliquotasISSHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			aliquotas, err := models.GetAliquotasISS(db)
+			if err != nil {
+				log.Printf("Erro ao buscar alíquotas de ISS: %v", err)
+				http.Error(w, "Erro ao buscar alíquotas de ISS", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(aliquotas)
+		case http.MethodPut:
+			var a models.AliquotaISS
+			if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+				http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			var ev models.ErroValidacao
+			if err := models.ValidarAliquotaISS(a); errors.As(err, &ev) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(map[string]string{"error": ev.Mensagem, "campo": ev.Campo})
+				return
+			}
+			if err := models.SalvarAliquotaISS(db, a); err != nil {
+				log.Printf("Erro ao gravar alíquota de ISS de %s: %v", a.CodigoMunicipio, err)
+				http.Error(w, "Erro ao gravar alíquota de ISS", http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`{"message":"Alíquota gravada com sucesso"}`))
+		default:
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,6 +13,7 @@ import (
 )
 
 // POST /pagamento - realiza pagamento (cliente)
+// Responde com o pagamento e o número do recibo (GET /pagamentos/{id}/recibo)
 func RealizarPagamentoHandler(db *sql.DB) http.HandlerFunc {
 	return AuthMiddleware(db, []string{"cliente"}, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -18,9 +21,9 @@ func RealizarPagamentoHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 		var input struct {
-			IDLocacao      int     `json:"id_locacao"`
-			ValorPago      float64 `json:"valor_pago"`
-			FormaPagamento string  `json:"forma_pagamento"`
+			IDLocacao      int             `json:"id_locacao"`
+			ValorPago      models.Dinheiro `json:"valor_pago"`
+			FormaPagamento string          `json:"forma_pagamento"`
 		}
 		err := json.NewDecoder(r.Body).Decode(&input)
 		if err != nil {
@@ -36,20 +39,31 @@ func RealizarPagamentoHandler(db *sql.DB) http.HandlerFunc {
 			StatusPagamento: "confirmado",
 		}
 
-		err = models.CreatePagamento(db, pagamento)
+		idPagamento, err := models.CreatePagamento(db, pagamento)
 		if err != nil {
 			http.Error(w, "Erro ao salvar pagamento", 500)
 			return
 		}
 
-		// Atualiza status da locação para 'pago'
+		// Atualiza status da locação para 'pago' (só antes da retirada; depois o status segue o fluxo da locação)
 		locacao, err := models.GetLocacaoByID(db, input.IDLocacao)
-		if err == nil {
+		if err == nil && locacao.Status == "pendente" {
 			locacao.Status = "pago"
 			_ = models.UpdateLocacao(db, locacao)
 		}
 
+		recibo, err := models.GetRecibo(db, idPagamento)
+		if err != nil {
+			log.Printf("Erro ao buscar recibo do pagamento %d: %v", idPagamento, err)
+		}
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":       "Pagamento registrado",
+			"id_pagamento":  idPagamento,
+			"numero_recibo": recibo.Numero,
+			"recibo":        fmt.Sprintf("/pagamentos/%d/recibo", idPagamento),
+		})
 	})
 }
 
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// GET /planos - listar planos de proteção (cliente e admin)
+func ListarPlanosHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		planos, err := models.GetAllPlanos(db)
+		if err != nil {
+			log.Printf("Erro ao buscar planos de proteção: %v", err)
+			http.Error(w, "Erro ao buscar planos de proteção", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(planos)
+	})
+}
+
+// POST /planos/criar - criar plano de proteção (admin)
+func CriarPlanoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		p := models.PlanoProtecao{Ativo: true}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !planoValido(w, p) {
+			return
+		}
+
+		if err := models.CreatePlano(db, p); err != nil {
+			log.Printf("Erro ao criar plano de proteção: %v", err)
+			http.Error(w, "Erro ao criar plano de proteção (o código já existe?)", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"Plano de proteção criado com sucesso"}`))
+	})
+}
+
+// PUT /planos/atualizar?id=1 - atualizar plano de proteção (admin)
+// Locações já feitas mantêm a franquia gravada no momento da reserva.
+func AtualizarPlanoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+
+		var p models.PlanoProtecao
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		p.ID = id
+		if !planoValido(w, p) {
+			return
+		}
+
+		if err := models.UpdatePlano(db, p); err != nil {
+			log.Printf("Erro ao atualizar plano de proteção %d: %v", id, err)
+			http.Error(w, "Erro ao atualizar plano de proteção", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// POST /planos/deletar?id=1 - remover plano de proteção (admin)
+func DeletarPlanoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if err := models.DeletePlano(db, id); err != nil {
+			log.Printf("Erro ao deletar plano de proteção %d: %v", id, err)
+			http.Error(w, "Erro ao deletar plano de proteção (se já foi usado em locações, desative-o)", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func planoValido(w http.ResponseWriter, p models.PlanoProtecao) bool {
+	if p.Codigo == "" || p.Nome == "" {
+		http.Error(w, "Código e nome são obrigatórios", http.StatusBadRequest)
+		return false
+	}
+	if p.ValorDiaria < 0 || p.Franquia < 0 {
+		http.Error(w, "Diária e franquia não podem ser negativas", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// planoSelecionavel confere o plano escolhido pelo cliente; 0 significa o plano padrão
+func planoSelecionavel(db *sql.DB, w http.ResponseWriter, id int) bool {
+	if id == 0 {
+		return true
+	}
+	plano, err := models.GetPlanoByID(db, id)
+	if err == sql.ErrNoRows || (err == nil && !plano.Ativo) {
+		http.Error(w, "Plano de proteção não encontrado ou inativo.", http.StatusBadRequest)
+		return false
+	}
+	if err != nil {
+		log.Printf("Erro ao buscar plano de proteção %d: %v", id, err)
+		http.Error(w, "Erro interno ao verificar o plano de proteção.", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
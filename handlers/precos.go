@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// GET /cotacao?id_carro=1|categoria=suv&data_inicio=AAAA-MM-DDTHH:MM&data_fim=AAAA-MM-DDTHH:MM[&id_cliente=1][&extras=1:2,4:1][&id_plano=2]
+// Retorna a cotação itemizada, com uma diária a cada 24 horas a partir da retirada. Com id_cliente, aplica também as regras de elegibilidade.
+// extras é uma lista id_extra:quantidade separada por vírgulas. Sem id_plano, cota o plano padrão.
+func CotacaoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+
+		inicio, fim, ok := lerPeriodoLocacao(w, q.Get("data_inicio"), q.Get("data_fim"))
+		if !ok {
+			return
+		}
+
+		idCarro, _ := strconv.Atoi(q.Get("id_carro"))
+		categoria := q.Get("categoria")
+		filialCarro := 0
+		if idCarro == 0 && categoria == "" {
+			http.Error(w, "Informe id_carro ou categoria.", http.StatusBadRequest)
+			return
+		}
+		if idCarro != 0 {
+			carro, err := models.GetCarroByID(db, idCarro)
+			if err != nil {
+				http.Error(w, "Carro não encontrado.", http.StatusBadRequest)
+				return
+			}
+			categoria = carro.Categoria
+			filialCarro = carro.IDFilial
+		}
+		idRetirada, _ := strconv.Atoi(q.Get("id_filial_retirada"))
+		idDevolucao, _ := strconv.Atoi(q.Get("id_filial_devolucao"))
+		trajeto, ok := trajetoLocacao(db, w, idRetirada, idDevolucao, filialCarro)
+		if !ok {
+			return
+		}
+
+		extras, err := lerExtras(q.Get("extras"))
+		if err != nil {
+			http.Error(w, "Parâmetro extras inválido. Use id_extra:quantidade separados por vírgula.", http.StatusBadRequest)
+			return
+		}
+		if !validarExtras(db, w, extras, inicio, fim, 0) {
+			return
+		}
+		idPlano, _ := strconv.Atoi(q.Get("id_plano"))
+		if !planoSelecionavel(db, w, idPlano) {
+			return
+		}
+
+		var avaliacao *models.AvaliacaoLocacao
+		if idCliente, err := strconv.Atoi(q.Get("id_cliente")); err == nil {
+			cliente, err := models.GetClienteByID(db, idCliente)
+			if err != nil {
+				http.Error(w, "Cliente não encontrado.", http.StatusBadRequest)
+				return
+			}
+			regras, err := models.GetRegrasAplicaveis(db, categoria)
+			if err != nil {
+				log.Printf("Erro ao buscar regras de locação para categoria '%s': %v", categoria, err)
+				http.Error(w, "Erro interno ao avaliar regras de locação.", http.StatusInternalServerError)
+				return
+			}
+			av := models.AvaliarRegras(regras, cliente, inicio)
+			avaliacao = &av
+		}
+
+		var sobretaxa models.Dinheiro
+		if avaliacao != nil {
+			sobretaxa = avaliacao.SobretaxaDiaria
+		}
+		cotacao, err := models.CotarLocacao(db, models.PedidoCotacao{
+			IDCarro:         idCarro,
+			Categoria:       categoria,
+			Inicio:          inicio,
+			Fim:             fim,
+			SobretaxaDiaria: sobretaxa,
+			Extras:          extras,
+			IDPlano:         idPlano,
+			Trajeto:         trajeto,
+		})
+		if err == sql.ErrNoRows {
+			http.Error(w, "Categoria não encontrada.", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao calcular cotação (carro %d, categoria '%s'): %v", idCarro, categoria, err)
+			http.Error(w, "Erro interno ao calcular cotação.", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			models.Cotacao
+			Avaliacao *models.AvaliacaoLocacao `json:"avaliacao,omitempty"`
+		}{cotacao, avaliacao})
+	})
+}
+
+// lerPeriodo valida data_inicio e data_fim (AAAA-MM-DD), respondendo 400 se inválidas
+func lerPeriodo(w http.ResponseWriter, dataInicio, dataFim string) (time.Time, time.Time, bool) {
+	inicio, err := time.Parse("2006-01-02", dataInicio)
+	if err != nil {
+		http.Error(w, "Data de início inválida. Use o formato AAAA-MM-DD.", http.StatusBadRequest)
+		return inicio, inicio, false
+	}
+	fim, err := time.Parse("2006-01-02", dataFim)
+	if err != nil {
+		http.Error(w, "Data de fim inválida. Use o formato AAAA-MM-DD.", http.StatusBadRequest)
+		return inicio, fim, false
+	}
+	if inicio.After(fim) {
+		http.Error(w, "A data de início não pode ser depois da data de fim.", http.StatusBadRequest)
+		return inicio, fim, false
+	}
+	return inicio, fim, true
+}
+
+// lerPeriodoLocacao valida a retirada e a devolução (AAAA-MM-DDTHH:MM, horário local das
+// filiais), respondendo 400 se inválidas. A devolução precisa ser depois da retirada.
+func lerPeriodoLocacao(w http.ResponseWriter, dataInicio, dataFim string) (time.Time, time.Time, bool) {
+	inicio, err := lerDataHora(dataInicio)
+	if err != nil {
+		http.Error(w, "Data de início inválida. Use o formato AAAA-MM-DDTHH:MM.", http.StatusBadRequest)
+		return inicio, inicio, false
+	}
+	fim, err := lerDataHora(dataFim)
+	if err != nil {
+		http.Error(w, "Data de fim inválida. Use o formato AAAA-MM-DDTHH:MM.", http.StatusBadRequest)
+		return inicio, fim, false
+	}
+	if !fim.After(inicio) {
+		http.Error(w, "A devolução precisa ser depois da retirada.", http.StatusBadRequest)
+		return inicio, fim, false
+	}
+	return inicio.UTC(), fim.UTC(), true
+}
+
+// GET /precos/temporadas - listar tarifas sazonais (admin)
+func ListarTemporadasHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		tarifas, err := models.GetAllTarifasSazonais(db)
+		if err != nil {
+			log.Printf("Erro ao buscar tarifas sazonais: %v", err)
+			http.Error(w, "Erro ao buscar tarifas sazonais", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tarifas)
+	})
+}
+
+// POST /precos/temporadas/criar - criar tarifa sazonal (admin)
+func CriarTemporadaHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var input struct {
+			Nome          string  `json:"nome"`
+			Categoria     string  `json:"categoria"`
+			DataInicio    string  `json:"data_inicio"`
+			DataFim       string  `json:"data_fim"`
+			Multiplicador float64 `json:"multiplicador"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		inicio, fim, ok := lerPeriodo(w, input.DataInicio, input.DataFim)
+		if !ok {
+			return
+		}
+		if input.Nome == "" || input.Multiplicador <= 0 {
+			http.Error(w, "Nome e multiplicador (maior que zero) são obrigatórios", http.StatusBadRequest)
+			return
+		}
+		if !categoriaExiste(db, w, input.Categoria) {
+			return
+		}
+
+		err := models.CreateTarifaSazonal(db, models.TarifaSazonal{
+			Nome: input.Nome, Categoria: input.Categoria, DataInicio: inicio, DataFim: fim, Multiplicador: input.Multiplicador,
+		})
+		if err != nil {
+			log.Printf("Erro ao criar tarifa sazonal: %v", err)
+			http.Error(w, "Erro ao criar tarifa sazonal", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"Tarifa sazonal criada com sucesso"}`))
+	})
+}
+
+// POST /precos/temporadas/deletar?id=1 - remover tarifa sazonal (admin)
+func DeletarTemporadaHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if err := models.DeleteTarifaSazonal(db, id); err != nil {
+			log.Printf("Erro ao deletar tarifa sazonal %d: %v", id, err)
+			http.Error(w, "Erro ao deletar tarifa sazonal", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// GET /precos/feriados - listar calendário de feriados (admin)
+func ListarFeriadosHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		feriados, err := models.GetAllFeriados(db)
+		if err != nil {
+			log.Printf("Erro ao buscar feriados: %v", err)
+			http.Error(w, "Erro ao buscar feriados", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(feriados)
+	})
+}
+
+// POST /precos/feriados/criar - incluir feriado (admin)
+func CriarFeriadoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var f models.Feriado
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		if _, err := time.Parse("2006-01-02", f.Data); err != nil || f.Descricao == "" {
+			http.Error(w, "Informe a data (AAAA-MM-DD) e a descrição do feriado", http.StatusBadRequest)
+			return
+		}
+
+		if err := models.CreateFeriado(db, f); err != nil {
+			log.Printf("Erro ao criar feriado %s: %v", f.Data, err)
+			http.Error(w, "Erro ao criar feriado", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"Feriado criado com sucesso"}`))
+	})
+}
+
+// POST /precos/feriados/deletar?data=AAAA-MM-DD - remover feriado (admin)
+func DeletarFeriadoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		data := r.URL.Query().Get("data")
+		if err := models.DeleteFeriado(db, data); err != nil {
+			log.Printf("Erro ao deletar feriado %s: %v", data, err)
+			http.Error(w, "Erro ao deletar feriado", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// GET /precos/descontos - listar faixas de desconto por duração (admin)
+func ListarDescontosHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		descontos, err := models.GetAllDescontosDuracao(db)
+		if err != nil {
+			log.Printf("Erro ao buscar descontos por duração: %v", err)
+			http.Error(w, "Erro ao buscar descontos", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(descontos)
+	})
+}
+
+// POST /precos/descontos/criar - criar faixa de desconto, ex.: 7 dias = 10% (admin)
+func CriarDescontoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var d models.DescontoDuracao
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		if d.DiasMinimos <= 0 || d.Percentual <= 0 || d.Percentual >= 100 {
+			http.Error(w, "Informe dias_minimos e um percentual entre 0 e 100", http.StatusBadRequest)
+			return
+		}
+		if !categoriaExiste(db, w, d.Categoria) {
+			return
+		}
+
+		if err := models.CreateDescontoDuracao(db, d); err != nil {
+			log.Printf("Erro ao criar desconto por duração: %v", err)
+			http.Error(w, "Erro ao criar desconto", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"Desconto criado com sucesso"}`))
+	})
+}
+
+// POST /precos/descontos/deletar?id=1 - remover faixa de desconto (admin)
+func DeletarDescontoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if err := models.DeleteDescontoDuracao(db, id); err != nil {
+			log.Printf("Erro ao deletar desconto %d: %v", id, err)
+			http.Error(w, "Erro ao deletar desconto", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// limitador controla quantas requisições cada IP pode fazer dentro de uma janela de tempo
+type limitador struct {
+	mu      sync.Mutex
+	limite  int
+	janela  time.Duration
+	acessos map[string][]time.Time
+}
+
+func novoLimitador(limite int, janela time.Duration) *limitador {
+	return &limitador{
+		limite:  limite,
+		janela:  janela,
+		acessos: make(map[string][]time.Time),
+	}
+}
+
+// permitir registra o acesso e informa se ele ainda está dentro do limite
+func (l *limitador) permitir(chave string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	agora := time.Now()
+	corte := agora.Add(-l.janela)
+
+	// Descarta acessos antigos de todas as chaves para o mapa não crescer indefinidamente
+	for k, tempos := range l.acessos {
+		i := 0
+		for i < len(tempos) && tempos[i].Before(corte) {
+			i++
+		}
+		if i == len(tempos) {
+			delete(l.acessos, k)
+		} else {
+			l.acessos[k] = tempos[i:]
+		}
+	}
+
+	if len(l.acessos[chave]) >= l.limite {
+		return false
+	}
+	l.acessos[chave] = append(l.acessos[chave], agora)
+	return true
+}
+
+// middleware responde 429 quando o IP excede o limite
+func (l *limitador) middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !l.permitir(ipCliente(r)) {
+			http.Error(w, "Muitas requisições. Tente novamente mais tarde.", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func ipCliente(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"text/template"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+// Modelos do recibo para impressão (HTML) e para o PDF
+const (
+	modeloReciboHTML = "templates/recibo.html"
+	modeloReciboPDF  = "templates/recibo.txt"
+)
+
+// GET /pagamentos/{id}/recibo?formato=json|html|pdf - recibo do pagamento (o próprio cliente ou admin)
+// Só pagamentos da locação e capturas de caução têm recibo. Sem formato, responde em JSON.
+func ReciboPagamentoHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente", "admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		formato := r.URL.Query().Get("formato")
+		if formato != "" && formato != "json" && formato != "html" && formato != "pdf" {
+			http.Error(w, "Formato inválido. Use json, html ou pdf.", http.StatusBadRequest)
+			return
+		}
+
+		pagamento, err := models.GetPagamentoByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Pagamento não encontrado.", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar pagamento %d: %v", id, err)
+			http.Error(w, "Erro interno ao buscar pagamento.", http.StatusInternalServerError)
+			return
+		}
+		locacao, err := models.GetLocacaoByID(db, pagamento.IDLocacao)
+		if err != nil {
+			log.Printf("Erro ao buscar locação %d do pagamento %d: %v", pagamento.IDLocacao, id, err)
+			http.Error(w, "Erro interno ao buscar locação.", http.StatusInternalServerError)
+			return
+		}
+		if !acessoDoCliente(db, r, locacao.IDCliente) {
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
+
+		recibo, err := models.GetRecibo(db, id)
+		if err == models.ErrSemRecibo {
+			http.Error(w, "Este lançamento ("+pagamento.Tipo+") não gera recibo.", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao emitir recibo do pagamento %d: %v", id, err)
+			http.Error(w, "Erro interno ao emitir recibo.", http.StatusInternalServerError)
+			return
+		}
+
+		switch formato {
+		case "html":
+			modelo, err := htmltemplate.New("recibo.html").Funcs(htmltemplate.FuncMap(funcoesDocumentos)).ParseFiles(modeloReciboHTML)
+			var pagina bytes.Buffer
+			if err == nil {
+				err = modelo.Execute(&pagina, recibo)
+			}
+			if err != nil {
+				log.Printf("Erro ao montar recibo %d em HTML: %v", recibo.Numero, err)
+				http.Error(w, "Erro interno ao montar recibo.", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(pagina.Bytes())
+		case "pdf":
+			modelo, err := template.New("recibo.txt").Funcs(funcoesDocumentos).ParseFiles(modeloReciboPDF)
+			var texto bytes.Buffer
+			if err == nil {
+				err = modelo.Execute(&texto, recibo)
+			}
+			if err != nil {
+				log.Printf("Erro ao montar recibo %d em PDF: %v", recibo.Numero, err)
+				http.Error(w, "Erro interno ao montar recibo.", http.StatusInternalServerError)
+				return
+			}
+			pdf := servicos.NovoPDF()
+			pdf.Escrever(texto.String())
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="recibo-%06d.pdf"`, recibo.Numero))
+			w.Write(pdf.Bytes())
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(recibo)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+// Validade do link de verificação enviado por e-mail
+const validadeTokenVerificacao = 24 * time.Hour
+
+// POST /registro - cadastro público de clientes (sem sessão)
+func RegistroHandler(db *sql.DB, notificador servicos.Notificador) http.HandlerFunc {
+	limite := novoLimitador(5, time.Hour)
+
+	return limite.middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Método não permitido"})
+			return
+		}
+
+		var input struct {
+			models.Cliente
+			Senha string `json:"senha"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "JSON inválido"})
+			return
+		}
+
+		c := input.Cliente
+		c.Username = strings.TrimSpace(c.Username)
+		c.Email = strings.TrimSpace(c.Email)
+
+		if c.Nome == "" || c.Username == "" || c.Email == "" || input.Senha == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Nome, e-mail, nome de usuário e senha são obrigatórios"})
+			return
+		}
+		if _, err := mail.ParseAddress(c.Email); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "E-mail inválido"})
+			return
+		}
+		if len(input.Senha) < 8 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "A senha deve ter pelo menos 8 caracteres"})
+			return
+		}
+
+		if !validarDocumentos(w, &c, true) {
+			return
+		}
+
+		campo, err := models.CampoDuplicado(db, c)
+		if err != nil {
+			log.Println("Erro verificando duplicidade de cliente:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Erro ao salvar cliente"})
+			return
+		}
+		if campo != "" {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Já existe um cadastro com este " + campo, "campo": campo})
+			return
+		}
+
+		token, err := gerarToken()
+		if err != nil {
+			log.Println("Erro gerando token de verificação:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Erro ao salvar cliente"})
+			return
+		}
+
+		err = models.RegistrarCliente(db, c, input.Senha, token, time.Now().Add(validadeTokenVerificacao))
+		if campo := models.CampoViolado(err); campo != "" {
+			// Outro cadastro com o mesmo dado passou pela verificação acima ao mesmo tempo
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Já existe um cadastro com este " + campo, "campo": campo})
+			return
+		}
+		if err != nil {
+			log.Println("Erro registrando cliente:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Erro ao salvar cliente"})
+			return
+		}
+
+		link := urlBase(r) + "/registro/verificar?token=" + token
+		err = notificador.Notificar(c.Email, "Confirme seu cadastro",
+			"Olá, "+c.Nome+"! Para ativar sua conta acesse: "+link)
+		if err != nil {
+			log.Printf("Erro enviando e-mail de verificação para %s: %v", c.Email, err)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Cadastro realizado. Verifique seu e-mail para ativar a conta."})
+	})
+}
+
+// GET /registro/verificar?token=... - confirma o e-mail (link de uso único)
+func VerificarEmailHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Método não permitido"})
+			return
+		}
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Token é obrigatório"})
+			return
+		}
+
+		err := models.VerificarEmail(db, token)
+		if errors.Is(err, models.ErrTokenInvalido) || errors.Is(err, models.ErrTokenExpirado) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Println("Erro verificando e-mail:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Erro ao verificar e-mail"})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "E-mail verificado com sucesso. Você já pode fazer login."})
+	}
+}
+
+// gerarToken cria um token aleatório de 32 bytes em hexadecimal
+func gerarToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func urlBase(r *http.Request) string {
+	esquema := "http"
+	if r.TLS != nil {
+		esquema = "https"
+	}
+	return esquema + "://" + r.Host
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// GET /regras - listar regras de locação (admin)
+func ListarRegrasHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		regras, err := models.GetAllRegras(db)
+		if err != nil {
+			log.Printf("Erro ao buscar regras de locação: %v", err)
+			http.Error(w, "Erro ao buscar regras", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(regras)
+	})
+}
+
+// POST /regras/criar - criar regra de locação (admin)
+func CriarRegraHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		regra := models.RegraLocacao{Ativa: true}
+		if err := json.NewDecoder(r.Body).Decode(&regra); err != nil {
+			http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !regraValida(w, regra) {
+			return
+		}
+
+		if err := models.CreateRegra(db, regra); err != nil {
+			log.Printf("Erro ao criar regra de locação: %v", err)
+			http.Error(w, "Erro ao criar regra", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"Regra criada com sucesso"}`))
+	})
+}
+
+// PUT /regras/atualizar?id=1 - atualizar regra de locação (admin)
+func AtualizarRegraHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+
+		// Campos ausentes no JSON mantêm o valor atual (um PUT sem "ativa" não desativa a regra)
+		regra, err := models.GetRegraByID(db, id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Regra não encontrada", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao buscar regra %d: %v", id, err)
+			http.Error(w, "Erro ao buscar regra", http.StatusInternalServerError)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&regra); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		regra.ID = id
+		if !regraValida(w, regra) {
+			return
+		}
+
+		if err := models.UpdateRegra(db, regra); err != nil {
+			log.Printf("Erro ao atualizar regra %d: %v", id, err)
+			http.Error(w, "Erro ao atualizar regra", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// POST /regras/deletar?id=1 - remover regra de locação (admin)
+func DeletarRegraHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"admin"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		if err := models.DeleteRegra(db, id); err != nil {
+			log.Printf("Erro ao deletar regra %d: %v", id, err)
+			http.Error(w, "Erro ao deletar regra", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func regraValida(w http.ResponseWriter, r models.RegraLocacao) bool {
+	if r.IdadeMinima < 0 || r.TempoHabilitacaoMinimo < 0 || r.IdadeCondutorJovem < 0 ||
+		r.SobretaxaJovemDiaria < 0 || r.ValorCaucao < 0 {
+		http.Error(w, "Os valores da regra não podem ser negativos", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
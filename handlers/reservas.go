@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// POST /reservas/hold - segura um carro ou uma vaga da categoria durante o checkout (cliente)
+// Body: {"id_cliente": 1, "id_carro": 3 | "categoria": "suv", "data_inicio": "2026-12-20T10:00",
+// "data_fim": "2026-12-27T10:00", "id_filial_retirada": 1, "id_filial_devolucao": 2}
+// A reserva vale pelos minutos configurados; para confirmá-la, crie a locação com id_reserva.
+func CriarReservaTemporariaHandler(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(db, []string{"cliente"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		var input struct {
+			IDCliente         int    `json:"id_cliente"`
+			IDCarro           int    `json:"id_carro"`
+			Categoria         string `json:"categoria"`
+			DataInicio        string `json:"data_inicio"`
+			DataFim           string `json:"data_fim"`
+			IDFilialRetirada  int    `json:"id_filial_retirada"`
+			IDFilialDevolucao int    `json:"id_filial_devolucao"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		if !acessoDoCliente(db, r, input.IDCliente) {
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
+
+		inicio, fim, ok := lerPeriodoLocacao(w, input.DataInicio, input.DataFim)
+		if !ok {
+			return
+		}
+		cliente, err := models.GetClienteByID(db, input.IDCliente)
+		if err != nil {
+			http.Error(w, "Cliente não encontrado.", http.StatusBadRequest)
+			return
+		}
+		if err := models.VerificarCNHParaLocacao(cliente, fim); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		categoria := input.Categoria
+		filialCarro := 0
+		if input.IDCarro != 0 {
+			carro, err := models.GetCarroByID(db, input.IDCarro)
+			if err != nil {
+				http.Error(w, "Carro não encontrado.", http.StatusBadRequest)
+				return
+			}
+			if !carro.Disponibilidade {
+				http.Error(w, "Carro atualmente indisponível para locação.", http.StatusConflict)
+				return
+			}
+			categoria, filialCarro = carro.Categoria, carro.IDFilial
+		} else if _, err := models.GetCategoriaByCodigo(db, categoria); err != nil {
+			http.Error(w, "Informe o carro (id_carro) ou uma categoria existente.", http.StatusBadRequest)
+			return
+		}
+		trajeto, ok := trajetoLocacao(db, w, input.IDFilialRetirada, input.IDFilialDevolucao, filialCarro)
+		if !ok || !horarioAtendimento(db, w, trajeto, inicio, fim) {
+			return
+		}
+
+		minutos, err := models.ConfigInt(db, models.ConfigReservaTemporariaMin)
+		if err != nil {
+			log.Printf("Erro ao ler validade das reservas temporárias: %v", err)
+			http.Error(w, "Erro interno ao criar reserva temporária.", http.StatusInternalServerError)
+			return
+		}
+		reserva, err := models.CriarReservaTemporaria(db, models.ReservaTemporaria{
+			IDCliente:         input.IDCliente,
+			IDCarro:           input.IDCarro,
+			Categoria:         categoria,
+			DataInicio:        inicio,
+			DataFim:           fim,
+			IDFilialRetirada:  trajeto.Retirada,
+			IDFilialDevolucao: trajeto.Devolucao,
+		}, time.Duration(minutos)*time.Minute)
+		if err == models.ErrSemCarroDisponivel {
+			http.Error(w, "Não há disponibilidade para o período solicitado.", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao criar reserva temporária (carro %d, categoria '%s'): %v", input.IDCarro, categoria, err)
+			http.Error(w, "Erro interno ao criar reserva temporária.", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":    "Período reservado temporariamente",
+			"id_reserva": reserva.ID,
+			"expira_em":  reserva.ExpiraEm,
+		})
+	})
+}
+
+// reservaDoCliente confere a reserva temporária que o cliente está confirmando
+func reservaDoCliente(db *sql.DB, w http.ResponseWriter, id, idCliente int) (models.ReservaTemporaria, bool) {
+	reserva, err := models.GetReservaTemporariaByID(db, id)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Erro ao buscar reserva temporária %d: %v", id, err)
+		http.Error(w, "Erro interno ao buscar a reserva temporária.", http.StatusInternalServerError)
+		return reserva, false
+	}
+	if err == sql.ErrNoRows || reserva.IDCliente != idCliente || !reserva.Vigente(time.Now()) {
+		http.Error(w, models.ErrReservaIndisponivel.Error(), http.StatusConflict)
+		return reserva, false
+	}
+	return reserva, true
+}
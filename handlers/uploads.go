@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+// Extensões aceitas por tipo detectado no conteúdo (não confiamos no nome do arquivo)
+var (
+	extensoesImagem = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/webp": ".webp",
+	}
+	extensoesDocumento = map[string]string{
+		"image/jpeg":      ".jpg",
+		"image/png":       ".png",
+		"application/pdf": ".pdf",
+	}
+)
+
+var errFormatoNaoAceito = errors.New("formato de arquivo não aceito")
+
+// salvarUpload grava o arquivo enviado em pasta/<nome aleatório>.<ext> e devolve a URL do arquivo.
+// Retorna errFormatoNaoAceito se o conteúdo não for de um dos tipos aceitos.
+func salvarUpload(armazenamento servicos.Armazenamento, cabecalho *multipart.FileHeader, pasta string, aceitos map[string]string) (string, error) {
+	f, err := cabecalho.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	inicio := make([]byte, 512)
+	n, _ := f.Read(inicio)
+	ext, ok := aceitos[http.DetectContentType(inicio[:n])]
+	if !ok {
+		return "", errFormatoNaoAceito
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return "", err
+	}
+
+	nome, err := gerarToken()
+	if err != nil {
+		return "", err
+	}
+	return armazenamento.Salvar(pasta+"/"+nome[:16]+ext, f)
+}
+
+// enviarArquivo responde com um arquivo do armazenamento privado. Quem chama já conferiu
+// que o arquivo pertence ao registro e que a sessão pode vê-lo.
+func enviarArquivo(w http.ResponseWriter, armazenamento servicos.Armazenamento, caminho string) {
+	f, err := armazenamento.Abrir(caminho)
+	if os.IsNotExist(err) || errors.Is(err, servicos.ErrCaminhoInvalido) {
+		http.Error(w, "Arquivo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao abrir %s: %v", caminho, err)
+		http.Error(w, "Erro interno ao abrir arquivo", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(caminho)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, f); err != nil {
+		log.Printf("Erro ao enviar %s: %v", caminho, err)
+	}
+}
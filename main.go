@@ -5,35 +5,170 @@ import (
 	"net/http"
 
 	"github.com/Kyutz/aluguel-carros-go/handlers"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
 )
 
 func main() {
 	SetupDatabase()
 	defer db.Close()
 
+	notificador := servicos.NotificadorLog{}
+	gateway := servicos.NovoGatewayLocal()
+	// Contratos, fotos de avarias, documentos dos carros e demais arquivos enviados não são
+	// públicos: cada URL /arquivos/... é servida por um handler que confere a sessão
+	arquivos := servicos.ArmazenamentoLocal{Diretorio: "arquivos", URLBase: "/arquivos"}
+	// Notas fiscais de serviço: certificado em arquivos/certificado (autoassinado se ausente)
+	// e a prefeitura simulada localmente, com as notas emitidas em arquivos/prefeitura
+	certificado, err := servicos.CertificadoLocal("arquivos/certificado", "Locadora (desenvolvimento)")
+	if err != nil {
+		log.Fatal("Erro ao carregar certificado digital:", err)
+	}
+	autoridade, err := servicos.NovaAutoridadeLocal("arquivos/prefeitura")
+	if err != nil {
+		log.Fatal("Erro ao carregar notas da prefeitura local:", err)
+	}
+
+	// Rotinas diárias (alertas de vencimento de documentos), revisão da lista de espera,
+	// expiração das reservas temporárias e emissão das notas fiscais pendentes
+	go iniciarTarefasDiarias(db, notificador)
+	go iniciarRevisaoListaEspera(db, notificador)
+	go iniciarVarreduraReservas(db, notificador)
+	go iniciarEmissaoNotasFiscais(db, arquivos, autoridade, certificado)
+
 	// Autenticação
 	http.HandleFunc("/login", handlers.LoginJSONHandler(db)) // POST /login
 	http.HandleFunc("/logout", handlers.LogoutJSONHandler)   // GET /logout
 
+	// Cadastro público
+	http.HandleFunc("/registro", handlers.RegistroHandler(db, notificador))    // POST
+	http.HandleFunc("/registro/verificar", handlers.VerificarEmailHandler(db)) // GET
+
 	// CRUD de carros
-	http.HandleFunc("/carros", handlers.ListarCarrosHandler(db))             // GET
-	http.HandleFunc("/carros/criar", handlers.CriarCarroHandler(db))         // POST
-	http.HandleFunc("/carros/atualizar", handlers.AtualizarCarroHandler(db)) // PUT (emulado via POST)
-	http.HandleFunc("/carros/deletar", handlers.DeletarCarroHandler(db))     // POST (emulando DELETE)
+	http.HandleFunc("/carros", handlers.ListarCarrosHandler(db))                                           // GET
+	http.HandleFunc("/carros/criar", handlers.CriarCarroHandler(db))                                       // POST
+	http.HandleFunc("/carros/atualizar", handlers.AtualizarCarroHandler(db))                               // PUT (emulado via POST)
+	http.HandleFunc("/carros/deletar", handlers.DeletarCarroHandler(db))                                   // POST (emulando DELETE)
+	http.HandleFunc("/carros/{id}/historico", handlers.HistoricoCarroHandler(db))                          // GET (admin)
+	http.HandleFunc("/carros/{id}/documentos", handlers.DocumentosCarroHandler(db, arquivos))              // GET, POST multipart (admin)
+	http.HandleFunc("/arquivos/documentos/{id}/{arquivo}", handlers.ArquivoDocumentoHandler(db, arquivos)) // GET (admin)
+	http.HandleFunc("/documentos/vencimentos", handlers.VencimentosDocumentosHandler(db))                  // GET (admin)
 
 	// Cliente
-	http.HandleFunc("/clientes", handlers.ClientesHandler(db))            // GET
-	http.HandleFunc("/clientes/criar", handlers.ClienteCreateHandler(db)) // POST
-	http.HandleFunc("/clientes/editar", handlers.ClienteEditHandler(db))  // POST
+	http.HandleFunc("/clientes", handlers.ClientesHandler(db))                       // GET
+	http.HandleFunc("/clientes/criar", handlers.ClienteCreateHandler(db))            // POST
+	http.HandleFunc("/clientes/editar", handlers.ClienteEditHandler(db))             // POST
+	http.HandleFunc("/clientes/bloquear", handlers.ClienteBloquearHandler(db))       // POST (admin)
+	http.HandleFunc("/clientes/desbloquear", handlers.ClienteDesbloquearHandler(db)) // POST (admin)
+
+	// Regras de elegibilidade para locação (admin)
+	http.HandleFunc("/regras", handlers.ListarRegrasHandler(db))             // GET
+	http.HandleFunc("/regras/criar", handlers.CriarRegraHandler(db))         // POST
+	http.HandleFunc("/regras/atualizar", handlers.AtualizarRegraHandler(db)) // PUT
+	http.HandleFunc("/regras/deletar", handlers.DeletarRegraHandler(db))     // POST
+
+	// Categorias de veículos
+	http.HandleFunc("/categorias", handlers.ListarCategoriasHandler(db))             // GET
+	http.HandleFunc("/categorias/criar", handlers.CriarCategoriaHandler(db))         // POST (admin)
+	http.HandleFunc("/categorias/atualizar", handlers.AtualizarCategoriaHandler(db)) // PUT (admin)
+	http.HandleFunc("/categorias/deletar", handlers.DeletarCategoriaHandler(db))     // POST (admin)
+
+	// Preços
+	http.HandleFunc("/cotacao", handlers.CotacaoHandler(db))                            // GET
+	http.HandleFunc("/precos/temporadas", handlers.ListarTemporadasHandler(db))         // GET (admin)
+	http.HandleFunc("/precos/temporadas/criar", handlers.CriarTemporadaHandler(db))     // POST (admin)
+	http.HandleFunc("/precos/temporadas/deletar", handlers.DeletarTemporadaHandler(db)) // POST (admin)
+	http.HandleFunc("/precos/feriados", handlers.ListarFeriadosHandler(db))             // GET (admin)
+	http.HandleFunc("/precos/feriados/criar", handlers.CriarFeriadoHandler(db))         // POST (admin)
+	http.HandleFunc("/precos/feriados/deletar", handlers.DeletarFeriadoHandler(db))     // POST (admin)
+	http.HandleFunc("/precos/descontos", handlers.ListarDescontosHandler(db))           // GET (admin)
+	http.HandleFunc("/precos/descontos/criar", handlers.CriarDescontoHandler(db))       // POST (admin)
+	http.HandleFunc("/precos/descontos/deletar", handlers.DeletarDescontoHandler(db))   // POST (admin)
+
+	// Extras (opcionais da locação)
+	http.HandleFunc("/extras", handlers.ListarExtrasHandler(db))             // GET
+	http.HandleFunc("/extras/criar", handlers.CriarExtraHandler(db))         // POST (admin)
+	http.HandleFunc("/extras/atualizar", handlers.AtualizarExtraHandler(db)) // PUT (admin)
+	http.HandleFunc("/extras/deletar", handlers.DeletarExtraHandler(db))     // POST (admin)
+
+	// Planos de proteção (franquia)
+	http.HandleFunc("/planos", handlers.ListarPlanosHandler(db))             // GET
+	http.HandleFunc("/planos/criar", handlers.CriarPlanoHandler(db))         // POST (admin)
+	http.HandleFunc("/planos/atualizar", handlers.AtualizarPlanoHandler(db)) // PUT (admin)
+	http.HandleFunc("/planos/deletar", handlers.DeletarPlanoHandler(db))     // POST (admin)
+
+	// Filiais, taxas de retorno (one-way) e movimentação da frota
+	http.HandleFunc("/filiais", handlers.ListarFiliaisHandler(db))                     // GET
+	http.HandleFunc("/filiais/criar", handlers.CriarFilialHandler(db))                 // POST (admin)
+	http.HandleFunc("/filiais/atualizar", handlers.AtualizarFilialHandler(db))         // PUT (admin)
+	http.HandleFunc("/filiais/deletar", handlers.DeletarFilialHandler(db))             // POST (admin)
+	http.HandleFunc("/filiais/taxas", handlers.ListarTaxasRetornoHandler(db))          // GET
+	http.HandleFunc("/filiais/taxas/salvar", handlers.SalvarTaxaRetornoHandler(db))    // POST (admin)
+	http.HandleFunc("/filiais/taxas/deletar", handlers.DeletarTaxaRetornoHandler(db))  // POST (admin)
+	http.HandleFunc("/filiais/movimentacoes", handlers.ListarMovimentacoesHandler(db)) // GET (admin)
+	http.HandleFunc("/filiais/transferir", handlers.TransferirCarroHandler(db))        // POST (admin)
+	http.HandleFunc("/filiais/{id}/horarios", handlers.HorariosFilialHandler(db))      // GET, PUT (admin)
+
+	// Manutenção da frota (admin)
+	http.HandleFunc("/manutencao/planos", handlers.ListarPlanosManutencaoHandler(db))             // GET
+	http.HandleFunc("/manutencao/planos/criar", handlers.CriarPlanoManutencaoHandler(db))         // POST
+	http.HandleFunc("/manutencao/planos/atualizar", handlers.AtualizarPlanoManutencaoHandler(db)) // PUT
+	http.HandleFunc("/manutencao/planos/deletar", handlers.DeletarPlanoManutencaoHandler(db))     // POST
+	http.HandleFunc("/manutencao/ordens", handlers.ListarOrdensManutencaoHandler(db))             // GET
+	http.HandleFunc("/manutencao/ordens/criar", handlers.CriarOrdemManutencaoHandler(db))         // POST
+	http.HandleFunc("/manutencao/ordens/atualizar", handlers.AtualizarOrdemManutencaoHandler(db)) // PUT
+	http.HandleFunc("/manutencao/alertas", handlers.AlertasManutencaoHandler(db))                 // GET
 
 	// Aluguel
-	http.HandleFunc("/carros/disponiveis", handlers.CarrosDisponiveisHandler(db)) // GET
-	http.HandleFunc("/aluguel", handlers.CriarLocacaoHandler(db))                 // POST
-	http.HandleFunc("/minhas-locacoes", handlers.MinhasLocacoesHandler(db))       // GET
+	http.HandleFunc("/carros/disponiveis", handlers.CarrosDisponiveisHandler(db))                 // GET
+	http.HandleFunc("/aluguel", handlers.CriarLocacaoHandler(db, arquivos))                       // POST
+	http.HandleFunc("/minhas-locacoes", handlers.MinhasLocacoesHandler(db))                       // GET
+	http.HandleFunc("/locacoes/retirada", handlers.RetiradaLocacaoHandler(db, gateway, arquivos)) // POST (admin)
+	http.HandleFunc("/locacoes/devolucao", handlers.DevolucaoLocacaoHandler(db, notificador))     // POST (admin)
+	http.HandleFunc("/locacoes/cancelar", handlers.CancelarLocacaoHandler(db, notificador))       // POST (cliente dono ou admin)
+	http.HandleFunc("/locacoes/{id}/prorrogar", handlers.ProrrogarLocacaoHandler(db, arquivos))   // POST (cliente dono ou admin)
+	http.HandleFunc("/locacoes/{id}/contrato.pdf", handlers.ContratoLocacaoHandler(db, arquivos)) // GET (cliente dono ou admin)
+	http.HandleFunc("/reservas/hold", handlers.CriarReservaTemporariaHandler(db))                 // POST
+
+	// Lista de espera para períodos sem disponibilidade
+	http.HandleFunc("/lista-espera", handlers.ListarEsperaHandler(db))                         // GET
+	http.HandleFunc("/lista-espera/criar", handlers.CriarEsperaHandler(db))                    // POST
+	http.HandleFunc("/lista-espera/cancelar", handlers.CancelarEsperaHandler(db, notificador)) // POST
+
+	// Avarias (admin)
+	http.HandleFunc("/avarias", handlers.ListarAvariasHandler(db))                                // GET
+	http.HandleFunc("/avarias/criar", handlers.CriarAvariaHandler(db))                            // POST
+	http.HandleFunc("/avarias/fotos", handlers.EnviarFotosAvariaHandler(db, arquivos))            // POST (multipart)
+	http.HandleFunc("/avarias/cobrar", handlers.CobrarAvariaHandler(db, gateway))                 // POST
+	http.HandleFunc("/avarias/reparada", handlers.AvariaReparadaHandler(db))                      // POST
+	http.HandleFunc("/arquivos/avarias/{id}/{arquivo}", handlers.FotoAvariaHandler(db, arquivos)) // GET (admin ou cliente da locação)
+
+	// Caução (pré-autorizada na retirada)
+	http.HandleFunc("/locacoes/caucao/capturar", handlers.CapturarCaucaoHandler(db, gateway)) // POST (admin)
+	http.HandleFunc("/locacoes/caucao/liberar", handlers.LiberarCaucaoHandler(db, gateway))   // POST (admin)
+
+	// Indicadores da frota para a gerência
+	http.HandleFunc("/dashboard/indicadores", handlers.IndicadoresHandler(db)) // GET (admin)
+
+	// Planilhas para o financeiro: /export/locacoes.csv, /export/carros.xlsx...
+	http.HandleFunc("/export/{arquivo}", handlers.ExportarHandler(db)) // GET (admin)
+
+	// Cadastro em massa (CSV ou JSON, ?simular=1 só valida)
+	http.HandleFunc("/importar/{entidade}", handlers.ImportarHandler(db)) // POST (admin)
+
+	// Notas fiscais de serviço (admin)
+	http.HandleFunc("/notas-fiscais", handlers.ListarNotasFiscaisHandler(db))                                         // GET
+	http.HandleFunc("/notas-fiscais/emitir", handlers.EmitirNotaFiscalHandler(db, arquivos, autoridade, certificado)) // POST
+	http.HandleFunc("/notas-fiscais/{numero}/xml", handlers.XMLNotaFiscalHandler(db, arquivos))                       // GET
+	http.HandleFunc("/aliquotas-iss", handlers.AliquotasISSHandler(db))                                               // GET, PUT
+
+	// Parâmetros operacionais (tolerância de atraso, combustível, km livres...)
+	http.HandleFunc("/configuracoes", handlers.ListarConfiguracoesHandler(db))              // GET (admin)
+	http.HandleFunc("/configuracoes/atualizar", handlers.AtualizarConfiguracoesHandler(db)) // PUT (admin)
 
 	// Pagamento
-	http.HandleFunc("/pagamento", handlers.RealizarPagamentoHandler(db))  // POST
-	http.HandleFunc("/pagamentos", handlers.PagamentosClienteHandler(db)) // GET
+	http.HandleFunc("/pagamento", handlers.RealizarPagamentoHandler(db))            // POST
+	http.HandleFunc("/pagamentos", handlers.PagamentosClienteHandler(db))           // GET
+	http.HandleFunc("/pagamentos/{id}/recibo", handlers.ReciboPagamentoHandler(db)) // GET (cliente dono ou admin)
 
 	log.Println("Servidor rodando na porta 8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
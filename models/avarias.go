@@ -0,0 +1,170 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	GravidadeLeve     = "leve"
+	GravidadeModerada = "moderada"
+	GravidadeGrave    = "grave"
+)
+
+const (
+	AvariaAberta   = "aberta"
+	AvariaCobrada  = "cobrada"
+	AvariaReparada = "reparada"
+)
+
+type Avaria struct {
+	ID               int          `db:"id_avaria" json:"id"`
+	IDCarro          int          `db:"id_carro" json:"id_carro"`
+	IDLocacao        int          `db:"id_locacao" json:"id_locacao,omitempty"`
+	LocalVeiculo     string       `db:"local_veiculo" json:"local_veiculo"` // ex.: "para-choque dianteiro"
+	Gravidade        string       `db:"gravidade" json:"gravidade"`
+	Descricao        string       `db:"descricao" json:"descricao"`
+	EstimativaReparo Dinheiro     `db:"estimativa_reparo" json:"estimativa_reparo"`
+	ValorCobrado     Dinheiro     `db:"valor_cobrado" json:"valor_cobrado"`
+	Status           string       `db:"status" json:"status"`
+	RegistradaEm     time.Time    `db:"registrada_em" json:"registrada_em"`
+	Fotos            []FotoAvaria `json:"fotos"`
+}
+
+type FotoAvaria struct {
+	ID        int       `db:"id_foto" json:"id"`
+	URL       string    `db:"url" json:"url"`
+	EnviadaEm time.Time `db:"enviada_em" json:"enviada_em"`
+}
+
+const colunasAvaria = "id_avaria, id_carro, COALESCE(id_locacao, 0), local_veiculo, gravidade, descricao, estimativa_reparo, valor_cobrado, status, registrada_em"
+
+func camposAvaria(a *Avaria) []any {
+	return []any{&a.ID, &a.IDCarro, &a.IDLocacao, &a.LocalVeiculo, &a.Gravidade, &a.Descricao,
+		&a.EstimativaReparo, &a.ValorCobrado, &a.Status, &a.RegistradaEm}
+}
+
+// GetAvarias lista avarias com as fotos; idCarro e idLocacao 0 não filtram
+func GetAvarias(db *sql.DB, idCarro, idLocacao int) ([]Avaria, error) {
+	rows, err := db.Query(`SELECT `+colunasAvaria+` FROM avarias
+		WHERE (? = 0 OR id_carro = ?) AND (? = 0 OR id_locacao = ?)
+		ORDER BY registrada_em DESC`, idCarro, idCarro, idLocacao, idLocacao)
+	if err != nil {
+		return nil, err
+	}
+	var avarias []Avaria
+	for rows.Next() {
+		var a Avaria
+		if err := rows.Scan(camposAvaria(&a)...); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		avarias = append(avarias, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range avarias {
+		if avarias[i].Fotos, err = getFotosAvaria(db, avarias[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return avarias, nil
+}
+
+func GetAvariaByID(db *sql.DB, id int) (Avaria, error) {
+	var a Avaria
+	err := db.QueryRow("SELECT "+colunasAvaria+" FROM avarias WHERE id_avaria = ?", id).Scan(camposAvaria(&a)...)
+	if err != nil {
+		return a, err
+	}
+	a.Fotos, err = getFotosAvaria(db, id)
+	return a, err
+}
+
+func getFotosAvaria(db *sql.DB, idAvaria int) ([]FotoAvaria, error) {
+	rows, err := db.Query("SELECT id_foto, url, enviada_em FROM avaria_fotos WHERE id_avaria = ? ORDER BY id_foto", idAvaria)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fotos := []FotoAvaria{}
+	for rows.Next() {
+		var f FotoAvaria
+		if err := rows.Scan(&f.ID, &f.URL, &f.EnviadaEm); err != nil {
+			return nil, err
+		}
+		fotos = append(fotos, f)
+	}
+	return fotos, rows.Err()
+}
+
+func CreateAvaria(db *sql.DB, a Avaria) (int, error) {
+	res, err := db.Exec(`INSERT INTO avarias (id_carro, id_locacao, local_veiculo, gravidade, descricao, estimativa_reparo, status, registrada_em)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.IDCarro, nuloSeZero(a.IDLocacao), a.LocalVeiculo, a.Gravidade, a.Descricao, a.EstimativaReparo, AvariaAberta, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func AdicionarFotoAvaria(db *sql.DB, idAvaria int, url string) error {
+	_, err := db.Exec("INSERT INTO avaria_fotos (id_avaria, url, enviada_em) VALUES (?, ?, ?)", idAvaria, url, time.Now())
+	return err
+}
+
+func MarcarAvariaReparada(db *sql.DB, id int) error {
+	_, err := db.Exec("UPDATE avarias SET status = ? WHERE id_avaria = ?", AvariaReparada, id)
+	return err
+}
+
+// DanosCobrados soma o que já foi cobrado de avarias na locação (para respeitar a franquia)
+func DanosCobrados(db *sql.DB, idLocacao int) (Dinheiro, error) {
+	var total Dinheiro
+	err := db.QueryRow("SELECT COALESCE(SUM(valor_cobrado), 0) FROM avarias WHERE id_locacao = ?", idLocacao).Scan(&total)
+	return total, err
+}
+
+// CobrarAvaria lança o valor como item da locação (somando ao ValorTotal) e marca a avaria
+// como cobrada. Valor zero (dano coberto pelo plano) só muda o status.
+func CobrarAvaria(db *sql.DB, a Avaria, valor Dinheiro) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE avarias SET status = ?, valor_cobrado = ? WHERE id_avaria = ? AND status = ?",
+		AvariaCobrada, valor, a.ID, AvariaAberta)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("avaria %d não está aberta", a.ID)
+	}
+
+	if valor > 0 {
+		item := ItemCotacao{
+			Tipo:          ItemAvaria,
+			Descricao:     fmt.Sprintf("Avaria: %s (%s)", a.LocalVeiculo, a.Gravidade),
+			Quantidade:    1,
+			ValorUnitario: valor,
+			Valor:         valor,
+		}
+		if err := inserirItensTx(tx, a.IDLocacao, []ItemCotacao{item}); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE locacoes SET valor_total = valor_total + ? WHERE id_locacao = ?", valor, a.IDLocacao); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
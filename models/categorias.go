@@ -0,0 +1,70 @@
+package models
+
+import "database/sql"
+
+// Categoria agrupa carros equivalentes; o cliente pode reservar a categoria
+// e o carro específico só é definido na retirada
+type Categoria struct {
+	Codigo          string   `db:"codigo" json:"codigo"`
+	Nome            string   `db:"nome" json:"nome"`
+	ValorDiariaBase Dinheiro `db:"valor_diaria_base" json:"valor_diaria_base"`
+	// Multiplicadores aplicados pelo motor de preços sobre a diária base
+	MultiplicadorFimSemana float64 `db:"multiplicador_fim_semana" json:"multiplicador_fim_semana"`
+	MultiplicadorFeriado   float64 `db:"multiplicador_feriado" json:"multiplicador_feriado"`
+}
+
+const colunasCategoria = "codigo, nome, valor_diaria_base, multiplicador_fim_semana, multiplicador_feriado"
+
+func camposCategoria(c *Categoria) []any {
+	return []any{&c.Codigo, &c.Nome, &c.ValorDiariaBase, &c.MultiplicadorFimSemana, &c.MultiplicadorFeriado}
+}
+
+func GetAllCategorias(db *sql.DB) ([]Categoria, error) {
+	rows, err := db.Query("SELECT " + colunasCategoria + " FROM categorias ORDER BY valor_diaria_base")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categorias []Categoria
+	for rows.Next() {
+		var c Categoria
+		if err := rows.Scan(camposCategoria(&c)...); err != nil {
+			return nil, err
+		}
+		categorias = append(categorias, c)
+	}
+	return categorias, rows.Err()
+}
+
+func GetCategoriaByCodigo(db *sql.DB, codigo string) (Categoria, error) {
+	var c Categoria
+	err := db.QueryRow("SELECT "+colunasCategoria+" FROM categorias WHERE codigo = ?", codigo).
+		Scan(camposCategoria(&c)...)
+	return c, err
+}
+
+func CreateCategoria(db *sql.DB, c Categoria) error {
+	_, err := db.Exec(`INSERT INTO categorias (codigo, nome, valor_diaria_base, multiplicador_fim_semana, multiplicador_feriado)
+		VALUES (?, ?, ?, ?, ?)`,
+		c.Codigo, c.Nome, c.ValorDiariaBase, c.MultiplicadorFimSemana, c.MultiplicadorFeriado)
+	return err
+}
+
+func UpdateCategoria(db *sql.DB, c Categoria) error {
+	_, err := db.Exec("UPDATE categorias SET nome=?, valor_diaria_base=?, multiplicador_fim_semana=?, multiplicador_feriado=? WHERE codigo=?",
+		c.Nome, c.ValorDiariaBase, c.MultiplicadorFimSemana, c.MultiplicadorFeriado, c.Codigo)
+	return err
+}
+
+func DeleteCategoria(db *sql.DB, codigo string) error {
+	_, err := db.Exec("DELETE FROM categorias WHERE codigo = ?", codigo)
+	return err
+}
+
+// ContarCarrosCategoria informa quantos carros pertencem à categoria
+func ContarCarrosCategoria(db *sql.DB, codigo string) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM carros WHERE categoria = ?", codigo).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Tipos de registro em pagamentos. A caução gera um bloqueio na retirada e,
+// ao ser encerrada, uma captura e/ou uma liberação. Ajustes registram a diferença
+// de valor de uma prorrogação ou devolução antecipada (negativo = crédito ao cliente);
+// não entram no saldo, que já parte do ValorTotal recalculado.
+const (
+	PagamentoLocacao         = "locacao"
+	PagamentoCaucaoBloqueio  = "caucao_bloqueio"
+	PagamentoCaucaoCaptura   = "caucao_captura"
+	PagamentoCaucaoLiberacao = "caucao_liberacao"
+	PagamentoAjuste          = "ajuste"
+)
+
+// Status do bloqueio da caução
+const (
+	CaucaoAutorizada = "autorizado"
+	CaucaoEncerrada  = "encerrado"
+)
+
+// ErrCaucaoEncerrada indica que o bloqueio já foi capturado ou liberado por outra operação
+var ErrCaucaoEncerrada = errors.New("a caução já foi encerrada")
+
+// GetCaucaoAberta retorna o bloqueio de caução ainda não capturado nem liberado da locação
+func GetCaucaoAberta(db *sql.DB, idLocacao int) (Pagamento, error) {
+	var p Pagamento
+	err := db.QueryRow("SELECT "+colunasPagamento+" FROM pagamentos WHERE id_locacao = ? AND tipo = ? AND status_pagamento = ?",
+		idLocacao, PagamentoCaucaoBloqueio, CaucaoAutorizada).Scan(camposPagamento(&p)...)
+	return p, err
+}
+
+func GetPagamentosLocacao(db *sql.DB, idLocacao int) ([]Pagamento, error) {
+	rows, err := db.Query("SELECT "+colunasPagamento+" FROM pagamentos WHERE id_locacao = ? ORDER BY id_pagamento", idLocacao)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pagamentos []Pagamento
+	for rows.Next() {
+		var p Pagamento
+		if err := rows.Scan(camposPagamento(&p)...); err != nil {
+			return nil, err
+		}
+		pagamentos = append(pagamentos, p)
+	}
+	return pagamentos, rows.Err()
+}
+
+// RegistrarRetirada grava o carro atribuído, o status e, se houver, a vistoria de saída
+// e o bloqueio da caução
+func RegistrarRetirada(db *sql.DB, l Locacao, vistoria *Vistoria, caucao *Pagamento) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE locacoes SET id_carro = ?, status = ? WHERE id_locacao = ?",
+		nuloSeZero(l.IDCarro), l.Status, l.ID); err != nil {
+		return err
+	}
+	if vistoria != nil {
+		if err := inserirVistoriaTx(tx, *vistoria); err != nil {
+			return err
+		}
+	}
+	if caucao != nil {
+		if _, err := tx.Exec(inserirPagamento, caucao.IDLocacao, caucao.DataPagamento, caucao.ValorPago,
+			caucao.FormaPagamento, caucao.StatusPagamento, caucao.Tipo, caucao.ReferenciaGateway); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// EncerrarCaucao fecha o bloqueio e registra a captura (se valorCapturado > 0) e a
+// liberação do restante (se sobrar algo), cada uma como um pagamento próprio. Se o bloqueio
+// não estiver mais autorizado, nada é gravado e o erro é ErrCaucaoEncerrada.
+func EncerrarCaucao(db *sql.DB, bloqueio Pagamento, valorCapturado Dinheiro) ([]Pagamento, error) {
+	agora := time.Now()
+	var movimentos []Pagamento
+	if valorCapturado > 0 {
+		movimentos = append(movimentos, Pagamento{
+			IDLocacao: bloqueio.IDLocacao, DataPagamento: agora, ValorPago: valorCapturado,
+			FormaPagamento: bloqueio.FormaPagamento, StatusPagamento: "confirmado",
+			Tipo: PagamentoCaucaoCaptura, ReferenciaGateway: bloqueio.ReferenciaGateway,
+		})
+	}
+	if resto := bloqueio.ValorPago - valorCapturado; resto > 0 {
+		movimentos = append(movimentos, Pagamento{
+			IDLocacao: bloqueio.IDLocacao, DataPagamento: agora, ValorPago: resto,
+			FormaPagamento: bloqueio.FormaPagamento, StatusPagamento: "liberado",
+			Tipo: PagamentoCaucaoLiberacao, ReferenciaGateway: bloqueio.ReferenciaGateway,
+		})
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE pagamentos SET status_pagamento = ? WHERE id_pagamento = ? AND status_pagamento = ?",
+		CaucaoEncerrada, bloqueio.ID, CaucaoAutorizada)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrCaucaoEncerrada
+	}
+	for i, m := range movimentos {
+		res, err := tx.Exec(inserirPagamento, m.IDLocacao, m.DataPagamento, m.ValorPago,
+			m.FormaPagamento, m.StatusPagamento, m.Tipo, m.ReferenciaGateway)
+		if err != nil {
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		movimentos[i].ID = int(id)
+		if err := emitirReciboTx(tx, int(id), m.Tipo); err != nil {
+			return nil, err
+		}
+	}
+	return movimentos, tx.Commit()
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Chaves da tabela configuracoes (parâmetros operacionais ajustáveis pelos admins)
+const (
+	ConfigToleranciaAtrasoMin  = "atraso_tolerancia_minutos"     // também na contagem das diárias da reserva
+	ConfigAtrasoHorasMax       = "atraso_horas_max"              // acima disto o atraso é cobrado em diárias
+	ConfigAtrasoPercentualHora = "atraso_percentual_hora"        // % da diária por hora de atraso
+	ConfigValorLitro           = "combustivel_valor_litro"       // reais
+	ConfigCapacidadeTanque     = "combustivel_capacidade_padrao" // litros, quando o carro não informa
+	ConfigKmLivresDia          = "km_livres_por_dia"             // 0 = quilometragem livre
+	ConfigValorKmExcedente     = "km_excedente_valor"            // reais por km
+	ConfigManutencaoMargemKm   = "manutencao_alerta_km"          // antecedência dos alertas de manutenção
+	ConfigManutencaoMargemDias = "manutencao_alerta_dias"
+	ConfigDocumentosMargemDias = "documentos_alerta_dias"     // antecedência dos alertas de vencimento
+	ConfigEmailFrota           = "email_frota"                // quem recebe os alertas da frota
+	ConfigIntervaloHorarioMin  = "horario_intervalo_minutos"  // granularidade dos horários de retirada e devolução
+	ConfigEsperaOfertaMin      = "espera_oferta_minutos"      // por quanto tempo a vaga oferecida fica segura
+	ConfigReservaTemporariaMin = "reserva_temporaria_minutos" // validade do hold feito no checkout
+	ConfigReembolsoAntecipado  = "reembolso_antecipado_pct"   // % devolvido das diárias não usadas
+	// Dados da locadora como prestadora na NFS-e
+	ConfigNFSeCNPJ               = "nfse_cnpj"
+	ConfigNFSeInscricaoMunicipal = "nfse_inscricao_municipal"
+	ConfigNFSeRazaoSocial        = "nfse_razao_social"
+	ConfigNFSeSerieRPS           = "nfse_serie_rps"
+)
+
+// ConfiguracoesPadrao são gravadas na criação do banco; valores já alterados não são sobrescritos
+var ConfiguracoesPadrao = map[string]string{
+	ConfigToleranciaAtrasoMin:  "59",
+	ConfigAtrasoHorasMax:       "4",
+	ConfigAtrasoPercentualHora: "20",
+	ConfigValorLitro:           "7.50",
+	ConfigCapacidadeTanque:     "50",
+	ConfigKmLivresDia:          "200",
+	ConfigValorKmExcedente:     "0.90",
+	ConfigManutencaoMargemKm:   "1000",
+	ConfigManutencaoMargemDias: "15",
+	ConfigDocumentosMargemDias: "30",
+	ConfigEmailFrota:           "frota@localhost",
+	ConfigIntervaloHorarioMin:  "30",
+	ConfigEsperaOfertaMin:      "120",
+	ConfigReservaTemporariaMin: "15",
+	ConfigReembolsoAntecipado:  "50",
+	// Preenchidos pelos admins antes da primeira nota
+	ConfigNFSeCNPJ:               "",
+	ConfigNFSeInscricaoMunicipal: "",
+	ConfigNFSeRazaoSocial:        "",
+	ConfigNFSeSerieRPS:           "A",
+}
+
+func GetConfiguracoes(db *sql.DB) (map[string]string, error) {
+	rows, err := db.Query("SELECT chave, valor FROM configuracoes")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	config := map[string]string{}
+	for rows.Next() {
+		var chave, valor string
+		if err := rows.Scan(&chave, &valor); err != nil {
+			return nil, err
+		}
+		config[chave] = valor
+	}
+	return config, rows.Err()
+}
+
+func SetConfiguracao(db *sql.DB, chave, valor string) error {
+	_, err := db.Exec("INSERT INTO configuracoes (chave, valor) VALUES (?, ?) ON CONFLICT(chave) DO UPDATE SET valor = excluded.valor",
+		chave, valor)
+	return err
+}
+
+// configuracao é um leitor tipado sobre o mapa de configurações; o primeiro erro fica guardado
+type configuracao struct {
+	valores map[string]string
+	err     error
+}
+
+func (c *configuracao) texto(chave string) string {
+	v, ok := c.valores[chave]
+	if !ok {
+		v = ConfiguracoesPadrao[chave]
+	}
+	return v
+}
+
+func (c *configuracao) inteiro(chave string) int {
+	n, err := strconv.Atoi(c.texto(chave))
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("configuração %s inválida: %w", chave, err)
+	}
+	return n
+}
+
+func (c *configuracao) decimal(chave string) float64 {
+	f, err := strconv.ParseFloat(c.texto(chave), 64)
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("configuração %s inválida: %w", chave, err)
+	}
+	return f
+}
+
+func (c *configuracao) dinheiro(chave string) Dinheiro {
+	d, err := ParseDinheiro(c.texto(chave))
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("configuração %s inválida: %w", chave, err)
+	}
+	return d
+}
+
+// ValidarConfiguracao confere se a chave existe e se o valor tem o formato esperado
+func ValidarConfiguracao(chave, valor string) error {
+	if _, ok := ConfiguracoesPadrao[chave]; !ok {
+		return fmt.Errorf("configuração desconhecida: %s", chave)
+	}
+	c := configuracao{valores: map[string]string{chave: valor}}
+	switch chave {
+	case ConfigValorLitro, ConfigValorKmExcedente:
+		if c.dinheiro(chave) < 0 {
+			return fmt.Errorf("configuração %s não pode ser negativa", chave)
+		}
+	case ConfigAtrasoPercentualHora:
+		if c.decimal(chave) < 0 {
+			return fmt.Errorf("configuração %s não pode ser negativa", chave)
+		}
+	case ConfigReembolsoAntecipado:
+		if p := c.decimal(chave); p < 0 || p > 100 {
+			return fmt.Errorf("configuração %s deve estar entre 0 e 100", chave)
+		}
+	case ConfigEmailFrota:
+		if !strings.Contains(valor, "@") {
+			return fmt.Errorf("configuração %s deve ser um e-mail", chave)
+		}
+	case ConfigNFSeCNPJ:
+		if !ValidarCNPJ(valor) {
+			return fmt.Errorf("configuração %s deve ser um CNPJ válido", chave)
+		}
+	case ConfigNFSeInscricaoMunicipal, ConfigNFSeRazaoSocial, ConfigNFSeSerieRPS:
+		if strings.TrimSpace(valor) == "" {
+			return fmt.Errorf("configuração %s não pode ficar vazia", chave)
+		}
+	default:
+		if c.inteiro(chave) < 0 {
+			return fmt.Errorf("configuração %s não pode ser negativa", chave)
+		}
+	}
+	return c.err
+}
+
+// ConfigInt lê uma configuração inteira (ou o padrão, se não estiver gravada)
+func ConfigInt(db *sql.DB, chave string) (int, error) {
+	valores, err := GetConfiguracoes(db)
+	if err != nil {
+		return 0, err
+	}
+	c := configuracao{valores: valores}
+	n := c.inteiro(chave)
+	return n, c.err
+}
+
+// ConfigTexto lê uma configuração textual (ou o padrão, se não estiver gravada)
+func ConfigTexto(db *sql.DB, chave string) (string, error) {
+	valores, err := GetConfiguracoes(db)
+	if err != nil {
+		return "", err
+	}
+	c := configuracao{valores: valores}
+	return c.texto(chave), nil
+}
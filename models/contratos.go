@@ -0,0 +1,145 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Contrato registra o aceite do contrato de locação pelo cliente (feito na reserva) e o PDF da
+// versão aceita — a primeira gerada, logo após o aceite —, com o hash do conteúdo. Arquivo e
+// hash não mudam depois; as versões seguintes ficam em contrato_versoes.
+type Contrato struct {
+	IDLocacao  int        `db:"id_locacao" json:"id_locacao"`
+	AceitoEm   *time.Time `db:"aceito_em" json:"aceito_em,omitempty"` // nil em locações anteriores ao aceite online
+	IPAceite   string     `db:"ip_aceite" json:"ip_aceite,omitempty"`
+	Arquivo    string     `db:"arquivo" json:"-"`
+	HashSHA256 string     `db:"hash_sha256" json:"hash_sha256,omitempty"`
+	GeradoEm   *time.Time `db:"gerado_em" json:"gerado_em,omitempty"`
+}
+
+// GetContrato devolve o contrato da locação; sem registro, devolve um contrato vazio
+// (locações anteriores ao contrato em PDF)
+func GetContrato(db *sql.DB, idLocacao int) (Contrato, error) {
+	c := Contrato{IDLocacao: idLocacao}
+	err := db.QueryRow("SELECT aceito_em, ip_aceite, arquivo, hash_sha256, gerado_em FROM contratos WHERE id_locacao = ?", idLocacao).
+		Scan(&c.AceitoEm, &c.IPAceite, &c.Arquivo, &c.HashSHA256, &c.GeradoEm)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	return c, err
+}
+
+func inserirAceiteTx(tx *sql.Tx, c Contrato) error {
+	_, err := tx.Exec("INSERT INTO contratos (id_locacao, aceito_em, ip_aceite) VALUES (?, ?, ?)", c.IDLocacao, c.AceitoEm, c.IPAceite)
+	return err
+}
+
+// VersaoContrato é um PDF do contrato gerado para a locação; cada mudança na locação (carro
+// atribuído, prorrogação) gera uma versão nova, sem sobrescrever as anteriores
+type VersaoContrato struct {
+	IDLocacao  int        `db:"id_locacao" json:"id_locacao"`
+	Versao     int        `db:"versao" json:"versao"`
+	Arquivo    string     `db:"arquivo" json:"-"` // vazio enquanto o PDF não foi gravado
+	HashSHA256 string     `db:"hash_sha256" json:"hash_sha256,omitempty"`
+	GeradoEm   *time.Time `db:"gerado_em" json:"gerado_em,omitempty"`
+}
+
+// NovaVersaoContrato reserva o próximo número de versão do contrato da locação. A versão fica
+// sem arquivo até SalvarArquivoContrato; se a geração falhar, ela continua sendo a última e o
+// próximo download gera o contrato de novo.
+func NovaVersaoContrato(db *sql.DB, idLocacao int) (int, error) {
+	var versao int
+	err := db.QueryRow(`INSERT INTO contrato_versoes (id_locacao, versao)
+		SELECT ?, COALESCE(MAX(versao), 0) + 1 FROM contrato_versoes WHERE id_locacao = ?
+		RETURNING versao`, idLocacao, idLocacao).Scan(&versao)
+	return versao, err
+}
+
+// GetVersaoContrato devolve uma versão do contrato da locação, ou a última quando versao é 0.
+// Sem versão gerada, devolve sql.ErrNoRows.
+func GetVersaoContrato(db *sql.DB, idLocacao, versao int) (VersaoContrato, error) {
+	v := VersaoContrato{IDLocacao: idLocacao}
+	err := db.QueryRow(`SELECT versao, arquivo, hash_sha256, gerado_em FROM contrato_versoes
+		WHERE id_locacao = ? AND (versao = ? OR ? = 0) ORDER BY versao DESC LIMIT 1`, idLocacao, versao, versao).
+		Scan(&v.Versao, &v.Arquivo, &v.HashSHA256, &v.GeradoEm)
+	return v, err
+}
+
+// SalvarArquivoContrato registra o PDF gravado para a versão. O primeiro PDF da locação é o da
+// versão aceita e fica também em contratos; versões seguintes não o substituem.
+func SalvarArquivoContrato(db *sql.DB, idLocacao, versao int, arquivo, hash string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	agora := time.Now().UTC()
+	if _, err := tx.Exec("UPDATE contrato_versoes SET arquivo = ?, hash_sha256 = ?, gerado_em = ? WHERE id_locacao = ? AND versao = ?",
+		arquivo, hash, agora, idLocacao, versao); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO contratos (id_locacao, arquivo, hash_sha256, gerado_em) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id_locacao) DO UPDATE SET arquivo = excluded.arquivo, hash_sha256 = excluded.hash_sha256, gerado_em = excluded.gerado_em
+		WHERE contratos.arquivo = ''`,
+		idLocacao, arquivo, hash, agora)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DadosContrato é o que o modelo do contrato (templates/contrato.txt) recebe
+type DadosContrato struct {
+	Locacao   Locacao
+	Cliente   Cliente
+	Carro     *Carro // nil enquanto a reserva por categoria não tem carro atribuído
+	Retirada  Filial
+	Devolucao Filial
+	Plano     *PlanoProtecao
+	Itens     []ItemLocacao
+	Contrato  Contrato
+	Versao    int // versão do PDF, preenchida na geração
+	EmitidoEm time.Time
+}
+
+// CarregarDadosContrato reúne locação, cliente, carro, filiais, plano e itens da locação
+func CarregarDadosContrato(db *sql.DB, idLocacao int) (DadosContrato, error) {
+	var d DadosContrato
+	var err error
+	if d.Locacao, err = GetLocacaoByID(db, idLocacao); err != nil {
+		return d, err
+	}
+	l := d.Locacao
+	if d.Cliente, err = GetClienteByID(db, l.IDCliente); err != nil {
+		return d, err
+	}
+	if l.IDCarro != 0 {
+		carro, err := GetCarroByID(db, l.IDCarro)
+		if err != nil {
+			return d, err
+		}
+		d.Carro = &carro
+	}
+	if d.Retirada, err = GetFilialByID(db, l.IDFilialRetirada); err != nil {
+		return d, err
+	}
+	if d.Devolucao, err = GetFilialByID(db, l.IDFilialDevolucao); err != nil {
+		return d, err
+	}
+	if l.IDPlano != 0 {
+		plano, err := GetPlanoByID(db, l.IDPlano)
+		if err != nil {
+			return d, err
+		}
+		d.Plano = &plano
+	}
+	if d.Itens, err = GetItensLocacao(db, idLocacao); err != nil {
+		return d, err
+	}
+	if d.Contrato, err = GetContrato(db, idLocacao); err != nil {
+		return d, err
+	}
+	d.EmitidoEm = time.Now()
+	return d, nil
+}
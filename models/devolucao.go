@@ -0,0 +1,182 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var ErrLocacaoNaoEmAndamento = errors.New("locação não está em andamento")
+
+// PoliticaDevolucao reúne os parâmetros de cobrança da devolução (tabela configuracoes)
+type PoliticaDevolucao struct {
+	ToleranciaAtraso     time.Duration
+	AtrasoHorasMax       int
+	AtrasoPercentualHora float64
+	ValorLitro           Dinheiro
+	CapacidadeTanque     int
+	KmLivresDia          int
+	ValorKmExcedente     Dinheiro
+	ReembolsoAntecipado  float64 // % do valor das diárias não usadas devolvido ao cliente
+}
+
+func CarregarPoliticaDevolucao(db *sql.DB) (PoliticaDevolucao, error) {
+	valores, err := GetConfiguracoes(db)
+	if err != nil {
+		return PoliticaDevolucao{}, err
+	}
+	c := configuracao{valores: valores}
+	p := PoliticaDevolucao{
+		ToleranciaAtraso:     time.Duration(c.inteiro(ConfigToleranciaAtrasoMin)) * time.Minute,
+		AtrasoHorasMax:       c.inteiro(ConfigAtrasoHorasMax),
+		AtrasoPercentualHora: c.decimal(ConfigAtrasoPercentualHora),
+		ValorLitro:           c.dinheiro(ConfigValorLitro),
+		CapacidadeTanque:     c.inteiro(ConfigCapacidadeTanque),
+		KmLivresDia:          c.inteiro(ConfigKmLivresDia),
+		ValorKmExcedente:     c.dinheiro(ConfigValorKmExcedente),
+		ReembolsoAntecipado:  c.decimal(ConfigReembolsoAntecipado),
+	}
+	return p, c.err
+}
+
+// PrazoDevolucao é a data e hora de devolução combinadas na reserva
+func (l Locacao) PrazoDevolucao() time.Time {
+	return l.DataFim
+}
+
+// DiasContratados conta as diárias cobradas na reserva (períodos de 24 horas)
+func (l Locacao) DiasContratados(tolerancia time.Duration) int {
+	return DiariasCobradas(l.DataInicio, l.DataFim, tolerancia)
+}
+
+// Cobrancas calcula atraso, reabastecimento e quilometragem excedente da devolução.
+// retirada pode ser nil quando não houve vistoria na saída: nesse caso considera-se
+// tanque cheio e não se cobra quilometragem. capacidadeTanque 0 usa o padrão da política.
+func (p PoliticaDevolucao) Cobrancas(l Locacao, diaria Dinheiro, retirada *Vistoria, devolucao Vistoria, capacidadeTanque int) []ItemCotacao {
+	var itens []ItemCotacao
+
+	// Atraso: tolerância, depois por hora e, passando do limite, por diária
+	if atraso := devolucao.DataHora.Sub(l.PrazoDevolucao()); atraso > p.ToleranciaAtraso {
+		horas := int(math.Ceil(atraso.Hours()))
+		if horas <= p.AtrasoHorasMax {
+			unitario := diaria.Percentual(p.AtrasoPercentualHora)
+			itens = append(itens, ItemCotacao{
+				Tipo:          ItemAtraso,
+				Descricao:     fmt.Sprintf("Atraso na devolução (%d h)", horas),
+				Quantidade:    horas,
+				ValorUnitario: unitario,
+				Valor:         unitario * Dinheiro(horas),
+			})
+		} else {
+			dias := (horas + 23) / 24
+			itens = append(itens, ItemCotacao{
+				Tipo:          ItemAtraso,
+				Descricao:     fmt.Sprintf("Atraso na devolução (%d diária(s))", dias),
+				Quantidade:    dias,
+				ValorUnitario: diaria,
+				Valor:         diaria * Dinheiro(dias),
+			})
+		}
+	}
+
+	// Combustível: oitavos que faltam em relação à retirada, convertidos em litros
+	nivelSaida := TanqueCheio
+	if retirada != nil {
+		nivelSaida = retirada.Combustivel
+	}
+	if capacidadeTanque == 0 {
+		capacidadeTanque = p.CapacidadeTanque
+	}
+	if falta := nivelSaida - devolucao.Combustivel; falta > 0 {
+		litros := (falta*capacidadeTanque + TanqueCheio - 1) / TanqueCheio
+		itens = append(itens, ItemCotacao{
+			Tipo:          ItemCombustivel,
+			Descricao:     fmt.Sprintf("Reabastecimento (%d/8 do tanque, %d L)", falta, litros),
+			Quantidade:    litros,
+			ValorUnitario: p.ValorLitro,
+			Valor:         p.ValorLitro * Dinheiro(litros),
+		})
+	}
+
+	// Quilometragem acima da franquia contratada (km livres por dia × dias)
+	if retirada != nil && p.KmLivresDia > 0 {
+		rodados := devolucao.Quilometragem - retirada.Quilometragem
+		if excedente := rodados - p.KmLivresDia*l.DiasContratados(p.ToleranciaAtraso); excedente > 0 {
+			itens = append(itens, ItemCotacao{
+				Tipo:          ItemKmExcedente,
+				Descricao:     fmt.Sprintf("Quilometragem excedente (%d km rodados)", rodados),
+				Quantidade:    excedente,
+				ValorUnitario: p.ValorKmExcedente,
+				Valor:         p.ValorKmExcedente * Dinheiro(excedente),
+			})
+		}
+	}
+
+	return itens
+}
+
+// RegistrarDevolucao grava a vistoria, lança as cobranças como itens da locação,
+// soma-as ao ValorTotal e finaliza a locação, tudo numa transação. O crédito da
+// devolução antecipada também fica registrado como ajuste em pagamentos.
+func RegistrarDevolucao(db *sql.DB, l Locacao, devolucao Vistoria, itens []ItemCotacao) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var acrescimo Dinheiro
+	for _, it := range itens {
+		acrescimo += it.Valor
+	}
+	res, err := tx.Exec("UPDATE locacoes SET valor_total = valor_total + ?, status = 'finalizada' WHERE id_locacao = ? AND status = 'em_andamento'",
+		acrescimo, l.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrLocacaoNaoEmAndamento
+	}
+
+	if err := inserirVistoriaTx(tx, devolucao); err != nil {
+		return err
+	}
+	if err := inserirItensTx(tx, l.ID, itens); err != nil {
+		return err
+	}
+	for _, it := range itens {
+		if it.Tipo == ItemDevolucaoAntecipada {
+			if err := ajusteTx(tx, l.ID, it.Valor); err != nil {
+				return err
+			}
+		}
+	}
+	// Na devolução one-way o carro passa a fazer parte da frota da filial de destino
+	if l.IDFilialDevolucao != 0 {
+		err := moverCarroTx(tx, MovimentacaoFrota{
+			IDCarro:         l.IDCarro,
+			IDFilialDestino: l.IDFilialDevolucao,
+			Motivo:          MovimentoLocacao,
+			IDLocacao:       l.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaldoLocacao é o que falta pagar: ValorTotal menos pagamentos e capturas de caução
+func SaldoLocacao(db *sql.DB, idLocacao int) (Dinheiro, error) {
+	var saldo Dinheiro
+	err := db.QueryRow(`
+		SELECT l.valor_total - COALESCE((SELECT SUM(p.valor_pago) FROM pagamentos p
+			WHERE p.id_locacao = l.id_locacao AND p.tipo IN (?, ?)), 0)
+		FROM locacoes l WHERE l.id_locacao = ?`,
+		PagamentoLocacao, PagamentoCaucaoCaptura, idLocacao).Scan(&saldo)
+	return saldo, err
+}
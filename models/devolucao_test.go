@@ -0,0 +1,81 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCobrancas(t *testing.T) {
+	p := PoliticaDevolucao{
+		ToleranciaAtraso:     time.Hour,
+		AtrasoHorasMax:       6,
+		AtrasoPercentualHora: 10,
+		ValorLitro:           Reais(6),
+		CapacidadeTanque:     50,
+		KmLivresDia:          200,
+		ValorKmExcedente:     Centavos(50),
+	}
+	inicio := time.Date(2026, 11, 16, 10, 0, 0, 0, Fuso)
+	l := Locacao{DataInicio: inicio, DataFim: inicio.Add(3 * Diaria)} // 600 km livres
+	diaria := Reais(100)
+	saida := &Vistoria{DataHora: inicio, Quilometragem: 10000, Combustivel: TanqueCheio}
+	chegada := func(atraso time.Duration, km, combustivel int) Vistoria {
+		return Vistoria{DataHora: l.DataFim.Add(atraso), Quilometragem: 10000 + km, Combustivel: combustivel}
+	}
+
+	type item struct {
+		tipo       string
+		quantidade int
+		valor      Dinheiro
+	}
+	casos := []struct {
+		nome       string
+		retirada   *Vistoria
+		devolucao  Vistoria
+		capacidade int
+		itens      []item
+	}{
+		{"no prazo, tanque cheio e dentro da franquia", saida, chegada(0, 600, TanqueCheio), 0, nil},
+		{"devolução antecipada não cobra atraso", saida, chegada(-Diaria, 100, TanqueCheio), 0, nil},
+		{"atraso dentro da tolerância", saida, chegada(59*time.Minute, 0, TanqueCheio), 0, nil},
+		{"atraso por hora", saida, chegada(3*time.Hour, 0, TanqueCheio), 0,
+			[]item{{ItemAtraso, 3, Reais(30)}}},
+		{"hora começada conta inteira", saida, chegada(time.Hour+time.Minute, 0, TanqueCheio), 0,
+			[]item{{ItemAtraso, 2, Reais(20)}}},
+		{"no limite de horas ainda cobra por hora", saida, chegada(6*time.Hour, 0, TanqueCheio), 0,
+			[]item{{ItemAtraso, 6, Reais(60)}}},
+		{"acima do limite vira diária", saida, chegada(7*time.Hour, 0, TanqueCheio), 0,
+			[]item{{ItemAtraso, 1, Reais(100)}}},
+		{"atraso de mais de um dia", saida, chegada(30*time.Hour, 0, TanqueCheio), 0,
+			[]item{{ItemAtraso, 2, Reais(200)}}},
+		// 2/8 de 50 L = 12,5 L, arredondado para cima
+		{"reabastecimento", saida, chegada(0, 0, 6), 0,
+			[]item{{ItemCombustivel, 13, Reais(78)}}},
+		{"capacidade do carro substitui a da política", saida, chegada(0, 0, 7), 40,
+			[]item{{ItemCombustivel, 5, Reais(30)}}},
+		{"devolvido com mais combustível não gera crédito", &Vistoria{DataHora: inicio, Quilometragem: 10000, Combustivel: 4}, chegada(0, 0, 6), 0, nil},
+		{"sem vistoria de saída considera tanque cheio e não cobra km", nil, chegada(0, 5000, 7), 0,
+			[]item{{ItemCombustivel, 7, Reais(42)}}},
+		{"quilometragem excedente", saida, chegada(0, 750, TanqueCheio), 0,
+			[]item{{ItemKmExcedente, 150, Reais(75)}}},
+		{"todas as cobranças", saida, chegada(2*time.Hour, 601, 4), 0,
+			[]item{{ItemAtraso, 2, Reais(20)}, {ItemCombustivel, 25, Reais(150)}, {ItemKmExcedente, 1, Centavos(50)}}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			itens := p.Cobrancas(l, diaria, c.retirada, c.devolucao, c.capacidade)
+			if len(itens) != len(c.itens) {
+				t.Fatalf("itens = %+v, esperado %+v", itens, c.itens)
+			}
+			for i, it := range itens {
+				got := item{it.Tipo, it.Quantidade, it.Valor}
+				if got != c.itens[i] {
+					t.Errorf("item %d = %+v, esperado %+v", i, got, c.itens[i])
+				}
+				if it.ValorUnitario*Dinheiro(it.Quantidade) != it.Valor {
+					t.Errorf("item %d: %d × %s ≠ %s", i, it.Quantidade, it.ValorUnitario, it.Valor)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Dinheiro guarda valores em reais (BRL) como centavos inteiros, evitando os
+// erros de arredondamento de float64 em somas e comparações.
+// No banco é gravado como INTEGER (centavos) e no JSON como string com duas
+// casas decimais ("1234.56").
+type Dinheiro int64
+
+const Moeda = "BRL"
+
+// Centavos cria um valor a partir de centavos
+func Centavos(c int64) Dinheiro {
+	return Dinheiro(c)
+}
+
+// Reais cria um valor a partir de reais inteiros
+func Reais(r int64) Dinheiro {
+	return Dinheiro(r * 100)
+}
+
+// ParseDinheiro interpreta "1234.56", "1234,56" ou "1234" sem passar por float
+func ParseDinheiro(s string) (Dinheiro, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("valor monetário vazio")
+	}
+
+	negativo := false
+	if s[0] == '-' || s[0] == '+' {
+		negativo = s[0] == '-'
+		s = s[1:]
+	}
+
+	inteiro, fracao, _ := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	// ParseInt aceitaria outro sinal em cada parte ("--5", "1.-5")
+	if !apenasAlgarismos(inteiro) || !apenasAlgarismos(fracao) || inteiro+fracao == "" {
+		return 0, fmt.Errorf("valor monetário inválido: %q", s)
+	}
+	if len(fracao) > 2 {
+		return 0, fmt.Errorf("valor monetário com mais de duas casas decimais: %q", s)
+	}
+	for len(fracao) < 2 {
+		fracao += "0"
+	}
+	if inteiro == "" {
+		inteiro = "0"
+	}
+
+	r, err := strconv.ParseInt(inteiro, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("valor monetário inválido: %q", s)
+	}
+	c, err := strconv.ParseInt(fracao, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("valor monetário inválido: %q", s)
+	}
+
+	d := Dinheiro(r*100 + c)
+	if negativo {
+		d = -d
+	}
+	return d, nil
+}
+
+func apenasAlgarismos(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Centavos devolve o valor em centavos
+func (d Dinheiro) Centavos() int64 {
+	return int64(d)
+}
+
+// Multiplicar aplica um fator (multiplicadores de tarifa, quantidades fracionadas)
+// arredondando para o centavo mais próximo
+func (d Dinheiro) Multiplicar(fator float64) Dinheiro {
+	return Dinheiro(math.Round(float64(d) * fator))
+}
+
+// Percentual calcula p% do valor, arredondado ao centavo
+func (d Dinheiro) Percentual(p float64) Dinheiro {
+	return d.Multiplicar(p / 100)
+}
+
+// String formata com ponto decimal e duas casas, como no JSON ("1234.56")
+func (d Dinheiro) String() string {
+	sinal := ""
+	v := int64(d)
+	if v < 0 {
+		sinal = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sinal, v/100, v%100)
+}
+
+// Formatar usa o padrão brasileiro para exibição: "R$ 1.234,56"
+func (d Dinheiro) Formatar() string {
+	sinal := ""
+	v := int64(d)
+	if v < 0 {
+		sinal = "-"
+		v = -v
+	}
+
+	inteiro := strconv.FormatInt(v/100, 10)
+	var b strings.Builder
+	for i, r := range inteiro {
+		if i > 0 && (len(inteiro)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(r)
+	}
+	return fmt.Sprintf("%sR$ %s,%02d", sinal, b.String(), v%100)
+}
+
+func (d Dinheiro) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON aceita tanto string ("150.90") quanto número (150.90)
+func (d *Dinheiro) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	}
+	v, err := ParseDinheiro(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (d Dinheiro) Value() (driver.Value, error) {
+	return int64(d), nil
+}
+
+// Scan lê centavos do banco. Colunas criadas como REAL em versões antigas
+// devolvem float64, mesmo já convertidas para centavos pela migração.
+func (d *Dinheiro) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = 0
+	case int64:
+		*d = Dinheiro(v)
+	case float64:
+		*d = Dinheiro(math.Round(v))
+	case []byte:
+		return d.Scan(string(v))
+	case string:
+		c, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("valor monetário inválido no banco: %q", v)
+		}
+		*d = Dinheiro(math.Round(c))
+	default:
+		return fmt.Errorf("tipo não suportado para Dinheiro: %T", src)
+	}
+	return nil
+}
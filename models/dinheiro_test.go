@@ -0,0 +1,91 @@
+package models
+
+import "testing"
+
+func TestParseDinheiro(t *testing.T) {
+	casos := []struct {
+		entrada string
+		valor   Dinheiro
+		erro    bool
+	}{
+		{"1234.56", 123456, false},
+		{"1234,56", 123456, false},
+		{"1234", 123400, false},
+		{"0,5", 50, false},
+		{".5", 50, false},
+		{"10.", 1000, false},
+		{" 99.90 ", 9990, false},
+		{"-5", -500, false},
+		{"+5.01", 501, false},
+		{"-0,07", -7, false},
+		{"", 0, true},
+		{".", 0, true},
+		{"-", 0, true},
+		{"--5", 0, true},
+		{"+-5", 0, true},
+		{"1.-5", 0, true},
+		{"1.+5", 0, true},
+		{"-1.5.0", 0, true},
+		{"1.234", 0, true}, // três casas decimais, não separador de milhar
+		{"1 000", 0, true},
+		{"R$ 10", 0, true},
+		{"1e3", 0, true},
+	}
+	for _, c := range casos {
+		v, err := ParseDinheiro(c.entrada)
+		if c.erro {
+			if err == nil {
+				t.Errorf("ParseDinheiro(%q) = %s, esperado erro", c.entrada, v)
+			}
+			continue
+		}
+		if err != nil || v != c.valor {
+			t.Errorf("ParseDinheiro(%q) = %s, %v; esperado %s", c.entrada, v, err, c.valor)
+		}
+	}
+}
+
+func TestDinheiroFormatacao(t *testing.T) {
+	casos := []struct {
+		valor            Dinheiro
+		texto, formatado string
+	}{
+		{0, "0.00", "R$ 0,00"},
+		{5, "0.05", "R$ 0,05"},
+		{123456, "1234.56", "R$ 1.234,56"},
+		{100000000, "1000000.00", "R$ 1.000.000,00"},
+		{-123456, "-1234.56", "-R$ 1.234,56"},
+	}
+	for _, c := range casos {
+		if got := c.valor.String(); got != c.texto {
+			t.Errorf("String(%d) = %q, esperado %q", c.valor, got, c.texto)
+		}
+		if got := c.valor.Formatar(); got != c.formatado {
+			t.Errorf("Formatar(%d) = %q, esperado %q", c.valor, got, c.formatado)
+		}
+		if v, err := ParseDinheiro(c.texto); err != nil || v != c.valor {
+			t.Errorf("ParseDinheiro(String(%d)) = %s, %v", c.valor, v, err)
+		}
+	}
+}
+
+func TestDinheiroMultiplicar(t *testing.T) {
+	casos := []struct {
+		valor    Dinheiro
+		fator    float64
+		esperado Dinheiro
+	}{
+		{12000, 1.15, 13800},
+		{999, 0.5, 500}, // 4,995 arredonda para cima
+		{10000, 0, 0},
+		{3333, 3, 9999},
+	}
+	for _, c := range casos {
+		if got := c.valor.Multiplicar(c.fator); got != c.esperado {
+			t.Errorf("%s × %v = %s, esperado %s", c.valor, c.fator, got, c.esperado)
+		}
+	}
+	if got := Dinheiro(25000).Percentual(10); got != 2500 {
+		t.Errorf("10%% de 250,00 = %s", got)
+	}
+}
@@ -0,0 +1,375 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Mecanismo de disponibilidade: um carro está livre num período quando está em operação
+// (disponibilidade = TRUE), nenhuma locação ativa nem manutenção agendada se sobrepõe ao período
+// e nenhum documento do carro (CRLV, IPVA...) vence antes do fim do período.
+// As locações ocupam o intervalo [data_inicio, data_fim): um carro devolvido às 10:00 pode
+// ser retirado de novo às 10:00. Manutenções e documentos são por dia, no fuso das filiais.
+//
+// A disponibilidade é por filial: o carro precisa estar na filial de retirada no início do
+// período (considerando as devoluções one-way já reservadas) e, se tiver outra locação depois,
+// ser devolvido na filial onde ela será retirada.
+
+var ErrSemCarroDisponivel = errors.New("nenhum carro da categoria disponível para o período")
+
+// Condição SQL para locações que ainda ocupam o carro (alias "l")
+const locacaoAtiva = "l.status NOT IN ('cancelada', 'finalizada')"
+
+// Condição SQL de sobreposição com o período; os argumentos são (fim, inicio)
+const sobrepoePeriodo = "julianday(l.data_inicio) < julianday(?) AND julianday(l.data_fim) > julianday(?)"
+
+// O mesmo para ordens de manutenção (alias "m") que tiram o carro de operação. As ordens
+// ocupam dias inteiros; os argumentos são as datas (AAAA-MM-DD) do último e do primeiro dia
+// tocados pelo período, ver diasDoPeriodo.
+const (
+	manutencaoAtiva           = "m.status IN ('agendada', 'em_execucao')"
+	manutencaoSobrepoePeriodo = "date(m.data_inicio) <= ? AND date(m.data_fim) >= ?"
+)
+
+// Condição SQL: algum documento do carro (alias "c") tem a validade mais recente do seu tipo
+// antes da data informada (?, AAAA-MM-DD). Carros sem documentos cadastrados não são bloqueados.
+const documentoVencido = `EXISTS (SELECT 1 FROM documentos_carro d WHERE d.id_carro = c.id_carro
+	GROUP BY d.tipo HAVING julianday(MAX(d.validade)) < julianday(?))`
+
+// Ignorar aponta o que não conta como ocupação numa verificação: a própria locação (ao
+// atribuir carro ou alterar) ou a oferta da lista de espera ou reserva temporária que o
+// cliente está transformando em locação
+type Ignorar struct {
+	Locacao int
+	Espera  int
+	Reserva int
+}
+
+// Trajeto indica as filiais de retirada e de devolução da locação
+type Trajeto struct {
+	Retirada  int
+	Devolucao int
+}
+
+// OneWay informa se o carro termina a locação em outra filial
+func (t Trajeto) OneWay() bool {
+	return t.Retirada != t.Devolucao
+}
+
+// diasDoPeriodo devolve o último e o primeiro dia local ocupados pelo período [inicio, fim)
+func diasDoPeriodo(inicio, fim time.Time) (string, string) {
+	return diaCivil(fim.Add(-time.Nanosecond)).Format("2006-01-02"), diaCivil(inicio).Format("2006-01-02")
+}
+
+// CarroLivre verifica se o carro pode ser locado no período com o trajeto informado.
+func CarroLivre(db *sql.DB, idCarro int, inicio, fim time.Time, t Trajeto, ignorar Ignorar) (bool, error) {
+	var emOperacao, vencido bool
+	err := db.QueryRow("SELECT c.disponibilidade, "+documentoVencido+" FROM carros c WHERE c.id_carro = ?", diaCivil(fim).Format("2006-01-02"), idCarro).
+		Scan(&emOperacao, &vencido)
+	if err != nil || !emOperacao || vencido {
+		return false, err
+	}
+
+	var conflitos int
+	err = db.QueryRow(`SELECT COUNT(*) FROM locacoes l
+		WHERE l.id_carro = ? AND l.id_locacao <> ? AND `+locacaoAtiva+` AND `+sobrepoePeriodo,
+		idCarro, ignorar.Locacao, fim, inicio).Scan(&conflitos)
+	if err != nil || conflitos > 0 {
+		return false, err
+	}
+
+	ultimoDia, primeiroDia := diasDoPeriodo(inicio, fim)
+	err = db.QueryRow(`SELECT COUNT(*) FROM reservas_temporarias h
+		WHERE h.id_carro = ? AND h.id_reserva <> ? AND `+reservaVigente+` AND julianday(h.data_inicio) < julianday(?) AND julianday(h.data_fim) > julianday(?)`,
+		idCarro, ignorar.Reserva, time.Now(), fim, inicio).Scan(&conflitos)
+	if err != nil || conflitos > 0 {
+		return false, err
+	}
+
+	err = db.QueryRow(`SELECT COUNT(*) FROM ordens_manutencao m
+		WHERE m.id_carro = ? AND `+manutencaoAtiva+` AND `+manutencaoSobrepoePeriodo,
+		idCarro, ultimoDia, primeiroDia).Scan(&conflitos)
+	if err != nil || conflitos > 0 {
+		return false, err
+	}
+
+	origem, err := filialPrevista(db, idCarro, inicio, ignorar)
+	if err != nil || origem != t.Retirada {
+		return false, err
+	}
+	proxima, err := proximaRetirada(db, idCarro, fim, ignorar)
+	if err != nil {
+		return false, err
+	}
+	return proxima == 0 || proxima == t.Devolucao, nil
+}
+
+// filialPrevista é onde o carro estará no instante: a filial de devolução da última locação
+// ativa encerrada até ele ou, se não houver, a filial atual do carro
+func filialPrevista(db *sql.DB, idCarro int, data time.Time, ignorar Ignorar) (int, error) {
+	var filial int
+	err := db.QueryRow(`SELECT COALESCE(l.id_filial_devolucao, 0) FROM locacoes l
+		WHERE l.id_carro = ? AND l.id_locacao <> ? AND `+locacaoAtiva+` AND julianday(l.data_fim) <= julianday(?)
+		ORDER BY julianday(l.data_fim) DESC LIMIT 1`,
+		idCarro, ignorar.Locacao, data).Scan(&filial)
+	if err == sql.ErrNoRows {
+		err = db.QueryRow("SELECT COALESCE(id_filial, 0) FROM carros WHERE id_carro = ?", idCarro).Scan(&filial)
+	}
+	return filial, err
+}
+
+// proximaRetirada é a filial de retirada da primeira locação ativa do carro a partir do instante (0 se não houver)
+func proximaRetirada(db *sql.DB, idCarro int, data time.Time, ignorar Ignorar) (int, error) {
+	var filial int
+	err := db.QueryRow(`SELECT COALESCE(l.id_filial_retirada, 0) FROM locacoes l
+		WHERE l.id_carro = ? AND l.id_locacao <> ? AND `+locacaoAtiva+` AND julianday(l.data_inicio) >= julianday(?)
+		ORDER BY julianday(l.data_inicio) LIMIT 1`,
+		idCarro, ignorar.Locacao, data).Scan(&filial)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return filial, err
+}
+
+type periodo struct {
+	inicio, fim time.Time
+	permanente  bool // one-way saindo da filial: o carro não volta depois do fim
+}
+
+// ocupacaoFilial reúne a frota de uma categoria numa filial e o que a ocupa ao longo do tempo
+type ocupacaoFilial struct {
+	frota     int
+	ocupacoes []periodo
+	chegadas  []time.Time // fim das locações one-way que trazem carros para a filial
+}
+
+// carregarOcupacao considera a frota atual da filial (com documentos válidos até validoAte),
+// as locações que saem dela ou chegam nela (com carro atribuído ou só por categoria), as ofertas
+// da lista de espera e as reservas temporárias ainda válidas e as manutenções dos seus carros
+func carregarOcupacao(db *sql.DB, categoria string, filial int, validoAte time.Time, ignorar Ignorar) (ocupacaoFilial, error) {
+	var o ocupacaoFilial
+	err := db.QueryRow(`SELECT COUNT(*) FROM carros c
+		WHERE c.categoria = ? AND c.id_filial = ? AND c.disponibilidade = TRUE AND NOT `+documentoVencido,
+		categoria, filial, diaCivil(validoAte).Format("2006-01-02")).Scan(&o.frota)
+	if err != nil {
+		return o, err
+	}
+
+	rows, err := db.Query(`SELECT l.data_inicio, l.data_fim, COALESCE(l.id_filial_retirada, 0), COALESCE(l.id_filial_devolucao, 0)
+		FROM locacoes l
+		LEFT JOIN carros c ON c.id_carro = l.id_carro
+		WHERE (c.categoria = ? OR (l.id_carro IS NULL AND l.categoria = ?))
+		AND l.id_locacao <> ? AND `+locacaoAtiva+` AND (l.id_filial_retirada = ? OR l.id_filial_devolucao = ?)`,
+		categoria, categoria, ignorar.Locacao, filial, filial)
+	if err != nil {
+		return o, err
+	}
+	if err := o.adicionarReservas(rows, filial); err != nil {
+		return o, err
+	}
+
+	ofertas, err := db.Query(`SELECT e.data_inicio, e.data_fim, e.id_filial_retirada, e.id_filial_devolucao
+		FROM lista_espera e
+		WHERE e.categoria = ? AND e.id_espera <> ? AND `+ofertaVigente+` AND (e.id_filial_retirada = ? OR e.id_filial_devolucao = ?)`,
+		categoria, ignorar.Espera, time.Now(), filial, filial)
+	if err != nil {
+		return o, err
+	}
+	if err := o.adicionarReservas(ofertas, filial); err != nil {
+		return o, err
+	}
+
+	temporarias, err := db.Query(`SELECT h.data_inicio, h.data_fim, h.id_filial_retirada, h.id_filial_devolucao
+		FROM reservas_temporarias h
+		WHERE h.categoria = ? AND h.id_reserva <> ? AND `+reservaVigente+` AND (h.id_filial_retirada = ? OR h.id_filial_devolucao = ?)`,
+		categoria, ignorar.Reserva, time.Now(), filial, filial)
+	if err != nil {
+		return o, err
+	}
+	if err := o.adicionarReservas(temporarias, filial); err != nil {
+		return o, err
+	}
+
+	manutencoes, err := db.Query(`SELECT m.data_inicio, m.data_fim FROM ordens_manutencao m
+		JOIN carros c ON c.id_carro = m.id_carro
+		WHERE c.categoria = ? AND c.id_filial = ? AND c.disponibilidade = TRUE AND `+manutencaoAtiva,
+		categoria, filial)
+	if err != nil {
+		return o, err
+	}
+	defer manutencoes.Close()
+	for manutencoes.Next() {
+		var p periodo
+		if err := manutencoes.Scan(&p.inicio, &p.fim); err != nil {
+			return o, err
+		}
+		p.inicio, p.fim = PeriodoDias(p.inicio, p.fim)
+		o.ocupacoes = append(o.ocupacoes, p)
+	}
+	return o, manutencoes.Err()
+}
+
+// adicionarReservas lê (inicio, fim, retirada, devolucao) de locações ou ofertas: as que saem
+// da filial ocupam um carro, as que chegam de outra filial trazem um carro no fim
+func (o *ocupacaoFilial) adicionarReservas(rows *sql.Rows, filial int) error {
+	defer rows.Close()
+	for rows.Next() {
+		var p periodo
+		var t Trajeto
+		if err := rows.Scan(&p.inicio, &p.fim, &t.Retirada, &t.Devolucao); err != nil {
+			return err
+		}
+		if t.Retirada != filial {
+			o.chegadas = append(o.chegadas, p.fim)
+			continue
+		}
+		p.permanente = t.OneWay()
+		o.ocupacoes = append(o.ocupacoes, p)
+	}
+	return rows.Err()
+}
+
+// comporta verifica se as ocupações cabem na frota da filial entre de e ate. O uso só cresce
+// quando uma ocupação começa, então basta conferir o instante de e o início de cada ocupação.
+func (o ocupacaoFilial) comporta(de, ate time.Time) bool {
+	instantes := []time.Time{de}
+	for _, p := range o.ocupacoes {
+		if p.inicio.After(de) && !p.inicio.After(ate) {
+			instantes = append(instantes, p.inicio)
+		}
+	}
+
+	for _, t := range instantes {
+		frota := o.frota
+		for _, c := range o.chegadas {
+			if !t.Before(c) {
+				frota++
+			}
+		}
+		emUso := 0
+		for _, p := range o.ocupacoes {
+			if !t.Before(p.inicio) && (p.permanente || t.Before(p.fim)) {
+				emUso++
+			}
+		}
+		if emUso > frota {
+			return false
+		}
+	}
+	return true
+}
+
+// horizonte é o último instante em que a ocupação da filial muda
+func (o ocupacaoFilial) horizonte() time.Time {
+	var ultima time.Time
+	for _, p := range o.ocupacoes {
+		if p.fim.After(ultima) {
+			ultima = p.fim
+		}
+	}
+	for _, c := range o.chegadas {
+		if c.After(ultima) {
+			ultima = c
+		}
+	}
+	return ultima
+}
+
+// CategoriaDisponivel verifica se sobra pelo menos um carro da categoria na filial de
+// retirada, contando locações com carro atribuído, reservas da categoria ainda sem carro e
+// manutenções. Numa locação one-way o carro não volta, então a vaga precisa existir também
+// depois do período, enquanto houver reservas na filial.
+func CategoriaDisponivel(db *sql.DB, categoria string, inicio, fim time.Time, t Trajeto, ignorar Ignorar) (bool, error) {
+	o, err := carregarOcupacao(db, categoria, t.Retirada, fim, ignorar)
+	if err != nil || (o.frota == 0 && len(o.chegadas) == 0) {
+		return false, err
+	}
+
+	ate := fim
+	if t.OneWay() && o.horizonte().After(ate) {
+		ate = o.horizonte()
+	}
+	o.ocupacoes = append(o.ocupacoes, periodo{inicio: inicio, fim: fim, permanente: t.OneWay()})
+	return o.comporta(inicio, ate), nil
+}
+
+// PeriodoDisponivel aplica a regra adequada à locação: carro específico ou categoria.
+// Reservar um carro específico também consome uma vaga da categoria dele.
+func PeriodoDisponivel(db *sql.DB, idCarro int, categoria string, inicio, fim time.Time, t Trajeto, ignorar Ignorar) (bool, error) {
+	if idCarro != 0 {
+		livre, err := CarroLivre(db, idCarro, inicio, fim, t, ignorar)
+		if err != nil || !livre || categoria == "" {
+			return livre, err
+		}
+	}
+	return CategoriaDisponivel(db, categoria, inicio, fim, t, ignorar)
+}
+
+// EscolherCarro atribui um carro da categoria para o período evitando fragmentar a disponibilidade:
+// entre os carros livres, escolhe aquele cujas locações vizinhas deixam os menores intervalos
+// ociosos antes e depois do período (best fit). Carros sem locações vizinhas ficam por último,
+// preservando blocos longos livres para reservas futuras. Só entram carros que estarão na
+// filial de retirada no início do período.
+func EscolherCarro(db *sql.DB, categoria string, inicio, fim time.Time, t Trajeto, ignorar Ignorar) (int, error) {
+	rows, err := db.Query("SELECT id_carro FROM carros WHERE categoria = ? AND disponibilidade = TRUE ORDER BY id_carro", categoria)
+	if err != nil {
+		return 0, err
+	}
+	var candidatos []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		candidatos = append(candidatos, id)
+	}
+	rows.Close()
+
+	// Intervalo usado quando não há locação vizinha (equivale a "muito longe")
+	const semVizinho = 10 * 365
+
+	escolhido, menorFolga := 0, 0.0
+	for _, id := range candidatos {
+		livre, err := CarroLivre(db, id, inicio, fim, t, ignorar)
+		if err != nil {
+			return 0, err
+		}
+		if !livre {
+			continue
+		}
+
+		var antes, depois sql.NullFloat64
+		err = db.QueryRow(`SELECT julianday(?) - MAX(julianday(l.data_fim)) FROM locacoes l
+			WHERE l.id_carro = ? AND l.id_locacao <> ? AND `+locacaoAtiva+` AND julianday(l.data_fim) <= julianday(?)`,
+			inicio, id, ignorar.Locacao, inicio).Scan(&antes)
+		if err != nil {
+			return 0, err
+		}
+		err = db.QueryRow(`SELECT MIN(julianday(l.data_inicio)) - julianday(?) FROM locacoes l
+			WHERE l.id_carro = ? AND l.id_locacao <> ? AND `+locacaoAtiva+` AND julianday(l.data_inicio) >= julianday(?)`,
+			fim, id, ignorar.Locacao, fim).Scan(&depois)
+		if err != nil {
+			return 0, err
+		}
+
+		folga := 0.0
+		for _, v := range []sql.NullFloat64{antes, depois} {
+			if v.Valid {
+				folga += v.Float64
+			} else {
+				folga += semVizinho
+			}
+		}
+
+		if escolhido == 0 || folga < menorFolga {
+			escolhido, menorFolga = id, folga
+		}
+	}
+
+	if escolhido == 0 {
+		return 0, ErrSemCarroDisponivel
+	}
+	return escolhido, nil
+}
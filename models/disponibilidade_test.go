@@ -0,0 +1,49 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOcupacaoFilialComporta(t *testing.T) {
+	base := time.Date(2026, 11, 16, 8, 0, 0, 0, Fuso)
+	h := func(n int) time.Time { return base.Add(time.Duration(n) * time.Hour) }
+	p := func(de, ate int) periodo { return periodo{inicio: h(de), fim: h(ate)} }
+	oneWay := func(de, ate int) periodo { return periodo{inicio: h(de), fim: h(ate), permanente: true} }
+
+	// Em cada caso a última ocupação é a do pedido, como em CategoriaDisponivel, e a conferência
+	// vai do início ao fim dela
+	casos := []struct {
+		nome      string
+		frota     int
+		ocupacoes []periodo
+		chegadas  []int
+		comporta  bool
+	}{
+		{"frota livre", 1, []periodo{p(0, 10)}, nil, true},
+		{"sem frota", 0, []periodo{p(0, 10)}, nil, false},
+		{"devolução e retirada no mesmo instante", 1, []periodo{p(0, 10), p(10, 20)}, nil, true},
+		{"sobreposição com um carro", 1, []periodo{p(0, 10), p(5, 15)}, nil, false},
+		{"sobreposição com dois carros", 2, []periodo{p(0, 10), p(5, 15)}, nil, true},
+		{"ocupação que começa no meio do pedido", 1, []periodo{p(5, 8), p(0, 10)}, nil, false},
+		{"ocupação que começa depois do pedido", 1, []periodo{p(12, 20), p(0, 10)}, nil, true},
+		{"três ocupações sem pico acima da frota", 2, []periodo{p(0, 6), p(6, 12), p(2, 10)}, nil, true},
+		{"três ocupações com pico acima da frota", 2, []periodo{p(0, 6), p(4, 12), p(2, 10)}, nil, false},
+		{"one-way que saiu não devolve o carro", 1, []periodo{oneWay(0, 5), p(10, 20)}, nil, false},
+		{"chegada de outra filial antes da retirada", 0, []periodo{p(6, 10)}, []int{5}, true},
+		{"chegada de outra filial depois da retirada", 0, []periodo{p(4, 10)}, []int{5}, false},
+		{"chegada repõe o one-way que saiu", 1, []periodo{oneWay(0, 5), p(10, 12)}, []int{8}, true},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			o := ocupacaoFilial{frota: c.frota, ocupacoes: c.ocupacoes}
+			for _, n := range c.chegadas {
+				o.chegadas = append(o.chegadas, h(n))
+			}
+			pedido := c.ocupacoes[len(c.ocupacoes)-1]
+			if got := o.comporta(pedido.inicio, pedido.fim); got != c.comporta {
+				t.Errorf("comporta(%s, %s) = %v, esperado %v", pedido.inicio, pedido.fim, got, c.comporta)
+			}
+		})
+	}
+}
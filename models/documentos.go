@@ -0,0 +1,107 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Tipos de documento exigidos para o carro circular
+const (
+	DocumentoCRLV              = "crlv"
+	DocumentoIPVA              = "ipva"
+	DocumentoSeguroObrigatorio = "seguro_obrigatorio"
+	DocumentoInspecao          = "inspecao"
+)
+
+var TiposDocumento = []string{DocumentoCRLV, DocumentoIPVA, DocumentoSeguroObrigatorio, DocumentoInspecao}
+
+type DocumentoCarro struct {
+	ID         int       `db:"id_documento" json:"id"`
+	IDCarro    int       `db:"id_carro" json:"id_carro"`
+	Tipo       string    `db:"tipo" json:"tipo"`
+	Numero     string    `db:"numero" json:"numero"`
+	Validade   time.Time `db:"validade" json:"validade"`
+	ArquivoURL string    `db:"arquivo_url" json:"arquivo_url,omitempty"`
+	EnviadoEm  time.Time `db:"enviado_em" json:"enviado_em"`
+}
+
+// VencimentoDocumento é um documento vigente que vence (ou já venceu) dentro da janela consultada
+type VencimentoDocumento struct {
+	DocumentoCarro
+	Placa string `json:"placa"`
+	Dias  int    `json:"dias_para_vencer"` // negativo se já venceu
+}
+
+const colunasDocumento = "id_documento, id_carro, tipo, numero, validade, arquivo_url, enviado_em"
+
+func camposDocumento(d *DocumentoCarro) []any {
+	return []any{&d.ID, &d.IDCarro, &d.Tipo, &d.Numero, &d.Validade, &d.ArquivoURL, &d.EnviadoEm}
+}
+
+// GetDocumentosCarro lista todos os documentos do carro, inclusive os já substituídos
+func GetDocumentosCarro(db *sql.DB, idCarro int) ([]DocumentoCarro, error) {
+	rows, err := db.Query("SELECT "+colunasDocumento+" FROM documentos_carro WHERE id_carro = ? ORDER BY tipo, validade DESC", idCarro)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documentos := []DocumentoCarro{}
+	for rows.Next() {
+		var d DocumentoCarro
+		if err := rows.Scan(camposDocumento(&d)...); err != nil {
+			return nil, err
+		}
+		documentos = append(documentos, d)
+	}
+	return documentos, rows.Err()
+}
+
+func CreateDocumentoCarro(db *sql.DB, d DocumentoCarro) error {
+	_, err := db.Exec(`INSERT INTO documentos_carro (id_carro, tipo, numero, validade, arquivo_url, enviado_em)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		d.IDCarro, d.Tipo, d.Numero, d.Validade, d.ArquivoURL, time.Now())
+	return err
+}
+
+// documentosVigentes seleciona, para cada carro e tipo, o documento de validade mais recente
+const documentosVigentes = `
+	SELECT d.id_documento, d.id_carro, d.tipo, d.numero, d.validade, d.arquivo_url, d.enviado_em, c.placa,
+		CAST(julianday(d.validade) - julianday(?) AS INTEGER)
+	FROM documentos_carro d
+	JOIN carros c ON c.id_carro = d.id_carro
+	WHERE d.validade = (SELECT MAX(d2.validade) FROM documentos_carro d2 WHERE d2.id_carro = d.id_carro AND d2.tipo = d.tipo)
+	AND julianday(d.validade) <= julianday(?)`
+
+// GetVencimentos lista os documentos vigentes que vencem até hoje + dias (inclui os vencidos)
+func GetVencimentos(db *sql.DB, hoje time.Time, dias int) ([]VencimentoDocumento, error) {
+	return consultarVencimentos(db, documentosVigentes+" ORDER BY d.validade", hoje, hoje.AddDate(0, 0, dias))
+}
+
+// VencimentosNaoAlertados é o mesmo que GetVencimentos, só com documentos ainda não avisados
+func VencimentosNaoAlertados(db *sql.DB, hoje time.Time, dias int) ([]VencimentoDocumento, error) {
+	return consultarVencimentos(db, documentosVigentes+" AND d.alertado_em IS NULL ORDER BY d.validade", hoje, hoje.AddDate(0, 0, dias))
+}
+
+func consultarVencimentos(db *sql.DB, query string, hoje, limite time.Time) ([]VencimentoDocumento, error) {
+	rows, err := db.Query(query, hoje.Format("2006-01-02"), limite.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vencimentos := []VencimentoDocumento{}
+	for rows.Next() {
+		var v VencimentoDocumento
+		if err := rows.Scan(append(camposDocumento(&v.DocumentoCarro), &v.Placa, &v.Dias)...); err != nil {
+			return nil, err
+		}
+		vencimentos = append(vencimentos, v)
+	}
+	return vencimentos, rows.Err()
+}
+
+func MarcarDocumentoAlertado(db *sql.DB, id int) error {
+	_, err := db.Exec("UPDATE documentos_carro SET alertado_em = ? WHERE id_documento = ?", time.Now(), id)
+	return err
+}
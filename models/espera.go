@@ -0,0 +1,161 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Situações de uma entrada da lista de espera
+const (
+	EsperaAguardando = "aguardando"
+	EsperaOferecida  = "oferecida" // vaga segura até OfertaExpiraEm
+	EsperaAtendida   = "atendida"  // virou locação
+	EsperaExpirada   = "expirada"  // a oferta venceu sem resposta
+	EsperaCancelada  = "cancelada"
+)
+
+// EsperaLocacao é um pedido de locação por categoria para um período sem disponibilidade
+type EsperaLocacao struct {
+	ID                int        `db:"id_espera" json:"id"`
+	IDCliente         int        `db:"id_cliente" json:"id_cliente"`
+	Categoria         string     `db:"categoria" json:"categoria"`
+	DataInicio        time.Time  `db:"data_inicio" json:"data_inicio"`
+	DataFim           time.Time  `db:"data_fim" json:"data_fim"`
+	IDFilialRetirada  int        `db:"id_filial_retirada" json:"id_filial_retirada"`
+	IDFilialDevolucao int        `db:"id_filial_devolucao" json:"id_filial_devolucao"`
+	Status            string     `db:"status" json:"status"`
+	CriadaEm          time.Time  `db:"criada_em" json:"criada_em"`
+	OfertaExpiraEm    *time.Time `db:"oferta_expira_em" json:"oferta_expira_em,omitempty"`
+	IDLocacao         int        `db:"id_locacao" json:"id_locacao,omitempty"`
+}
+
+var ErrOfertaIndisponivel = errors.New("a oferta da lista de espera não existe, expirou ou já foi usada")
+
+// Condição SQL: oferta (alias "e") que ainda segura a vaga no instante (?)
+const ofertaVigente = "e.status = 'oferecida' AND julianday(e.oferta_expira_em) > julianday(?)"
+
+func (e EsperaLocacao) Trajeto() Trajeto {
+	return Trajeto{Retirada: e.IDFilialRetirada, Devolucao: e.IDFilialDevolucao}
+}
+
+// OfertaValida informa se a vaga oferecida ainda está segura para o cliente
+func (e EsperaLocacao) OfertaValida(agora time.Time) bool {
+	return e.Status == EsperaOferecida && e.OfertaExpiraEm != nil && agora.Before(*e.OfertaExpiraEm)
+}
+
+const colunasEspera = "id_espera, id_cliente, categoria, data_inicio, data_fim, id_filial_retirada, id_filial_devolucao, status, criada_em, " +
+	"oferta_expira_em, COALESCE(id_locacao, 0)"
+
+func camposEspera(e *EsperaLocacao) []any {
+	return []any{&e.ID, &e.IDCliente, &e.Categoria, &e.DataInicio, &e.DataFim, &e.IDFilialRetirada, &e.IDFilialDevolucao,
+		&e.Status, &e.CriadaEm, &e.OfertaExpiraEm, &e.IDLocacao}
+}
+
+func listarEsperas(db *sql.DB, query string, args ...any) ([]EsperaLocacao, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	esperas := []EsperaLocacao{}
+	for rows.Next() {
+		var e EsperaLocacao
+		if err := rows.Scan(camposEspera(&e)...); err != nil {
+			return nil, err
+		}
+		esperas = append(esperas, e)
+	}
+	return esperas, rows.Err()
+}
+
+// GetListaEspera lista as entradas, opcionalmente de um cliente (0 = todos), das mais antigas para as mais novas
+func GetListaEspera(db *sql.DB, idCliente int) ([]EsperaLocacao, error) {
+	return listarEsperas(db, "SELECT "+colunasEspera+" FROM lista_espera WHERE ? = 0 OR id_cliente = ? ORDER BY criada_em, id_espera",
+		idCliente, idCliente)
+}
+
+func GetEsperaByID(db *sql.DB, id int) (EsperaLocacao, error) {
+	esperas, err := listarEsperas(db, "SELECT "+colunasEspera+" FROM lista_espera WHERE id_espera = ?", id)
+	if err == nil && len(esperas) == 0 {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		return EsperaLocacao{}, err
+	}
+	return esperas[0], nil
+}
+
+func CreateEspera(db *sql.DB, e EsperaLocacao) (int, error) {
+	res, err := db.Exec(`INSERT INTO lista_espera (id_cliente, categoria, data_inicio, data_fim, id_filial_retirada, id_filial_devolucao, status, criada_em)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.IDCliente, e.Categoria, e.DataInicio, e.DataFim, e.IDFilialRetirada, e.IDFilialDevolucao, EsperaAguardando, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// CancelarEspera tira o cliente da fila; uma oferta em aberto libera a vaga
+func CancelarEspera(db *sql.DB, id int) error {
+	res, err := db.Exec("UPDATE lista_espera SET status = ? WHERE id_espera = ? AND status IN (?, ?)",
+		EsperaCancelada, id, EsperaAguardando, EsperaOferecida)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AtenderEspera registra a locação criada a partir da oferta
+func AtenderEspera(db *sql.DB, id, idLocacao int) error {
+	_, err := db.Exec("UPDATE lista_espera SET status = ?, id_locacao = ? WHERE id_espera = ?", EsperaAtendida, idLocacao, id)
+	return err
+}
+
+// OferecerVagas expira as ofertas vencidas e percorre a fila da categoria por ordem de chegada,
+// oferecendo a vaga a cada entrada cujo período voltou a ter disponibilidade. Cada oferta segura
+// a vaga por validade, então as entradas seguintes já a veem ocupada. Pedidos cujo início já
+// passou saem da fila. Categoria vazia revisa todas as categorias com gente esperando.
+func OferecerVagas(db *sql.DB, categoria string, validade time.Duration) ([]EsperaLocacao, error) {
+	travaReservas.Lock()
+	defer travaReservas.Unlock()
+
+	agora := time.Now().UTC()
+	_, err := db.Exec(`UPDATE lista_espera SET status = ?
+		WHERE (status = ? AND julianday(oferta_expira_em) <= julianday(?)) OR (status = ? AND julianday(data_inicio) <= julianday(?))`,
+		EsperaExpirada, EsperaOferecida, agora, EsperaAguardando, agora)
+	if err != nil {
+		return nil, err
+	}
+
+	fila, err := listarEsperas(db, "SELECT "+colunasEspera+" FROM lista_espera WHERE status = ? AND (? = '' OR categoria = ?) ORDER BY criada_em, id_espera",
+		EsperaAguardando, categoria, categoria)
+	if err != nil {
+		return nil, err
+	}
+
+	var ofertas []EsperaLocacao
+	for _, e := range fila {
+		livre, err := CategoriaDisponivel(db, e.Categoria, e.DataInicio, e.DataFim, e.Trajeto(), Ignorar{})
+		if err != nil {
+			return ofertas, err
+		}
+		if !livre {
+			continue
+		}
+		expira := agora.Add(validade)
+		e.Status, e.OfertaExpiraEm = EsperaOferecida, &expira
+		_, err = db.Exec("UPDATE lista_espera SET status = ?, oferta_expira_em = ? WHERE id_espera = ? AND status = ?",
+			e.Status, expira, e.ID, EsperaAguardando)
+		if err != nil {
+			return ofertas, err
+		}
+		ofertas = append(ofertas, e)
+	}
+	return ofertas, nil
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrEntidadeDesconhecida = errors.New("entidade não exportável")
+
+// EntidadesExportaveis são as tabelas que podem ir para planilha
+var EntidadesExportaveis = []string{"locacoes", "pagamentos", "clientes", "carros"}
+
+// FiltroExportacao segue os filtros das listagens. IDCliente vale para locações e pagamentos,
+// Status para locações e o período (data de início da locação ou data do pagamento) para ambos.
+type FiltroExportacao struct {
+	IDCliente int
+	Status    string
+	Inicio    time.Time // zero = sem limite
+	Fim       time.Time // exclusivo; zero = sem limite
+}
+
+// DestinoExportacao recebe o cabeçalho e depois cada linha. As células são string, int,
+// bool, Dinheiro ou time.Time, para o destino formatar conforme o tipo de arquivo.
+type DestinoExportacao interface {
+	Cabecalho(colunas []string) error
+	Linha(celulas []any) error
+}
+
+// Exportar percorre a consulta linha a linha e entrega cada uma ao destino, sem carregar a
+// tabela inteira em memória
+func Exportar(db *sql.DB, entidade string, f FiltroExportacao, destino DestinoExportacao) error {
+	var (
+		cabecalho []string
+		consulta  string
+		linha     func(*sql.Rows) ([]any, error)
+		filtros   []string
+		args      []any
+	)
+	porPeriodo := func(coluna string) {
+		if !f.Inicio.IsZero() {
+			filtros = append(filtros, "julianday("+coluna+") >= julianday(?)")
+			args = append(args, f.Inicio.UTC())
+		}
+		if !f.Fim.IsZero() {
+			filtros = append(filtros, "julianday("+coluna+") < julianday(?)")
+			args = append(args, f.Fim.UTC())
+		}
+	}
+
+	switch entidade {
+	case "locacoes":
+		cabecalho = []string{"id_locacao", "id_cliente", "cliente", "id_carro", "placa", "categoria", "data_inicio", "data_fim",
+			"status", "valor_total", "valor_caucao", "franquia", "id_filial_retirada", "id_filial_devolucao"}
+		consulta = "SELECT " + colunasLocacao + `,
+			COALESCE((SELECT nome FROM clientes c WHERE c.id_cliente = locacoes.id_cliente), ''),
+			COALESCE((SELECT placa FROM carros ca WHERE ca.id_carro = locacoes.id_carro), '')
+			FROM locacoes`
+		if f.IDCliente != 0 {
+			filtros, args = append(filtros, "id_cliente = ?"), append(args, f.IDCliente)
+		}
+		if f.Status != "" {
+			filtros, args = append(filtros, "status = ?"), append(args, f.Status)
+		}
+		porPeriodo("data_inicio")
+		linha = func(rows *sql.Rows) ([]any, error) {
+			var l Locacao
+			var cliente, placa string
+			err := rows.Scan(append(camposLocacao(&l), &cliente, &placa)...)
+			return []any{l.ID, l.IDCliente, cliente, l.IDCarro, placa, l.Categoria, l.DataInicio, l.DataFim,
+				l.Status, l.ValorTotal, l.ValorCaucao, l.Franquia, l.IDFilialRetirada, l.IDFilialDevolucao}, err
+		}
+	case "pagamentos":
+		cabecalho = []string{"id_pagamento", "id_locacao", "id_cliente", "data_pagamento", "valor_pago", "forma_pagamento",
+			"status_pagamento", "tipo", "referencia_gateway", "numero_recibo"}
+		consulta = "SELECT " + colunasPagamento + `,
+			COALESCE((SELECT l.id_cliente FROM locacoes l WHERE l.id_locacao = pagamentos.id_locacao), 0),
+			COALESCE((SELECT r.numero FROM recibos r WHERE r.id_pagamento = pagamentos.id_pagamento), 0)
+			FROM pagamentos`
+		if f.IDCliente != 0 {
+			filtros = append(filtros, "id_locacao IN (SELECT id_locacao FROM locacoes WHERE id_cliente = ?)")
+			args = append(args, f.IDCliente)
+		}
+		porPeriodo("data_pagamento")
+		linha = func(rows *sql.Rows) ([]any, error) {
+			var p Pagamento
+			var idCliente, recibo int
+			err := rows.Scan(append(camposPagamento(&p), &idCliente, &recibo)...)
+			return []any{p.ID, p.IDLocacao, idCliente, p.DataPagamento, p.ValorPago, p.FormaPagamento,
+				p.StatusPagamento, p.Tipo, p.ReferenciaGateway, recibo}, err
+		}
+	case "clientes":
+		cabecalho = []string{"id_cliente", "nome", "email", "telefone", "endereco", "documento_identidade", "username", "cpf_cnpj",
+			"cnh_numero", "cnh_categoria", "cnh_validade", "data_nascimento", "cnh_primeira_habilitacao", "bloqueado", "motivo_bloqueio"}
+		consulta = "SELECT " + colunasCliente + " FROM clientes"
+		linha = func(rows *sql.Rows) ([]any, error) {
+			var c Cliente
+			err := rows.Scan(camposCliente(&c)...)
+			return []any{c.ID, c.Nome, c.Email, c.Telefone, c.Endereco, c.DocumentoIdentidade, c.Username, c.CPFCNPJ,
+				c.CNHNumero, c.CNHCategoria, c.CNHValidade, c.DataNascimento, c.CNHPrimeiraHabilitacao, c.Bloqueado, c.MotivoBloqueio}, err
+		}
+	case "carros":
+		cabecalho = []string{"id_carro", "placa", "marca", "modelo", "ano", "cor", "categoria", "valor_diaria", "disponibilidade",
+			"quilometragem", "nivel_combustivel", "tipo_combustivel", "capacidade_tanque", "transmissao", "lugares", "id_filial"}
+		consulta = "SELECT " + colunasCarro + " FROM carros"
+		linha = func(rows *sql.Rows) ([]any, error) {
+			var c Carro
+			err := rows.Scan(camposCarro(&c)...)
+			return []any{c.ID, c.Placa, c.Marca, c.Modelo, c.Ano, c.Cor, c.Categoria, c.ValorDiaria, c.Disponibilidade,
+				c.Quilometragem, c.NivelCombustivel, c.TipoCombustivel, c.CapacidadeTanque, c.Transmissao, c.Lugares, c.IDFilial}, err
+		}
+	default:
+		return ErrEntidadeDesconhecida
+	}
+
+	if len(filtros) > 0 {
+		consulta += " WHERE " + strings.Join(filtros, " AND ")
+	}
+	rows, err := db.Query(consulta+" ORDER BY 1", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if err := destino.Cabecalho(cabecalho); err != nil {
+		return err
+	}
+	for rows.Next() {
+		celulas, err := linha(rows)
+		if err != nil {
+			return err
+		}
+		if err := destino.Linha(celulas); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
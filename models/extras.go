@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Formas de cobrança de um extra
+const (
+	CobrancaDiaria = "diaria"
+	CobrancaFixa   = "fixa"
+)
+
+// Extra é um opcional que pode ser incluído na locação (GPS, cadeirinha, tag de pedágio...)
+type Extra struct {
+	ID           int      `db:"id_extra" json:"id"`
+	Nome         string   `db:"nome" json:"nome"`
+	TipoCobranca string   `db:"tipo_cobranca" json:"tipo_cobranca"` // "diaria" ou "fixa"
+	Preco        Dinheiro `db:"preco" json:"preco"`
+	Estoque      *int     `db:"estoque" json:"estoque"` // nil = sem limite
+	Ativo        bool     `db:"ativo" json:"ativo"`
+}
+
+// ExtraSolicitado é um extra pedido pelo cliente na cotação ou na reserva
+type ExtraSolicitado struct {
+	IDExtra    int `json:"id_extra"`
+	Quantidade int `json:"quantidade"`
+}
+
+// ItemLocacao é uma linha do saldo da locação (diárias, descontos, extras, taxas...)
+type ItemLocacao struct {
+	ID        int `db:"id_item" json:"id"`
+	IDLocacao int `db:"id_locacao" json:"id_locacao"`
+	ItemCotacao
+}
+
+// ItemCotacao monta a linha do extra para a quantidade e número de dias informados
+func (e Extra) ItemCotacao(quantidade, dias int) ItemCotacao {
+	item := ItemCotacao{
+		Tipo:          ItemExtra,
+		IDExtra:       e.ID,
+		Descricao:     e.Nome,
+		Quantidade:    quantidade,
+		ValorUnitario: e.Preco,
+		Valor:         e.Preco * Dinheiro(quantidade),
+	}
+	if e.TipoCobranca == CobrancaDiaria {
+		item.Descricao = fmt.Sprintf("%s (%d dias)", e.Nome, dias)
+		item.Valor *= Dinheiro(dias)
+	}
+	return item
+}
+
+const colunasExtra = "id_extra, nome, tipo_cobranca, preco, estoque, ativo"
+
+func camposExtra(e *Extra) []any {
+	return []any{&e.ID, &e.Nome, &e.TipoCobranca, &e.Preco, &e.Estoque, &e.Ativo}
+}
+
+func GetAllExtras(db *sql.DB) ([]Extra, error) {
+	rows, err := db.Query("SELECT " + colunasExtra + " FROM extras ORDER BY nome")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var extras []Extra
+	for rows.Next() {
+		var e Extra
+		if err := rows.Scan(camposExtra(&e)...); err != nil {
+			return nil, err
+		}
+		extras = append(extras, e)
+	}
+	return extras, rows.Err()
+}
+
+func GetExtraByID(db *sql.DB, id int) (Extra, error) {
+	var e Extra
+	err := db.QueryRow("SELECT "+colunasExtra+" FROM extras WHERE id_extra = ?", id).Scan(camposExtra(&e)...)
+	return e, err
+}
+
+func CreateExtra(db *sql.DB, e Extra) error {
+	_, err := db.Exec("INSERT INTO extras (nome, tipo_cobranca, preco, estoque, ativo) VALUES (?, ?, ?, ?, ?)",
+		e.Nome, e.TipoCobranca, e.Preco, e.Estoque, e.Ativo)
+	return err
+}
+
+func UpdateExtra(db *sql.DB, e Extra) error {
+	_, err := db.Exec("UPDATE extras SET nome=?, tipo_cobranca=?, preco=?, estoque=?, ativo=? WHERE id_extra=?",
+		e.Nome, e.TipoCobranca, e.Preco, e.Estoque, e.Ativo, e.ID)
+	return err
+}
+
+func DeleteExtra(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM extras WHERE id_extra = ?", id)
+	return err
+}
+
+// ExtraDisponivel verifica se ainda há estoque do extra para o período, somando
+// as quantidades reservadas em locações ativas que se sobrepõem a ele
+func ExtraDisponivel(db *sql.DB, e Extra, quantidade int, inicio, fim time.Time, ignorarLocacao int) (bool, error) {
+	if !e.Ativo {
+		return false, nil
+	}
+	if e.Estoque == nil {
+		return true, nil
+	}
+
+	var reservados int
+	err := db.QueryRow(`SELECT COALESCE(SUM(i.quantidade), 0) FROM locacao_itens i
+		JOIN locacoes l ON l.id_locacao = i.id_locacao
+		WHERE i.id_extra = ? AND l.id_locacao <> ? AND `+locacaoAtiva+` AND `+sobrepoePeriodo,
+		e.ID, ignorarLocacao, fim, inicio).Scan(&reservados)
+	if err != nil {
+		return false, err
+	}
+	return reservados+quantidade <= *e.Estoque, nil
+}
+
+// --- ItemLocacao ---
+
+func GetItensLocacao(db *sql.DB, idLocacao int) ([]ItemLocacao, error) {
+	rows, err := db.Query(`SELECT id_item, id_locacao, tipo, COALESCE(id_extra, 0), descricao, quantidade, valor_unitario, valor
+		FROM locacao_itens WHERE id_locacao = ? ORDER BY id_item`, idLocacao)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var itens []ItemLocacao
+	for rows.Next() {
+		var i ItemLocacao
+		err := rows.Scan(&i.ID, &i.IDLocacao, &i.Tipo, &i.IDExtra, &i.Descricao, &i.Quantidade, &i.ValorUnitario, &i.Valor)
+		if err != nil {
+			return nil, err
+		}
+		itens = append(itens, i)
+	}
+	return itens, rows.Err()
+}
+
+func inserirItensTx(tx *sql.Tx, idLocacao int, itens []ItemCotacao) error {
+	for _, it := range itens {
+		_, err := tx.Exec(`INSERT INTO locacao_itens (id_locacao, tipo, id_extra, descricao, quantidade, valor_unitario, valor)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			idLocacao, it.Tipo, nuloSeZero(it.IDExtra), it.Descricao, it.Quantidade, it.ValorUnitario, it.Valor)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateLocacaoComItens grava a locação, as linhas da cotação e o aceite do contrato numa única transação
+func CreateLocacaoComItens(db *sql.DB, l Locacao, itens []ItemCotacao, aceite Contrato) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`INSERT INTO locacoes (id_cliente, id_carro, data_inicio, data_fim, valor_total, status, valor_caucao, categoria, id_plano, franquia,
+		id_filial_retirada, id_filial_devolucao)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		l.IDCliente, nuloSeZero(l.IDCarro), l.DataInicio, l.DataFim, l.ValorTotal, l.Status, l.ValorCaucao, l.Categoria,
+		nuloSeZero(l.IDPlano), l.Franquia, nuloSeZero(l.IDFilialRetirada), nuloSeZero(l.IDFilialDevolucao))
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := inserirItensTx(tx, int(id), itens); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	aceite.IDLocacao = int(id)
+	if err := inserirAceiteTx(tx, aceite); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return int(id), tx.Commit()
+}
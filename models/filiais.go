@@ -0,0 +1,269 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Filial struct {
+	ID                int    `db:"id_filial" json:"id"`
+	Nome              string `db:"nome" json:"nome"`
+	Endereco          string `db:"endereco" json:"endereco"`
+	Cidade            string `db:"cidade" json:"cidade"`
+	UF                string `db:"uf" json:"uf"`
+	HorarioAbertura   string `db:"horario_abertura" json:"horario_abertura"`     // HH:MM
+	HorarioFechamento string `db:"horario_fechamento" json:"horario_fechamento"` // HH:MM
+	AbreFeriados      bool   `db:"abre_feriados" json:"abre_feriados"`
+	Ativa             bool   `db:"ativa" json:"ativa"`
+}
+
+// TaxaRetorno é cobrada quando o carro retirado na origem é devolvido no destino
+type TaxaRetorno struct {
+	ID              int      `db:"id_taxa" json:"id"`
+	IDFilialOrigem  int      `db:"id_filial_origem" json:"id_filial_origem"`
+	IDFilialDestino int      `db:"id_filial_destino" json:"id_filial_destino"`
+	Valor           Dinheiro `db:"valor" json:"valor"`
+}
+
+// Motivos de movimentação da frota entre filiais
+const (
+	MovimentoTransferencia = "transferencia"
+	MovimentoLocacao       = "locacao" // devolução one-way
+)
+
+type MovimentacaoFrota struct {
+	ID              int       `db:"id_movimentacao" json:"id"`
+	IDCarro         int       `db:"id_carro" json:"id_carro"`
+	IDFilialOrigem  int       `db:"id_filial_origem" json:"id_filial_origem"`
+	IDFilialDestino int       `db:"id_filial_destino" json:"id_filial_destino"`
+	Motivo          string    `db:"motivo" json:"motivo"`
+	IDLocacao       int       `db:"id_locacao" json:"id_locacao,omitempty"`
+	Observacao      string    `db:"observacao" json:"observacao,omitempty"`
+	DataHora        time.Time `db:"data_hora" json:"data_hora"`
+}
+
+var (
+	ErrRetornoNaoOferecido = errors.New("devolução nesta filial não é oferecida a partir da filial de retirada")
+	ErrCarroComLocacao     = errors.New("o carro está locado ou reservado para retirada em outra filial")
+	ErrFilialDesfalcada    = errors.New("a transferência deixaria a filial de origem sem carros para as reservas já feitas")
+)
+
+// ItemCotacao monta a linha da taxa de retorno da locação one-way
+func (t TaxaRetorno) ItemCotacao(destino string) ItemCotacao {
+	return ItemCotacao{
+		Tipo:          ItemTaxaRetorno,
+		Descricao:     "Taxa de retorno (devolução em " + destino + ")",
+		Quantidade:    1,
+		ValorUnitario: t.Valor,
+		Valor:         t.Valor,
+	}
+}
+
+// --- Filial ---
+
+const colunasFilial = "id_filial, nome, endereco, cidade, uf, horario_abertura, horario_fechamento, abre_feriados, ativa"
+
+func camposFilial(f *Filial) []any {
+	return []any{&f.ID, &f.Nome, &f.Endereco, &f.Cidade, &f.UF, &f.HorarioAbertura, &f.HorarioFechamento, &f.AbreFeriados, &f.Ativa}
+}
+
+func GetAllFiliais(db *sql.DB) ([]Filial, error) {
+	rows, err := db.Query("SELECT " + colunasFilial + " FROM filiais ORDER BY nome")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filiais []Filial
+	for rows.Next() {
+		var f Filial
+		if err := rows.Scan(camposFilial(&f)...); err != nil {
+			return nil, err
+		}
+		filiais = append(filiais, f)
+	}
+	return filiais, rows.Err()
+}
+
+func GetFilialByID(db *sql.DB, id int) (Filial, error) {
+	var f Filial
+	err := db.QueryRow("SELECT "+colunasFilial+" FROM filiais WHERE id_filial = ?", id).Scan(camposFilial(&f)...)
+	return f, err
+}
+
+// GetFilialPadrao retorna a filial ativa mais antiga, usada quando a filial não é informada
+func GetFilialPadrao(db *sql.DB) (Filial, error) {
+	var f Filial
+	err := db.QueryRow("SELECT " + colunasFilial + " FROM filiais WHERE ativa = TRUE ORDER BY id_filial LIMIT 1").
+		Scan(camposFilial(&f)...)
+	return f, err
+}
+
+func CreateFilial(db *sql.DB, f Filial) error {
+	_, err := db.Exec(`INSERT INTO filiais (nome, endereco, cidade, uf, horario_abertura, horario_fechamento, abre_feriados, ativa)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		f.Nome, f.Endereco, f.Cidade, f.UF, f.HorarioAbertura, f.HorarioFechamento, f.AbreFeriados, f.Ativa)
+	return err
+}
+
+func UpdateFilial(db *sql.DB, f Filial) error {
+	_, err := db.Exec(`UPDATE filiais SET nome=?, endereco=?, cidade=?, uf=?, horario_abertura=?, horario_fechamento=?, abre_feriados=?, ativa=?
+		WHERE id_filial=?`,
+		f.Nome, f.Endereco, f.Cidade, f.UF, f.HorarioAbertura, f.HorarioFechamento, f.AbreFeriados, f.Ativa, f.ID)
+	return err
+}
+
+// DeleteFilial falha (chave estrangeira) se a filial tiver carros, locações ou movimentações
+func DeleteFilial(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM filiais WHERE id_filial = ?", id)
+	return err
+}
+
+// --- TaxaRetorno ---
+
+func GetAllTaxasRetorno(db *sql.DB) ([]TaxaRetorno, error) {
+	rows, err := db.Query("SELECT id_taxa, id_filial_origem, id_filial_destino, valor FROM taxas_retorno ORDER BY id_filial_origem, id_filial_destino")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var taxas []TaxaRetorno
+	for rows.Next() {
+		var t TaxaRetorno
+		if err := rows.Scan(&t.ID, &t.IDFilialOrigem, &t.IDFilialDestino, &t.Valor); err != nil {
+			return nil, err
+		}
+		taxas = append(taxas, t)
+	}
+	return taxas, rows.Err()
+}
+
+// GetTaxaRetorno busca a taxa do par de filiais; ErrRetornoNaoOferecido se o par não estiver cadastrado
+func GetTaxaRetorno(db *sql.DB, origem, destino int) (TaxaRetorno, error) {
+	var t TaxaRetorno
+	err := db.QueryRow("SELECT id_taxa, id_filial_origem, id_filial_destino, valor FROM taxas_retorno WHERE id_filial_origem = ? AND id_filial_destino = ?",
+		origem, destino).Scan(&t.ID, &t.IDFilialOrigem, &t.IDFilialDestino, &t.Valor)
+	if err == sql.ErrNoRows {
+		return t, ErrRetornoNaoOferecido
+	}
+	return t, err
+}
+
+// SalvarTaxaRetorno cria ou atualiza a taxa do par de filiais
+func SalvarTaxaRetorno(db *sql.DB, t TaxaRetorno) error {
+	_, err := db.Exec(`INSERT INTO taxas_retorno (id_filial_origem, id_filial_destino, valor) VALUES (?, ?, ?)
+		ON CONFLICT(id_filial_origem, id_filial_destino) DO UPDATE SET valor = excluded.valor`,
+		t.IDFilialOrigem, t.IDFilialDestino, t.Valor)
+	return err
+}
+
+func DeleteTaxaRetorno(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM taxas_retorno WHERE id_taxa = ?", id)
+	return err
+}
+
+// --- Movimentação da frota ---
+
+// GetMovimentacoes lista as movimentações, opcionalmente de um carro ou envolvendo uma filial (0 = todos)
+func GetMovimentacoes(db *sql.DB, idCarro, idFilial int) ([]MovimentacaoFrota, error) {
+	rows, err := db.Query(`SELECT id_movimentacao, id_carro, COALESCE(id_filial_origem, 0), id_filial_destino, motivo,
+		COALESCE(id_locacao, 0), observacao, data_hora
+		FROM movimentacoes_frota
+		WHERE (? = 0 OR id_carro = ?) AND (? = 0 OR id_filial_origem = ? OR id_filial_destino = ?)
+		ORDER BY data_hora DESC, id_movimentacao DESC`,
+		idCarro, idCarro, idFilial, idFilial, idFilial)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movimentacoes := []MovimentacaoFrota{}
+	for rows.Next() {
+		var m MovimentacaoFrota
+		if err := rows.Scan(&m.ID, &m.IDCarro, &m.IDFilialOrigem, &m.IDFilialDestino, &m.Motivo,
+			&m.IDLocacao, &m.Observacao, &m.DataHora); err != nil {
+			return nil, err
+		}
+		movimentacoes = append(movimentacoes, m)
+	}
+	return movimentacoes, rows.Err()
+}
+
+// moverCarroTx troca a filial atual do carro e registra a movimentação; não faz nada se o
+// carro já estiver no destino
+func moverCarroTx(tx *sql.Tx, m MovimentacaoFrota) error {
+	var atual sql.NullInt64
+	if err := tx.QueryRow("SELECT id_filial FROM carros WHERE id_carro = ?", m.IDCarro).Scan(&atual); err != nil {
+		return err
+	}
+	if int(atual.Int64) == m.IDFilialDestino {
+		return nil
+	}
+	if _, err := tx.Exec("UPDATE carros SET id_filial = ? WHERE id_carro = ?", m.IDFilialDestino, m.IDCarro); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT INTO movimentacoes_frota (id_carro, id_filial_origem, id_filial_destino, motivo, id_locacao, observacao, data_hora)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		m.IDCarro, atual, m.IDFilialDestino, m.Motivo, nuloSeZero(m.IDLocacao), m.Observacao, time.Now())
+	return err
+}
+
+// TransferirCarro leva o carro para outra filial. A transferência é recusada se o carro estiver
+// locado ou se a próxima locação dele for retirada em filial diferente do destino; também não
+// pode deixar a filial de origem sem carros para as reservas por categoria já feitas.
+func TransferirCarro(db *sql.DB, idCarro, destino int, observacao string) error {
+	// A filial de origem é conferida e o carro sai dela sem que outra reserva entre no meio
+	travaReservas.Lock()
+	defer travaReservas.Unlock()
+
+	carro, err := GetCarroByID(db, idCarro)
+	if err != nil {
+		return err
+	}
+	if carro.IDFilial == destino {
+		return nil
+	}
+
+	var emAndamento int
+	err = db.QueryRow("SELECT COUNT(*) FROM locacoes WHERE id_carro = ? AND status = 'em_andamento'", idCarro).Scan(&emAndamento)
+	if err != nil {
+		return err
+	}
+	proxima, err := proximaRetirada(db, idCarro, time.Time{}, Ignorar{})
+	if err != nil {
+		return err
+	}
+	if emAndamento > 0 || (proxima != 0 && proxima != destino) {
+		return ErrCarroComLocacao
+	}
+
+	if carro.Disponibilidade && carro.Categoria != "" {
+		agora := time.Now()
+		ocupacao, err := carregarOcupacao(db, carro.Categoria, carro.IDFilial, agora, Ignorar{})
+		if err != nil {
+			return err
+		}
+		ocupacao.frota--
+		if !ocupacao.comporta(agora, ocupacao.horizonte()) {
+			return ErrFilialDesfalcada
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = moverCarroTx(tx, MovimentacaoFrota{
+		IDCarro:         idCarro,
+		IDFilialDestino: destino,
+		Motivo:          MovimentoTransferencia,
+		Observacao:      observacao,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
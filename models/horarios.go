@@ -0,0 +1,158 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	_ "time/tzdata" // o fuso não depende do zoneinfo instalado no servidor
+)
+
+// Fuso das filiais: horários informados pelos clientes são locais; o banco guarda instantes em UTC
+var Fuso = carregarFuso()
+
+func carregarFuso() *time.Location {
+	fuso, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		panic(err)
+	}
+	return fuso
+}
+
+// Período de cobrança de uma diária
+const Diaria = 24 * time.Hour
+
+// HorarioFilial substitui o horário padrão da filial num dia da semana (0 = domingo)
+type HorarioFilial struct {
+	DiaSemana  int    `db:"dia_semana" json:"dia_semana"`
+	Abertura   string `db:"abertura" json:"abertura,omitempty"`     // HH:MM
+	Fechamento string `db:"fechamento" json:"fechamento,omitempty"` // HH:MM
+	Fechado    bool   `db:"fechado" json:"fechado"`
+}
+
+// DiariasCobradas conta os períodos de 24 horas entre retirada e devolução. Passar do último
+// período por até tolerancia não gera nova diária; qualquer locação cobra ao menos uma.
+func DiariasCobradas(inicio, fim time.Time, tolerancia time.Duration) int {
+	duracao := fim.Sub(inicio) - tolerancia
+	if duracao <= 0 {
+		return 1
+	}
+	return int((duracao + Diaria - 1) / Diaria)
+}
+
+// diaCivil é a data local (no fuso das filiais) do instante, à meia-noite UTC, como as
+// colunas DATE do banco (feriados, temporadas, manutenções, documentos)
+func diaCivil(t time.Time) time.Time {
+	local := t.In(Fuso)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// inicioDoDia é a meia-noite local do dia de uma coluna DATE
+func inicioDoDia(dia time.Time) time.Time {
+	return time.Date(dia.Year(), dia.Month(), dia.Day(), 0, 0, 0, 0, Fuso)
+}
+
+// PeriodoDias converte um intervalo de datas inclusivo (ex.: uma manutenção) no intervalo
+// de instantes equivalente: da meia-noite local do primeiro dia à do dia seguinte ao último
+func PeriodoDias(inicio, fim time.Time) (time.Time, time.Time) {
+	return inicioDoDia(inicio), inicioDoDia(fim).AddDate(0, 0, 1)
+}
+
+func GetHorariosFilial(db *sql.DB, idFilial int) ([]HorarioFilial, error) {
+	rows, err := db.Query("SELECT dia_semana, abertura, fechamento, fechado FROM horarios_filial WHERE id_filial = ? ORDER BY dia_semana", idFilial)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	horarios := []HorarioFilial{}
+	for rows.Next() {
+		var h HorarioFilial
+		if err := rows.Scan(&h.DiaSemana, &h.Abertura, &h.Fechamento, &h.Fechado); err != nil {
+			return nil, err
+		}
+		horarios = append(horarios, h)
+	}
+	return horarios, rows.Err()
+}
+
+// SalvarHorariosFilial substitui todos os horários especiais da filial
+func SalvarHorariosFilial(db *sql.DB, idFilial int, horarios []HorarioFilial) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM horarios_filial WHERE id_filial = ?", idFilial); err != nil {
+		return err
+	}
+	for _, h := range horarios {
+		_, err := tx.Exec("INSERT INTO horarios_filial (id_filial, dia_semana, abertura, fechamento, fechado) VALUES (?, ?, ?, ?, ?)",
+			idFilial, h.DiaSemana, h.Abertura, h.Fechamento, h.Fechado)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Expediente diz se a filial abre no dia (local) do instante e, se abrir, em que horário.
+// Nos feriados a filial fica fechada, a não ser que abra em feriados; nesse caso vale o
+// horário do dia da semana.
+func Expediente(db *sql.DB, f Filial, t time.Time) (abertura, fechamento time.Time, aberta bool, err error) {
+	dia := diaCivil(t)
+	if !f.AbreFeriados {
+		var n int
+		err = db.QueryRow("SELECT COUNT(*) FROM feriados WHERE data = ?", dia.Format("2006-01-02")).Scan(&n)
+		if err != nil || n > 0 {
+			return abertura, fechamento, false, err
+		}
+	}
+
+	h := HorarioFilial{Abertura: f.HorarioAbertura, Fechamento: f.HorarioFechamento}
+	err = db.QueryRow("SELECT abertura, fechamento, fechado FROM horarios_filial WHERE id_filial = ? AND dia_semana = ?",
+		f.ID, int(dia.Weekday())).Scan(&h.Abertura, &h.Fechamento, &h.Fechado)
+	if err != nil && err != sql.ErrNoRows {
+		return abertura, fechamento, false, err
+	}
+	if h.Fechado {
+		return abertura, fechamento, false, nil
+	}
+
+	local := inicioDoDia(dia)
+	abre, err1 := time.Parse("15:04", h.Abertura)
+	fecha, err2 := time.Parse("15:04", h.Fechamento)
+	if err1 != nil || err2 != nil {
+		return abertura, fechamento, false, fmt.Errorf("horário inválido na filial %d: %s-%s", f.ID, h.Abertura, h.Fechamento)
+	}
+	abertura = local.Add(time.Duration(abre.Hour())*time.Hour + time.Duration(abre.Minute())*time.Minute)
+	fechamento = local.Add(time.Duration(fecha.Hour())*time.Hour + time.Duration(fecha.Minute())*time.Minute)
+	return abertura, fechamento, true, nil
+}
+
+// ValidarHorarioAtendimento confere se o instante é um horário de atendimento da filial:
+// dentro do expediente e no início de um intervalo de agendamento (ex.: de 30 em 30 minutos).
+// campo identifica no erro se é a retirada ou a devolução.
+func ValidarHorarioAtendimento(db *sql.DB, f Filial, t time.Time, intervalo time.Duration, campo string) error {
+	if intervalo <= 0 {
+		intervalo = time.Minute
+	}
+	local := t.In(Fuso)
+	desdeMeiaNoite := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	if local.Second() != 0 || local.Nanosecond() != 0 || desdeMeiaNoite%intervalo != 0 {
+		return ErroValidacao{campo, fmt.Sprintf("Horários são agendados de %d em %d minutos", int(intervalo.Minutes()), int(intervalo.Minutes()))}
+	}
+
+	abertura, fechamento, aberta, err := Expediente(db, f, t)
+	if err != nil {
+		return err
+	}
+	if !aberta {
+		return ErroValidacao{campo, fmt.Sprintf("A filial %s não abre em %s", f.Nome, local.Format("02/01/2006"))}
+	}
+	if t.Before(abertura) || t.After(fechamento) {
+		return ErroValidacao{campo, fmt.Sprintf("A filial %s atende em %s das %s às %s",
+			f.Nome, local.Format("02/01/2006"), abertura.Format("15:04"), fechamento.Format("15:04"))}
+	}
+	return nil
+}
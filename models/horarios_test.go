@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiariasCobradas(t *testing.T) {
+	inicio := time.Date(2026, 11, 16, 10, 0, 0, 0, Fuso)
+	tolerancia := time.Hour
+	casos := []struct {
+		nome       string
+		fim        time.Time
+		tolerancia time.Duration
+		diarias    int
+	}{
+		{"menos de um dia cobra uma diária", inicio.Add(3 * time.Hour), tolerancia, 1},
+		{"devolução antes da retirada cobra uma diária", inicio.Add(-time.Hour), tolerancia, 1},
+		{"exatamente 24 horas", inicio.Add(Diaria), tolerancia, 1},
+		{"atraso dentro da tolerância", inicio.Add(Diaria + tolerancia), tolerancia, 1},
+		{"atraso além da tolerância", inicio.Add(Diaria + tolerancia + time.Minute), tolerancia, 2},
+		{"sem tolerância, um minuto a mais", inicio.Add(Diaria + time.Minute), 0, 2},
+		{"três dias", inicio.Add(3 * Diaria), tolerancia, 3},
+		{"três dias e meio", inicio.Add(3*Diaria + 12*time.Hour), tolerancia, 4},
+		// Conta períodos de 24 horas, não dias do calendário
+		{"devolução no dia seguinte antes do horário da retirada", time.Date(2026, 11, 17, 9, 0, 0, 0, Fuso), tolerancia, 1},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			if got := DiariasCobradas(inicio, c.fim, c.tolerancia); got != c.diarias {
+				t.Errorf("DiariasCobradas(%s, %s, %s) = %d, esperado %d", inicio, c.fim, c.tolerancia, got, c.diarias)
+			}
+		})
+	}
+}
@@ -0,0 +1,428 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrFormatoImportacao = errors.New("formato de importação inválido (use csv ou json)")
+
+// ErroLinha aponta o problema de um registro: no CSV, Linha é a linha do arquivo (o cabeçalho
+// é a linha 1); no JSON, a posição do item na lista, a partir de 1
+type ErroLinha struct {
+	Linha    int    `json:"linha"`
+	Campo    string `json:"campo,omitempty"`
+	Mensagem string `json:"mensagem"`
+}
+
+// ResultadoImportacao resume a importação. Com qualquer erro nada é gravado; na simulação
+// os registros são validados e inseridos numa transação desfeita no final.
+type ResultadoImportacao struct {
+	Simulacao  bool        `json:"simulacao"`
+	Registros  int         `json:"registros"`
+	Importados int         `json:"importados"`
+	Erros      []ErroLinha `json:"erros"`
+}
+
+// ClienteImportado é o cliente com a senha de acesso inicial
+type ClienteImportado struct {
+	Cliente
+	Senha string `json:"senha"`
+}
+
+// registroCSV lê os campos de uma linha pelo nome da coluna, guardando o primeiro erro de conversão
+type registroCSV struct {
+	valores map[string]string
+	erro    *ErroValidacao
+}
+
+func (r *registroCSV) texto(coluna string) string {
+	return strings.TrimSpace(r.valores[coluna])
+}
+
+func (r *registroCSV) inteiro(coluna string, destino *int) {
+	v := r.texto(coluna)
+	if v == "" {
+		return
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil && r.erro == nil {
+		r.erro = &ErroValidacao{coluna, fmt.Sprintf("%s deve ser um número inteiro", coluna)}
+	}
+	*destino = n
+}
+
+func (r *registroCSV) dinheiro(coluna string, destino *Dinheiro) {
+	v := r.texto(coluna)
+	if v == "" {
+		return
+	}
+	d, err := ParseDinheiro(v)
+	if err != nil && r.erro == nil {
+		r.erro = &ErroValidacao{coluna, err.Error()}
+	}
+	*destino = d
+}
+
+func (r *registroCSV) booleano(coluna string, destino *bool) {
+	switch strings.ToLower(r.texto(coluna)) {
+	case "":
+	case "sim", "s", "true", "1":
+		*destino = true
+	case "não", "nao", "n", "false", "0":
+		*destino = false
+	default:
+		if r.erro == nil {
+			r.erro = &ErroValidacao{coluna, coluna + " deve ser sim ou não"}
+		}
+	}
+}
+
+// lerCSV aceita separador "," ou ";" (o do cabeçalho vale para o arquivo) e o BOM que o Excel
+// grava. Colunas desconhecidas, como os IDs da exportação, são ignoradas.
+func lerCSV(r io.Reader) ([]registroCSV, error) {
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+	cabecalho, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	leitor := csv.NewReader(io.MultiReader(strings.NewReader(cabecalho), br))
+	if strings.Count(cabecalho, ";") > strings.Count(cabecalho, ",") {
+		leitor.Comma = ';'
+	}
+	leitor.FieldsPerRecord = -1
+
+	colunas, err := leitor.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cabeçalho do CSV: %w", err)
+	}
+	for i := range colunas {
+		colunas[i] = strings.ToLower(strings.TrimSpace(colunas[i]))
+	}
+	var registros []registroCSV
+	for {
+		campos, err := leitor.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		r := registroCSV{valores: map[string]string{}}
+		for i, v := range campos {
+			if i < len(colunas) {
+				r.valores[colunas[i]] = v
+			}
+		}
+		registros = append(registros, r)
+	}
+	return registros, nil
+}
+
+// numeroLinha converte a posição do registro na numeração informada em ErroLinha
+func numeroLinha(formato string, i int) int {
+	if formato == "csv" {
+		return i + 2
+	}
+	return i + 1
+}
+
+// ImportarCarros cadastra os carros de um CSV (colunas como na exportação) ou de uma lista
+// JSON no formato de /carros/criar, com os mesmos padrões da ficha técnica
+func ImportarCarros(db *sql.DB, r io.Reader, formato string, simular bool) (ResultadoImportacao, error) {
+	res := ResultadoImportacao{Simulacao: simular, Erros: []ErroLinha{}}
+	padrao := Carro{NivelCombustivel: TanqueCheio, TipoCombustivel: "flex", Transmissao: "manual", Lugares: 5, Disponibilidade: true}
+
+	var carros []Carro
+	var conversao = map[int]ErroValidacao{}
+	switch formato {
+	case "json":
+		var brutos []json.RawMessage
+		if err := json.NewDecoder(r).Decode(&brutos); err != nil {
+			return res, fmt.Errorf("%w: %w", ErrFormatoImportacao, err)
+		}
+		for i, b := range brutos {
+			c := padrao
+			if err := json.Unmarshal(b, &c); err != nil {
+				conversao[i] = ErroValidacao{"", "JSON inválido: " + err.Error()}
+			}
+			carros = append(carros, c)
+		}
+	case "csv":
+		registros, err := lerCSV(r)
+		if err != nil {
+			return res, fmt.Errorf("%w: %w", ErrFormatoImportacao, err)
+		}
+		for i, reg := range registros {
+			c := padrao
+			c.Placa, c.Marca, c.Modelo, c.Cor = reg.texto("placa"), reg.texto("marca"), reg.texto("modelo"), reg.texto("cor")
+			c.Categoria = reg.texto("categoria")
+			reg.inteiro("ano", &c.Ano)
+			reg.dinheiro("valor_diaria", &c.ValorDiaria)
+			reg.booleano("disponibilidade", &c.Disponibilidade)
+			reg.inteiro("quilometragem", &c.Quilometragem)
+			reg.inteiro("nivel_combustivel", &c.NivelCombustivel)
+			if v := reg.texto("tipo_combustivel"); v != "" {
+				c.TipoCombustivel = v
+			}
+			reg.inteiro("capacidade_tanque", &c.CapacidadeTanque)
+			if v := reg.texto("transmissao"); v != "" {
+				c.Transmissao = v
+			}
+			reg.inteiro("lugares", &c.Lugares)
+			reg.inteiro("id_filial", &c.IDFilial)
+			if reg.erro != nil {
+				conversao[i] = *reg.erro
+			}
+			carros = append(carros, c)
+		}
+	default:
+		return res, ErrFormatoImportacao
+	}
+	res.Registros = len(carros)
+
+	categorias, err := GetAllCategorias(db)
+	if err != nil {
+		return res, err
+	}
+	filiais, err := GetAllFiliais(db)
+	if err != nil {
+		return res, err
+	}
+	existeCategoria := map[string]bool{"": true}
+	for _, c := range categorias {
+		existeCategoria[c.Codigo] = true
+	}
+	filialAtiva := map[int]bool{}
+	for _, f := range filiais {
+		filialAtiva[f.ID] = f.Ativa
+	}
+	filialPadrao, errPadrao := GetFilialPadrao(db)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	placas := map[string]int{}
+	for i, c := range carros {
+		linha := numeroLinha(formato, i)
+		c.Placa = strings.ToUpper(strings.TrimSpace(c.Placa))
+		if c.IDFilial == 0 && errPadrao == nil {
+			c.IDFilial = filialPadrao.ID
+		}
+
+		var duplicada int
+		erro, temErro := conversao[i]
+		switch {
+		case temErro:
+		case c.Placa == "" || strings.TrimSpace(c.Modelo) == "":
+			erro, temErro = ErroValidacao{"placa", "Placa e modelo são obrigatórios"}, true
+		case placas[c.Placa] != 0:
+			erro, temErro = ErroValidacao{"placa", fmt.Sprintf("Placa %s repetida (linha %d)", c.Placa, placas[c.Placa])}, true
+		case !existeCategoria[c.Categoria]:
+			erro, temErro = ErroValidacao{"categoria", "Categoria inexistente: " + c.Categoria}, true
+		case c.IDFilial == 0 || !filialAtiva[c.IDFilial]:
+			erro, temErro = ErroValidacao{"id_filial", "Filial inexistente ou inativa"}, true
+		default:
+			if err := ValidarCarro(c); err != nil {
+				erro, temErro = err.(ErroValidacao), true
+				break
+			}
+			if err := tx.QueryRow("SELECT COUNT(*) FROM carros WHERE upper(placa) = ?", c.Placa).Scan(&duplicada); err != nil {
+				return res, err
+			}
+			if duplicada > 0 {
+				erro, temErro = ErroValidacao{"placa", "Placa " + c.Placa + " já cadastrada"}, true
+			}
+		}
+		if c.Placa != "" && placas[c.Placa] == 0 {
+			placas[c.Placa] = linha
+		}
+		if temErro {
+			res.Erros = append(res.Erros, ErroLinha{Linha: linha, Campo: erro.Campo, Mensagem: erro.Mensagem})
+			continue
+		}
+		if _, err := tx.Exec(inserirCarro, valoresCarro(c)...); err != nil {
+			res.Erros = append(res.Erros, ErroLinha{Linha: linha, Mensagem: "Erro ao gravar: " + err.Error()})
+			continue
+		}
+		res.Importados++
+	}
+	return concluirImportacao(tx, res)
+}
+
+// ImportarClientes cadastra clientes (com usuário e senha inicial) de um CSV ou de uma lista
+// JSON no formato de /clientes/criar. CPF/CNPJ é obrigatório e, como no cadastro pelo admin,
+// o e-mail já entra verificado.
+func ImportarClientes(db *sql.DB, r io.Reader, formato string, simular bool) (ResultadoImportacao, error) {
+	res := ResultadoImportacao{Simulacao: simular, Erros: []ErroLinha{}}
+
+	var clientes []ClienteImportado
+	var conversao = map[int]ErroValidacao{}
+	switch formato {
+	case "json":
+		var brutos []json.RawMessage
+		if err := json.NewDecoder(r).Decode(&brutos); err != nil {
+			return res, fmt.Errorf("%w: %w", ErrFormatoImportacao, err)
+		}
+		for i, b := range brutos {
+			var c ClienteImportado
+			if err := json.Unmarshal(b, &c); err != nil {
+				conversao[i] = ErroValidacao{"", "JSON inválido: " + err.Error()}
+			}
+			clientes = append(clientes, c)
+		}
+	case "csv":
+		registros, err := lerCSV(r)
+		if err != nil {
+			return res, fmt.Errorf("%w: %w", ErrFormatoImportacao, err)
+		}
+		for _, reg := range registros {
+			clientes = append(clientes, ClienteImportado{Senha: reg.texto("senha"), Cliente: Cliente{
+				Nome: reg.texto("nome"), Email: reg.texto("email"), Telefone: reg.texto("telefone"), Endereco: reg.texto("endereco"),
+				DocumentoIdentidade: reg.texto("documento_identidade"), Username: reg.texto("username"), CPFCNPJ: reg.texto("cpf_cnpj"),
+				CNHNumero: reg.texto("cnh_numero"), CNHCategoria: reg.texto("cnh_categoria"), CNHValidade: reg.texto("cnh_validade"),
+				DataNascimento: reg.texto("data_nascimento"), CNHPrimeiraHabilitacao: reg.texto("cnh_primeira_habilitacao"),
+			}})
+		}
+	default:
+		return res, ErrFormatoImportacao
+	}
+	res.Registros = len(clientes)
+
+	// Validação sem transação aberta: as consultas de duplicidade não seguram o banco
+	vistos := map[string]map[string]int{}
+	for i := range clientes {
+		c := &clientes[i]
+		linha := numeroLinha(formato, i)
+		NormalizarDocumentos(&c.Cliente)
+		c.Username = strings.TrimSpace(c.Username)
+		c.Email = strings.TrimSpace(c.Email)
+
+		erro, temErro := conversao[i]
+		if !temErro {
+			erro, temErro = validarClienteImportado(db, *c, vistos)
+		}
+		for campo, valor := range chavesUnicasCliente(c.Cliente) {
+			if valor == "" {
+				continue
+			}
+			if vistos[campo] == nil {
+				vistos[campo] = map[string]int{}
+			}
+			if vistos[campo][valor] == 0 {
+				vistos[campo][valor] = linha
+			}
+		}
+		if temErro {
+			res.Erros = append(res.Erros, ErroLinha{Linha: linha, Campo: erro.Campo, Mensagem: erro.Mensagem})
+		}
+	}
+	if len(res.Erros) > 0 {
+		return res, nil
+	}
+
+	// O hash da senha é a parte cara; só é calculado quando a importação vai ser gravada e
+	// antes de abrir a transação, para não travar as escritas enquanto roda
+	hashes := make([]string, len(clientes))
+	if !simular {
+		for i, c := range clientes {
+			var err error
+			if hashes[i], err = HashPassword(c.Senha); err != nil {
+				return res, err
+			}
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+	for i, c := range clientes {
+		if err := inserirClienteTx(tx, c.Cliente, hashes[i], true); err != nil {
+			// Cadastro feito por outro caminho entre a validação e a gravação
+			if campo := CampoViolado(err); campo != "" {
+				res.Erros = append(res.Erros, ErroLinha{Linha: numeroLinha(formato, i), Campo: campo, Mensagem: nomesCamposUnicos[campo] + " já cadastrado"})
+				continue
+			}
+			res.Erros = append(res.Erros, ErroLinha{Linha: numeroLinha(formato, i), Mensagem: "Erro ao gravar: " + err.Error()})
+			continue
+		}
+		res.Importados++
+	}
+	return concluirImportacao(tx, res)
+}
+
+// chavesUnicasCliente são os campos que não podem se repetir entre clientes, como estão
+// gravados (e-mail sem diferença de maiúsculas)
+func chavesUnicasCliente(c Cliente) map[string]string {
+	return map[string]string{
+		"username":             c.Username,
+		"email":                strings.ToLower(c.Email),
+		"documento_identidade": c.DocumentoIdentidade,
+		"cpf_cnpj":             c.CPFCNPJ,
+	}
+}
+
+// Nomes dos campos únicos nas mensagens de duplicidade
+var nomesCamposUnicos = map[string]string{
+	"username":             "Usuário",
+	"email":                "E-mail",
+	"documento_identidade": "Documento de identidade",
+	"cpf_cnpj":             "CPF/CNPJ",
+}
+
+// validarClienteImportado confere os campos obrigatórios, os documentos e a duplicidade de
+// usuário, e-mail e documentos no arquivo (linhas já vistas) e no banco
+func validarClienteImportado(db *sql.DB, c ClienteImportado, vistos map[string]map[string]int) (ErroValidacao, bool) {
+	if strings.TrimSpace(c.Nome) == "" || c.Senha == "" || c.Username == "" {
+		return ErroValidacao{"nome", "Nome, senha e nome de usuário são obrigatórios"}, true
+	}
+	if err := ValidarDocumentosCliente(c.Cliente, true); err != nil {
+		var ev ErroValidacao
+		if errors.As(err, &ev) {
+			return ev, true
+		}
+		return ErroValidacao{"", err.Error()}, true
+	}
+	chaves := chavesUnicasCliente(c.Cliente)
+	for _, campo := range []string{"username", "email", "documento_identidade", "cpf_cnpj"} {
+		if l := vistos[campo][chaves[campo]]; chaves[campo] != "" && l != 0 {
+			return ErroValidacao{campo, fmt.Sprintf("%s repetido (linha %d)", nomesCamposUnicos[campo], l)}, true
+		}
+	}
+
+	campo, err := CampoDuplicado(db, c.Cliente)
+	if err != nil {
+		return ErroValidacao{"", "Erro ao verificar duplicidade: " + err.Error()}, true
+	}
+	if campo != "" {
+		return ErroValidacao{campo, nomesCamposUnicos[campo] + " já cadastrado"}, true
+	}
+	return ErroValidacao{}, false
+}
+
+// concluirImportacao grava tudo só se não houve erro e não é simulação
+func concluirImportacao(tx *sql.Tx, res ResultadoImportacao) (ResultadoImportacao, error) {
+	if len(res.Erros) > 0 || res.Simulacao {
+		if len(res.Erros) > 0 {
+			res.Importados = 0
+		}
+		return res, tx.Rollback()
+	}
+	return res, tx.Commit()
+}
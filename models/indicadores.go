@@ -0,0 +1,233 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Agrupamentos da receita por período
+const (
+	AgruparDia    = "dia"
+	AgruparSemana = "semana" // semanas começam na segunda-feira
+	AgruparMes    = "mes"
+)
+
+// locacaoContabilizada: reservas canceladas não ocupam carro nem geram receita
+const locacaoContabilizada = "l.status <> 'cancelada'"
+
+// sobreposicao são os dias da locação l dentro do período (?1 = fim, ?2 = início, em dia juliano)
+const sobreposicao = "MAX(0, MIN(julianday(l.data_fim), ?1) - MAX(julianday(l.data_inicio), ?2))"
+
+// UtilizacaoFrota é a ocupação de um carro ou de uma categoria no período. A receita das
+// locações é rateada pelo tempo que cada uma ocupa dentro do período.
+type UtilizacaoFrota struct {
+	IDCarro     int      `json:"id_carro,omitempty"`
+	Placa       string   `json:"placa,omitempty"`
+	Modelo      string   `json:"modelo,omitempty"`
+	Categoria   string   `json:"categoria"`
+	Carros      int      `json:"carros,omitempty"`
+	DiasLocados float64  `json:"dias_locados"`
+	Utilizacao  float64  `json:"utilizacao"` // % do tempo disponível
+	Receita     Dinheiro `json:"receita"`
+}
+
+// ReceitaPeriodo soma os recebimentos (pagamentos da locação e capturas de caução) de um dia,
+// semana ou mês; Periodo é a data do dia, da segunda-feira da semana ou AAAA-MM
+type ReceitaPeriodo struct {
+	Periodo    string   `json:"periodo"`
+	Valor      Dinheiro `json:"valor"`
+	Pagamentos int      `json:"pagamentos"`
+}
+
+type ClienteDestaque struct {
+	IDCliente int      `json:"id_cliente"`
+	Nome      string   `json:"nome"`
+	Locacoes  int      `json:"locacoes"`
+	Valor     Dinheiro `json:"valor"`
+}
+
+// Indicadores reúne os KPIs da frota em [Inicio, Fim)
+type Indicadores struct {
+	Inicio        time.Time         `json:"inicio"`
+	Fim           time.Time         `json:"fim"`
+	Dias          float64           `json:"dias"`
+	Carros        int               `json:"carros"`
+	Utilizacao    float64           `json:"utilizacao"` // % da frota inteira
+	PorCarro      []UtilizacaoFrota `json:"por_carro"`
+	PorCategoria  []UtilizacaoFrota `json:"por_categoria"`
+	ReceitaLocada Dinheiro          `json:"receita_locada"` // valor das locações rateado no período
+	// RevPAC: receita locada por carro disponível por dia
+	RevPAC            Dinheiro          `json:"revpac"`
+	Recebido          Dinheiro          `json:"recebido"`
+	Agrupamento       string            `json:"agrupamento"`
+	ReceitaPorPeriodo []ReceitaPeriodo  `json:"receita_por_periodo"`
+	DuracaoMediaDias  float64           `json:"duracao_media_dias"` // locações iniciadas no período
+	LocacoesIniciadas int               `json:"locacoes_iniciadas"`
+	AReceber          Dinheiro          `json:"a_receber"` // saldo devedor atual de todas as locações
+	LocacoesAReceber  int               `json:"locacoes_a_receber"`
+	TopClientes       []ClienteDestaque `json:"top_clientes"`
+}
+
+// CalcularIndicadores agrega locações e pagamentos do período. A frota disponível é a atual
+// (todos os carros cadastrados), e cada carro conta o período inteiro como disponível.
+func CalcularIndicadores(db *sql.DB, inicio, fim time.Time, agrupamento string, top int) (Indicadores, error) {
+	ind := Indicadores{Inicio: inicio, Fim: fim, Agrupamento: agrupamento, Dias: fim.Sub(inicio).Hours() / 24}
+	var err error
+	if ind.PorCarro, err = utilizacaoPorCarro(db, inicio, fim, ind.Dias); err != nil {
+		return ind, err
+	}
+
+	// Os carros vêm ordenados por categoria
+	ind.PorCategoria = []UtilizacaoFrota{}
+	var diasLocados float64
+	for _, c := range ind.PorCarro {
+		if n := len(ind.PorCategoria); n == 0 || ind.PorCategoria[n-1].Categoria != c.Categoria {
+			ind.PorCategoria = append(ind.PorCategoria, UtilizacaoFrota{Categoria: c.Categoria})
+		}
+		cat := &ind.PorCategoria[len(ind.PorCategoria)-1]
+		cat.Carros++
+		cat.DiasLocados += c.DiasLocados
+		cat.Receita += c.Receita
+		diasLocados += c.DiasLocados
+		ind.ReceitaLocada += c.Receita
+	}
+	for i := range ind.PorCategoria {
+		cat := &ind.PorCategoria[i]
+		cat.Utilizacao = percentualOcupado(cat.DiasLocados, float64(cat.Carros)*ind.Dias)
+		cat.DiasLocados = duasCasas(cat.DiasLocados)
+	}
+	ind.Carros = len(ind.PorCarro)
+	ind.Utilizacao = percentualOcupado(diasLocados, float64(ind.Carros)*ind.Dias)
+	if diasDisponiveis := float64(ind.Carros) * ind.Dias; diasDisponiveis > 0 {
+		ind.RevPAC = ind.ReceitaLocada.Multiplicar(1 / diasDisponiveis)
+	}
+
+	if ind.ReceitaPorPeriodo, err = receitaPorPeriodo(db, inicio, fim, agrupamento); err != nil {
+		return ind, err
+	}
+	for _, r := range ind.ReceitaPorPeriodo {
+		ind.Recebido += r.Valor
+	}
+
+	err = db.QueryRow(`SELECT COUNT(*), COALESCE(AVG(julianday(l.data_fim) - julianday(l.data_inicio)), 0)
+		FROM locacoes l WHERE `+locacaoContabilizada+` AND julianday(l.data_inicio) >= julianday(?) AND julianday(l.data_inicio) < julianday(?)`,
+		inicio.UTC(), fim.UTC()).Scan(&ind.LocacoesIniciadas, &ind.DuracaoMediaDias)
+	if err != nil {
+		return ind, err
+	}
+	ind.DuracaoMediaDias = duasCasas(ind.DuracaoMediaDias)
+
+	err = db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(saldo), 0) FROM (
+			SELECT l.valor_total - COALESCE((SELECT SUM(p.valor_pago) FROM pagamentos p
+				WHERE p.id_locacao = l.id_locacao AND p.tipo IN (?, ?)), 0) AS saldo
+			FROM locacoes l WHERE `+locacaoContabilizada+`
+		) WHERE saldo > 0`, PagamentoLocacao, PagamentoCaucaoCaptura).Scan(&ind.LocacoesAReceber, &ind.AReceber)
+	if err != nil {
+		return ind, err
+	}
+
+	ind.TopClientes, err = topClientes(db, inicio, fim, top)
+	return ind, err
+}
+
+func percentualOcupado(dias, disponiveis float64) float64 {
+	if disponiveis <= 0 {
+		return 0
+	}
+	return duasCasas(dias / disponiveis * 100)
+}
+
+func duasCasas(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func utilizacaoPorCarro(db *sql.DB, inicio, fim time.Time, dias float64) ([]UtilizacaoFrota, error) {
+	jdInicio, jdFim := julianday(inicio), julianday(fim)
+	rows, err := db.Query(`SELECT c.id_carro, c.placa, c.modelo, c.categoria,
+			COALESCE(SUM(`+sobreposicao+`), 0),
+			COALESCE(SUM(l.valor_total * `+sobreposicao+` / MAX(julianday(l.data_fim) - julianday(l.data_inicio), 1.0 / 1440)), 0)
+		FROM carros c
+		LEFT JOIN locacoes l ON l.id_carro = c.id_carro AND `+locacaoContabilizada+`
+			AND julianday(l.data_inicio) < ?1 AND julianday(l.data_fim) > ?2
+		GROUP BY c.id_carro ORDER BY c.categoria, c.id_carro`, jdFim, jdInicio)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	carros := []UtilizacaoFrota{}
+	for rows.Next() {
+		var u UtilizacaoFrota
+		if err := rows.Scan(&u.IDCarro, &u.Placa, &u.Modelo, &u.Categoria, &u.DiasLocados, &u.Receita); err != nil {
+			return nil, err
+		}
+		u.Utilizacao = percentualOcupado(u.DiasLocados, dias)
+		u.DiasLocados = duasCasas(u.DiasLocados)
+		carros = append(carros, u)
+	}
+	return carros, rows.Err()
+}
+
+// receitaPorPeriodo agrupa pela data local dos pagamentos
+func receitaPorPeriodo(db *sql.DB, inicio, fim time.Time, agrupamento string) ([]ReceitaPeriodo, error) {
+	_, deslocamento := inicio.In(Fuso).Zone()
+	local := fmt.Sprintf("'%+d seconds'", deslocamento)
+	var periodo string
+	switch agrupamento {
+	case AgruparSemana:
+		periodo = "date(p.data_pagamento, " + local + ", 'weekday 0', '-6 days')"
+	case AgruparMes:
+		periodo = "strftime('%Y-%m', p.data_pagamento, " + local + ")"
+	default:
+		periodo = "date(p.data_pagamento, " + local + ")"
+	}
+	rows, err := db.Query(`SELECT `+periodo+` AS periodo, COALESCE(SUM(p.valor_pago), 0), COUNT(*)
+		FROM pagamentos p
+		WHERE p.tipo IN (?, ?) AND julianday(p.data_pagamento) >= julianday(?) AND julianday(p.data_pagamento) < julianday(?)
+		GROUP BY periodo ORDER BY periodo`,
+		PagamentoLocacao, PagamentoCaucaoCaptura, inicio.UTC(), fim.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receitas := []ReceitaPeriodo{}
+	for rows.Next() {
+		var r ReceitaPeriodo
+		if err := rows.Scan(&r.Periodo, &r.Valor, &r.Pagamentos); err != nil {
+			return nil, err
+		}
+		receitas = append(receitas, r)
+	}
+	return receitas, rows.Err()
+}
+
+// topClientes ordena pelo valor das locações iniciadas no período
+func topClientes(db *sql.DB, inicio, fim time.Time, limite int) ([]ClienteDestaque, error) {
+	rows, err := db.Query(`SELECT c.id_cliente, c.nome, COUNT(*), SUM(l.valor_total) AS valor
+		FROM locacoes l JOIN clientes c ON c.id_cliente = l.id_cliente
+		WHERE `+locacaoContabilizada+` AND julianday(l.data_inicio) >= julianday(?) AND julianday(l.data_inicio) < julianday(?)
+		GROUP BY c.id_cliente ORDER BY valor DESC, c.id_cliente LIMIT ?`,
+		inicio.UTC(), fim.UTC(), limite)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clientes := []ClienteDestaque{}
+	for rows.Next() {
+		var c ClienteDestaque
+		if err := rows.Scan(&c.IDCliente, &c.Nome, &c.Locacoes, &c.Valor); err != nil {
+			return nil, err
+		}
+		clientes = append(clientes, c)
+	}
+	return clientes, rows.Err()
+}
+
+// julianday converte para o dia juliano usado pelo SQLite
+func julianday(t time.Time) float64 {
+	return float64(t.UTC().UnixNano())/float64(24*time.Hour) + 2440587.5
+}
@@ -0,0 +1,253 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Status de uma ordem de manutenção; agendada e em_execucao bloqueiam o carro
+const (
+	OrdemAgendada   = "agendada"
+	OrdemEmExecucao = "em_execucao"
+	OrdemConcluida  = "concluida"
+	OrdemCancelada  = "cancelada"
+)
+
+// PlanoManutencao é uma manutenção preventiva que vence por quilometragem ou por tempo,
+// o que ocorrer primeiro. Zero desativa o critério; categoria vazia vale para toda a frota.
+type PlanoManutencao struct {
+	ID             int    `db:"id_plano_manutencao" json:"id"`
+	Descricao      string `db:"descricao" json:"descricao"`
+	Categoria      string `db:"categoria" json:"categoria"`
+	IntervaloKm    int    `db:"intervalo_km" json:"intervalo_km"`
+	IntervaloMeses int    `db:"intervalo_meses" json:"intervalo_meses"`
+	Ativo          bool   `db:"ativo" json:"ativo"`
+}
+
+type OrdemManutencao struct {
+	ID            int       `db:"id_ordem" json:"id"`
+	IDCarro       int       `db:"id_carro" json:"id_carro"`
+	IDPlano       int       `db:"id_plano_manutencao" json:"id_plano,omitempty"` // 0 = corretiva
+	Descricao     string    `db:"descricao" json:"descricao"`
+	Oficina       string    `db:"oficina" json:"oficina"`
+	DataInicio    time.Time `db:"data_inicio" json:"data_inicio"`
+	DataFim       time.Time `db:"data_fim" json:"data_fim"`
+	Custo         Dinheiro  `db:"custo" json:"custo"`
+	Status        string    `db:"status" json:"status"`
+	Quilometragem int       `db:"quilometragem" json:"quilometragem"` // odômetro na conclusão
+}
+
+// AlertaManutencao aponta um plano vencido ou perto de vencer para um carro
+type AlertaManutencao struct {
+	IDCarro       int    `json:"id_carro"`
+	Placa         string `json:"placa"`
+	IDPlano       int    `json:"id_plano"`
+	Descricao     string `json:"descricao"`
+	Quilometragem int    `json:"quilometragem"`
+	ProximaKm     int    `json:"proxima_km,omitempty"`
+	ProximaData   string `json:"proxima_data,omitempty"`
+	Situacao      string `json:"situacao"` // "vencida" ou "proxima"
+}
+
+// --- Planos ---
+
+const colunasPlanoManutencao = "id_plano_manutencao, descricao, categoria, intervalo_km, intervalo_meses, ativo"
+
+func camposPlanoManutencao(p *PlanoManutencao) []any {
+	return []any{&p.ID, &p.Descricao, &p.Categoria, &p.IntervaloKm, &p.IntervaloMeses, &p.Ativo}
+}
+
+func GetAllPlanosManutencao(db *sql.DB) ([]PlanoManutencao, error) {
+	rows, err := db.Query("SELECT " + colunasPlanoManutencao + " FROM planos_manutencao ORDER BY id_plano_manutencao")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var planos []PlanoManutencao
+	for rows.Next() {
+		var p PlanoManutencao
+		if err := rows.Scan(camposPlanoManutencao(&p)...); err != nil {
+			return nil, err
+		}
+		planos = append(planos, p)
+	}
+	return planos, rows.Err()
+}
+
+func GetPlanoManutencaoByID(db *sql.DB, id int) (PlanoManutencao, error) {
+	var p PlanoManutencao
+	err := db.QueryRow("SELECT "+colunasPlanoManutencao+" FROM planos_manutencao WHERE id_plano_manutencao = ?", id).
+		Scan(camposPlanoManutencao(&p)...)
+	return p, err
+}
+
+func CreatePlanoManutencao(db *sql.DB, p PlanoManutencao) error {
+	_, err := db.Exec("INSERT INTO planos_manutencao (descricao, categoria, intervalo_km, intervalo_meses, ativo) VALUES (?, ?, ?, ?, ?)",
+		p.Descricao, p.Categoria, p.IntervaloKm, p.IntervaloMeses, p.Ativo)
+	return err
+}
+
+func UpdatePlanoManutencao(db *sql.DB, p PlanoManutencao) error {
+	_, err := db.Exec("UPDATE planos_manutencao SET descricao=?, categoria=?, intervalo_km=?, intervalo_meses=?, ativo=? WHERE id_plano_manutencao=?",
+		p.Descricao, p.Categoria, p.IntervaloKm, p.IntervaloMeses, p.Ativo, p.ID)
+	return err
+}
+
+func DeletePlanoManutencao(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM planos_manutencao WHERE id_plano_manutencao = ?", id)
+	return err
+}
+
+// --- Ordens ---
+
+const colunasOrdem = "id_ordem, id_carro, COALESCE(id_plano_manutencao, 0), descricao, oficina, data_inicio, data_fim, custo, status, quilometragem"
+
+func camposOrdem(o *OrdemManutencao) []any {
+	return []any{&o.ID, &o.IDCarro, &o.IDPlano, &o.Descricao, &o.Oficina, &o.DataInicio, &o.DataFim, &o.Custo, &o.Status, &o.Quilometragem}
+}
+
+// GetOrdensManutencao lista as ordens, opcionalmente de um carro só (idCarro 0 = todos)
+func GetOrdensManutencao(db *sql.DB, idCarro int) ([]OrdemManutencao, error) {
+	rows, err := db.Query("SELECT "+colunasOrdem+" FROM ordens_manutencao WHERE ? = 0 OR id_carro = ? ORDER BY data_inicio DESC",
+		idCarro, idCarro)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ordens []OrdemManutencao
+	for rows.Next() {
+		var o OrdemManutencao
+		if err := rows.Scan(camposOrdem(&o)...); err != nil {
+			return nil, err
+		}
+		ordens = append(ordens, o)
+	}
+	return ordens, rows.Err()
+}
+
+func GetOrdemByID(db *sql.DB, id int) (OrdemManutencao, error) {
+	var o OrdemManutencao
+	err := db.QueryRow("SELECT "+colunasOrdem+" FROM ordens_manutencao WHERE id_ordem = ?", id).Scan(camposOrdem(&o)...)
+	return o, err
+}
+
+func CreateOrdem(db *sql.DB, o OrdemManutencao) error {
+	_, err := db.Exec(`INSERT INTO ordens_manutencao (id_carro, id_plano_manutencao, descricao, oficina, data_inicio, data_fim, custo, status, quilometragem)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		o.IDCarro, nuloSeZero(o.IDPlano), o.Descricao, o.Oficina, o.DataInicio, o.DataFim, o.Custo, o.Status, o.Quilometragem)
+	return err
+}
+
+// UpdateOrdem não altera carro nem datas: para remarcar, cancele e crie outra ordem
+func UpdateOrdem(db *sql.DB, o OrdemManutencao) error {
+	_, err := db.Exec("UPDATE ordens_manutencao SET descricao=?, oficina=?, custo=?, status=?, quilometragem=? WHERE id_ordem=?",
+		o.Descricao, o.Oficina, o.Custo, o.Status, o.Quilometragem, o.ID)
+	return err
+}
+
+// ManutencaoPossivel verifica se o carro pode sair de operação no período sem
+// prejudicar locações: nenhuma locação dele e ainda uma vaga na categoria em cada dia
+// (para não deixar reservas por categoria sem carro). inicio e fim são datas inclusivas.
+func ManutencaoPossivel(db *sql.DB, idCarro int, inicio, fim time.Time) (bool, error) {
+	inicio, fim = PeriodoDias(inicio, fim)
+	var conflitos int
+	err := db.QueryRow(`SELECT COUNT(*) FROM locacoes l
+		WHERE l.id_carro = ? AND `+locacaoAtiva+` AND `+sobrepoePeriodo,
+		idCarro, fim, inicio).Scan(&conflitos)
+	if err != nil || conflitos > 0 {
+		return false, err
+	}
+
+	// Uma reserva temporária deste carro já prometeu o período ao cliente no checkout
+	err = db.QueryRow(`SELECT COUNT(*) FROM reservas_temporarias h
+		WHERE h.id_carro = ? AND `+reservaVigente+` AND julianday(h.data_inicio) < julianday(?) AND julianday(h.data_fim) > julianday(?)`,
+		idCarro, time.Now(), fim, inicio).Scan(&conflitos)
+	if err != nil || conflitos > 0 {
+		return false, err
+	}
+
+	var categoria string
+	var filial int
+	var emOperacao bool
+	err = db.QueryRow("SELECT categoria, COALESCE(id_filial, 0), disponibilidade FROM carros WHERE id_carro = ?", idCarro).
+		Scan(&categoria, &filial, &emOperacao)
+	if err != nil {
+		return false, err
+	}
+	if !emOperacao || categoria == "" {
+		return true, nil // carro fora da frota locável não tira vaga de ninguém
+	}
+	return CategoriaDisponivel(db, categoria, inicio, fim, Trajeto{filial, filial}, Ignorar{})
+}
+
+// --- Alertas ---
+
+// AlertasManutencao cruza cada carro com os planos ativos da sua categoria e aponta os
+// vencidos ou que vencem dentro das margens. A base é a última ordem concluída do plano;
+// sem histórico, conta a quilometragem desde zero e não há vencimento por tempo.
+// Planos com ordem já agendada ou em execução para o carro não geram alerta.
+func AlertasManutencao(db *sql.DB, hoje time.Time, margemKm, margemDias int) ([]AlertaManutencao, error) {
+	planos, err := GetAllPlanosManutencao(db)
+	if err != nil {
+		return nil, err
+	}
+	carros, err := GetAllCarros(db)
+	if err != nil {
+		return nil, err
+	}
+
+	alertas := []AlertaManutencao{}
+	for _, c := range carros {
+		for _, p := range planos {
+			if !p.Ativo || (p.Categoria != "" && p.Categoria != c.Categoria) {
+				continue
+			}
+
+			var abertas int
+			if err := db.QueryRow("SELECT COUNT(*) FROM ordens_manutencao m WHERE m.id_carro = ? AND m.id_plano_manutencao = ? AND "+manutencaoAtiva,
+				c.ID, p.ID).Scan(&abertas); err != nil {
+				return nil, err
+			}
+			if abertas > 0 {
+				continue
+			}
+
+			var ultimaKm int
+			var ultimaData time.Time
+			err := db.QueryRow(`SELECT quilometragem, data_fim FROM ordens_manutencao
+				WHERE id_carro = ? AND id_plano_manutencao = ? AND status = ?
+				ORDER BY data_fim DESC LIMIT 1`, c.ID, p.ID, OrdemConcluida).Scan(&ultimaKm, &ultimaData)
+			if err != nil && err != sql.ErrNoRows {
+				return nil, err
+			}
+			temHistorico := err == nil
+
+			a := AlertaManutencao{IDCarro: c.ID, Placa: c.Placa, IDPlano: p.ID, Descricao: p.Descricao, Quilometragem: c.Quilometragem}
+			if p.IntervaloKm > 0 {
+				a.ProximaKm = ultimaKm + p.IntervaloKm
+				switch {
+				case c.Quilometragem >= a.ProximaKm:
+					a.Situacao = "vencida"
+				case c.Quilometragem >= a.ProximaKm-margemKm:
+					a.Situacao = "proxima"
+				}
+			}
+			if p.IntervaloMeses > 0 && temHistorico {
+				proxima := ultimaData.AddDate(0, p.IntervaloMeses, 0)
+				a.ProximaData = proxima.Format("2006-01-02")
+				switch {
+				case !hoje.Before(proxima):
+					a.Situacao = "vencida"
+				case a.Situacao == "" && !hoje.Before(proxima.AddDate(0, 0, -margemDias)):
+					a.Situacao = "proxima"
+				}
+			}
+			if a.Situacao != "" {
+				alertas = append(alertas, a)
+			}
+		}
+	}
+	return alertas, nil
+}
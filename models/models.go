@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -22,38 +23,74 @@ type Cliente struct {
 	Endereco            string `db:"endereco" json:"endereco"`
 	DocumentoIdentidade string `db:"documento_identidade" json:"documento_identidade"`
 	Username            string `db:"username" json:"username"`
+	CPFCNPJ             string `db:"cpf_cnpj" json:"cpf_cnpj"`
+	CNHNumero           string `db:"cnh_numero" json:"cnh_numero"`
+	CNHCategoria        string `db:"cnh_categoria" json:"cnh_categoria"`
+	CNHValidade         string `db:"cnh_validade" json:"cnh_validade"` // AAAA-MM-DD
+	// Usados pelas regras de locação (AAAA-MM-DD)
+	DataNascimento         string `db:"data_nascimento" json:"data_nascimento"`
+	CNHPrimeiraHabilitacao string `db:"cnh_primeira_habilitacao" json:"cnh_primeira_habilitacao"`
+	// Alterados apenas por BloquearCliente/DesbloquearCliente
+	Bloqueado      bool   `db:"bloqueado" json:"bloqueado"`
+	MotivoBloqueio string `db:"motivo_bloqueio" json:"motivo_bloqueio"`
 }
 
 type Carro struct {
-	ID              int     `db:"id_carro" json:"id"`
-	Modelo          string  `db:"modelo" json:"modelo"`
-	Marca           string  `db:"marca" json:"marca"`
-	Ano             int     `db:"ano" json:"ano"`
-	Placa           string  `db:"placa" json:"placa"`
-	Cor             string  `db:"cor" json:"cor"`
-	Disponibilidade bool    `db:"disponibilidade" json:"disponibilidade"`
-	ValorDiaria     float64 `db:"valor_diaria" json:"valor_diaria"`
+	ID              int      `db:"id_carro" json:"id"`
+	Modelo          string   `db:"modelo" json:"modelo"`
+	Marca           string   `db:"marca" json:"marca"`
+	Ano             int      `db:"ano" json:"ano"`
+	Placa           string   `db:"placa" json:"placa"`
+	Cor             string   `db:"cor" json:"cor"`
+	Disponibilidade bool     `db:"disponibilidade" json:"disponibilidade"`
+	ValorDiaria     Dinheiro `db:"valor_diaria" json:"valor_diaria"`
+	Categoria       string   `db:"categoria" json:"categoria"`
+	// Estado atual, atualizado a cada vistoria de retirada e devolução
+	Quilometragem    int `db:"quilometragem" json:"quilometragem"`
+	NivelCombustivel int `db:"nivel_combustivel" json:"nivel_combustivel"` // oitavos do tanque
+	// Ficha técnica
+	TipoCombustivel  string `db:"tipo_combustivel" json:"tipo_combustivel"`
+	CapacidadeTanque int    `db:"capacidade_tanque" json:"capacidade_tanque"` // litros
+	Transmissao      string `db:"transmissao" json:"transmissao"`
+	Lugares          int    `db:"lugares" json:"lugares"`
+	// Filial onde o carro está; muda só por transferência ou devolução one-way
+	IDFilial int `db:"id_filial" json:"id_filial"`
 }
 
 type Locacao struct {
 	ID         int       `db:"id_locacao"`
 	IDCliente  int       `db:"id_cliente"`
-	IDCarro    int       `db:"id_carro"`
+	IDCarro    int       `db:"id_carro"` // 0 enquanto a locação por categoria não tem carro atribuído
 	DataInicio time.Time `db:"data_inicio"`
 	DataFim    time.Time `db:"data_fim"`
-	ValorTotal float64   `db:"valor_total"`
+	ValorTotal Dinheiro  `db:"valor_total"`
 	Status     string    `db:"status"`
+	// Caução exigida pelas regras de locação no momento da reserva
+	ValorCaucao Dinheiro `db:"valor_caucao"`
+	Categoria   string   `db:"categoria"`
+	IDPlano     int      `db:"id_plano"` // plano de proteção escolhido na reserva
+	Franquia    Dinheiro `db:"franquia"` // franquia do plano no momento da reserva
+	// Filiais de retirada e devolução (diferentes numa locação one-way)
+	IDFilialRetirada  int `db:"id_filial_retirada"`
+	IDFilialDevolucao int `db:"id_filial_devolucao"`
+}
+
+// Trajeto devolve as filiais de retirada e devolução da locação
+func (l Locacao) Trajeto() Trajeto {
+	return Trajeto{Retirada: l.IDFilialRetirada, Devolucao: l.IDFilialDevolucao}
 }
 
 // Você calcularia ValorTotal no código Go antes de salvar a Locacao
 
 type Pagamento struct {
-	ID              int       `db:"id_pagamento"`
-	IDLocacao       int       `db:"id_locacao"`
-	DataPagamento   time.Time `db:"data_pagamento"`
-	ValorPago       float64   `db:"valor_pago"`
-	FormaPagamento  string    `db:"forma_pagamento"`
-	StatusPagamento string    `db:"status_pagamento"`
+	ID                int       `db:"id_pagamento"`
+	IDLocacao         int       `db:"id_locacao"`
+	DataPagamento     time.Time `db:"data_pagamento"`
+	ValorPago         Dinheiro  `db:"valor_pago"`
+	FormaPagamento    string    `db:"forma_pagamento"`
+	StatusPagamento   string    `db:"status_pagamento"`
+	Tipo              string    `db:"tipo"`               // ver Pagamento* em caucao.go
+	ReferenciaGateway string    `db:"referencia_gateway"` // identificador da operação no gateway, quando houver
 }
 
 // --- Cliente ---
@@ -64,7 +101,7 @@ func HashPassword(password string) (string, error) {
 }
 
 func GetAllClientes(db *sql.DB) ([]Cliente, error) {
-	rows, err := db.Query("SELECT id_cliente, nome, email, telefone, endereco, documento_identidade, username FROM clientes") // Adicione 'username' aqui
+	rows, err := db.Query("SELECT " + colunasCliente + " FROM clientes")
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +110,7 @@ func GetAllClientes(db *sql.DB) ([]Cliente, error) {
 	var clientes []Cliente
 	for rows.Next() {
 		var c Cliente
-		err := rows.Scan(&c.ID, &c.Nome, &c.Email, &c.Telefone, &c.Endereco, &c.DocumentoIdentidade, &c.Username) // Adicione &c.Username
+		err := rows.Scan(camposCliente(&c)...)
 		if err != nil {
 			return nil, err
 		}
@@ -84,11 +121,21 @@ func GetAllClientes(db *sql.DB) ([]Cliente, error) {
 
 func GetClienteByID(db *sql.DB, id int) (Cliente, error) {
 	var c Cliente
-	err := db.QueryRow("SELECT id_cliente, nome, email, telefone, endereco, documento_identidade, username FROM clientes WHERE id_cliente = ?", id). // Adicione 'username' aqui
-																				Scan(&c.ID, &c.Nome, &c.Email, &c.Telefone, &c.Endereco, &c.DocumentoIdentidade, &c.Username) // Adicione &c.Username
+	err := db.QueryRow("SELECT "+colunasCliente+" FROM clientes WHERE id_cliente = ?", id).
+		Scan(camposCliente(&c)...)
 	return c, err
 }
 
+const colunasCliente = "id_cliente, nome, email, telefone, endereco, documento_identidade, username, cpf_cnpj, cnh_numero, cnh_categoria, cnh_validade, " +
+	"data_nascimento, cnh_primeira_habilitacao, bloqueado, motivo_bloqueio"
+
+// camposCliente devolve os destinos do Scan na mesma ordem de colunasCliente
+func camposCliente(c *Cliente) []any {
+	return []any{&c.ID, &c.Nome, &c.Email, &c.Telefone, &c.Endereco, &c.DocumentoIdentidade, &c.Username,
+		&c.CPFCNPJ, &c.CNHNumero, &c.CNHCategoria, &c.CNHValidade,
+		&c.DataNascimento, &c.CNHPrimeiraHabilitacao, &c.Bloqueado, &c.MotivoBloqueio}
+}
+
 // Em models.go
 func CreateCliente(db *sql.DB, c Cliente, senha string) error {
 	tx, err := db.Begin()
@@ -96,40 +143,62 @@ func CreateCliente(db *sql.DB, c Cliente, senha string) error {
 		return err
 	}
 
-	res, err := tx.Exec(`INSERT INTO clientes (nome, email, telefone, endereco, documento_identidade, username)
-		VALUES (?, ?, ?, ?, ?, ?)`,
-		c.Nome, c.Email, c.Telefone, c.Endereco, c.DocumentoIdentidade, c.Username)
-	if err != nil {
+	if err := criarClienteTx(tx, c, senha, true); err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	id, err := res.LastInsertId()
+	return tx.Commit()
+}
+
+// criarClienteTx insere o cliente e o seu usuário dentro da transação informada
+func criarClienteTx(tx *sql.Tx, c Cliente, senha string, emailVerificado bool) error {
+	senhaHash, err := HashPassword(senha)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
-	c.ID = int(id)
+	return inserirClienteTx(tx, c, senhaHash, emailVerificado)
+}
 
-	senhaHash, err := HashPassword(senha)
+// inserirClienteTx grava o cliente e o usuário com a senha já transformada em hash
+func inserirClienteTx(tx *sql.Tx, c Cliente, senhaHash string, emailVerificado bool) error {
+	res, err := tx.Exec(`INSERT INTO clientes (nome, email, telefone, endereco, documento_identidade, username,
+		cpf_cnpj, cnh_numero, cnh_categoria, cnh_validade, data_nascimento, cnh_primeira_habilitacao)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.Nome, c.Email, c.Telefone, c.Endereco, c.DocumentoIdentidade, c.Username,
+		c.CPFCNPJ, c.CNHNumero, c.CNHCategoria, c.CNHValidade, c.DataNascimento, c.CNHPrimeiraHabilitacao)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
-	_, err = tx.Exec(`INSERT INTO usuarios (usuario, senha_hash, papel) VALUES (?, ?, ?)`,
-		c.Username, senhaHash, "cliente")
+	id, err := res.LastInsertId()
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
+	c.ID = int(id)
 
-	return tx.Commit()
+	_, err = tx.Exec(`INSERT INTO usuarios (usuario, senha_hash, papel, email_verificado) VALUES (?, ?, ?, ?)`,
+		c.Username, senhaHash, "cliente", emailVerificado)
+	return err
 }
+
 func UpdateCliente(db *sql.DB, c Cliente) error {
-	_, err := db.Exec(`UPDATE clientes SET nome=?, email=?, telefone=?, endereco=?, documento_identidade=?, username=?
+	_, err := db.Exec(`UPDATE clientes SET nome=?, email=?, telefone=?, endereco=?, documento_identidade=?, username=?,
+		cpf_cnpj=?, cnh_numero=?, cnh_categoria=?, cnh_validade=?, data_nascimento=?, cnh_primeira_habilitacao=?
 		WHERE id_cliente=?`,
-		c.Nome, c.Email, c.Telefone, c.Endereco, c.DocumentoIdentidade, c.Username, c.ID)
+		c.Nome, c.Email, c.Telefone, c.Endereco, c.DocumentoIdentidade, c.Username,
+		c.CPFCNPJ, c.CNHNumero, c.CNHCategoria, c.CNHValidade, c.DataNascimento, c.CNHPrimeiraHabilitacao, c.ID)
+	return err
+}
+
+// BloquearCliente coloca o cliente na lista de bloqueio, impedindo novas locações
+func BloquearCliente(db *sql.DB, id int, motivo string) error {
+	_, err := db.Exec("UPDATE clientes SET bloqueado = TRUE, motivo_bloqueio = ? WHERE id_cliente = ?", motivo, id)
+	return err
+}
+
+func DesbloquearCliente(db *sql.DB, id int) error {
+	_, err := db.Exec("UPDATE clientes SET bloqueado = FALSE, motivo_bloqueio = '' WHERE id_cliente = ?", id)
 	return err
 }
 
@@ -141,7 +210,7 @@ func DeleteCliente(db *sql.DB, id int) error {
 // --- Carro ---
 
 func GetAllCarros(db *sql.DB) ([]Carro, error) {
-	rows, err := db.Query("SELECT id_carro, modelo, marca, ano, placa, cor, disponibilidade, valor_diaria FROM carros")
+	rows, err := db.Query("SELECT " + colunasCarro + " FROM carros")
 	if err != nil {
 		return nil, err
 	}
@@ -150,7 +219,7 @@ func GetAllCarros(db *sql.DB) ([]Carro, error) {
 	var carros []Carro
 	for rows.Next() {
 		var c Carro
-		err := rows.Scan(&c.ID, &c.Modelo, &c.Marca, &c.Ano, &c.Placa, &c.Cor, &c.Disponibilidade, &c.ValorDiaria)
+		err := rows.Scan(camposCarro(&c)...)
 		if err != nil {
 			return nil, err
 		}
@@ -161,22 +230,42 @@ func GetAllCarros(db *sql.DB) ([]Carro, error) {
 
 func GetCarroByID(db *sql.DB, id int) (Carro, error) {
 	var c Carro
-	err := db.QueryRow("SELECT id_carro, modelo, marca, ano, placa, cor, disponibilidade, valor_diaria FROM carros WHERE id_carro = ?", id).
-		Scan(&c.ID, &c.Modelo, &c.Marca, &c.Ano, &c.Placa, &c.Cor, &c.Disponibilidade, &c.ValorDiaria)
+	err := db.QueryRow("SELECT "+colunasCarro+" FROM carros WHERE id_carro = ?", id).
+		Scan(camposCarro(&c)...)
 	return c, err
 }
 
+const colunasCarro = "id_carro, modelo, marca, ano, placa, cor, disponibilidade, valor_diaria, categoria, " +
+	"quilometragem, nivel_combustivel, tipo_combustivel, capacidade_tanque, transmissao, lugares, COALESCE(id_filial, 0)"
+
+// camposCarro devolve os destinos do Scan na mesma ordem de colunasCarro
+func camposCarro(c *Carro) []any {
+	return []any{&c.ID, &c.Modelo, &c.Marca, &c.Ano, &c.Placa, &c.Cor, &c.Disponibilidade, &c.ValorDiaria, &c.Categoria,
+		&c.Quilometragem, &c.NivelCombustivel, &c.TipoCombustivel, &c.CapacidadeTanque, &c.Transmissao, &c.Lugares, &c.IDFilial}
+}
+
 func CreateCarro(db *sql.DB, c Carro) error {
-	_, err := db.Exec(`INSERT INTO carros (modelo, marca, ano, placa, cor, disponibilidade, valor_diaria)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		c.Modelo, c.Marca, c.Ano, c.Placa, c.Cor, c.Disponibilidade, c.ValorDiaria)
+	_, err := db.Exec(inserirCarro, valoresCarro(c)...)
 	return err
 }
 
+const inserirCarro = `INSERT INTO carros (modelo, marca, ano, placa, cor, disponibilidade, valor_diaria, categoria,
+		quilometragem, nivel_combustivel, tipo_combustivel, capacidade_tanque, transmissao, lugares, id_filial)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// valoresCarro devolve os argumentos de inserirCarro
+func valoresCarro(c Carro) []any {
+	return []any{c.Modelo, c.Marca, c.Ano, c.Placa, c.Cor, c.Disponibilidade, c.ValorDiaria, c.Categoria,
+		c.Quilometragem, c.NivelCombustivel, c.TipoCombustivel, c.CapacidadeTanque, c.Transmissao, c.Lugares, nuloSeZero(c.IDFilial)}
+}
+
+// UpdateCarro não altera a filial: use TransferirCarro, que registra a movimentação
 func UpdateCarro(db *sql.DB, c Carro) error {
-	_, err := db.Exec(`UPDATE carros SET modelo=?, marca=?, ano=?, placa=?, cor=?, disponibilidade=?, valor_diaria=?
+	_, err := db.Exec(`UPDATE carros SET modelo=?, marca=?, ano=?, placa=?, cor=?, disponibilidade=?, valor_diaria=?, categoria=?,
+		quilometragem=?, nivel_combustivel=?, tipo_combustivel=?, capacidade_tanque=?, transmissao=?, lugares=?
 		WHERE id_carro=?`,
-		c.Modelo, c.Marca, c.Ano, c.Placa, c.Cor, c.Disponibilidade, c.ValorDiaria, c.ID)
+		c.Modelo, c.Marca, c.Ano, c.Placa, c.Cor, c.Disponibilidade, c.ValorDiaria, c.Categoria,
+		c.Quilometragem, c.NivelCombustivel, c.TipoCombustivel, c.CapacidadeTanque, c.Transmissao, c.Lugares, c.ID)
 	return err
 }
 
@@ -188,7 +277,7 @@ func DeleteCarro(db *sql.DB, id int) error {
 // --- Locacao ---
 
 func GetAllLocacoes(db *sql.DB) ([]Locacao, error) {
-	rows, err := db.Query("SELECT id_locacao, id_cliente, id_carro, data_inicio, data_fim, valor_total, status FROM locacoes")
+	rows, err := db.Query("SELECT " + colunasLocacao + " FROM locacoes")
 	if err != nil {
 		return nil, err
 	}
@@ -197,7 +286,7 @@ func GetAllLocacoes(db *sql.DB) ([]Locacao, error) {
 	var locacoes []Locacao
 	for rows.Next() {
 		var l Locacao
-		err := rows.Scan(&l.ID, &l.IDCliente, &l.IDCarro, &l.DataInicio, &l.DataFim, &l.ValorTotal, &l.Status)
+		err := rows.Scan(camposLocacao(&l)...)
 		if err != nil {
 			return nil, err
 		}
@@ -208,25 +297,70 @@ func GetAllLocacoes(db *sql.DB) ([]Locacao, error) {
 
 func GetLocacaoByID(db *sql.DB, id int) (Locacao, error) {
 	var l Locacao
-	err := db.QueryRow("SELECT id_locacao, id_cliente, id_carro, data_inicio, data_fim, valor_total, status FROM locacoes WHERE id_locacao = ?", id).
-		Scan(&l.ID, &l.IDCliente, &l.IDCarro, &l.DataInicio, &l.DataFim, &l.ValorTotal, &l.Status)
+	err := db.QueryRow("SELECT "+colunasLocacao+" FROM locacoes WHERE id_locacao = ?", id).
+		Scan(camposLocacao(&l)...)
 	return l, err
 }
 
+const colunasLocacao = "id_locacao, id_cliente, COALESCE(id_carro, 0), data_inicio, data_fim, valor_total, status, valor_caucao, categoria, COALESCE(id_plano, 0), franquia, " +
+	"COALESCE(id_filial_retirada, 0), COALESCE(id_filial_devolucao, 0)"
+
+// camposLocacao devolve os destinos do Scan na mesma ordem de colunasLocacao
+func camposLocacao(l *Locacao) []any {
+	return []any{&l.ID, &l.IDCliente, &l.IDCarro, &l.DataInicio, &l.DataFim, &l.ValorTotal, &l.Status, &l.ValorCaucao, &l.Categoria, &l.IDPlano, &l.Franquia,
+		&l.IDFilialRetirada, &l.IDFilialDevolucao}
+}
+
 func CreateLocacao(db *sql.DB, l Locacao) error {
-	_, err := db.Exec(`INSERT INTO locacoes (id_cliente, id_carro, data_inicio, data_fim, valor_total, status)
-		VALUES (?, ?, ?, ?, ?, ?)`,
-		l.IDCliente, l.IDCarro, l.DataInicio, l.DataFim, l.ValorTotal, l.Status)
+	_, err := db.Exec(`INSERT INTO locacoes (id_cliente, id_carro, data_inicio, data_fim, valor_total, status, valor_caucao, categoria, id_plano, franquia,
+		id_filial_retirada, id_filial_devolucao)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		l.IDCliente, nuloSeZero(l.IDCarro), l.DataInicio, l.DataFim, l.ValorTotal, l.Status, l.ValorCaucao, l.Categoria,
+		nuloSeZero(l.IDPlano), l.Franquia, nuloSeZero(l.IDFilialRetirada), nuloSeZero(l.IDFilialDevolucao))
 	return err
 }
 
 func UpdateLocacao(db *sql.DB, l Locacao) error {
-	_, err := db.Exec(`UPDATE locacoes SET id_cliente=?, id_carro=?, data_inicio=?, data_fim=?, valor_total=?, status=?
+	_, err := db.Exec(`UPDATE locacoes SET id_cliente=?, id_carro=?, data_inicio=?, data_fim=?, valor_total=?, status=?, valor_caucao=?, categoria=?,
+		id_plano=?, franquia=?, id_filial_retirada=?, id_filial_devolucao=?
 		WHERE id_locacao=?`,
-		l.IDCliente, l.IDCarro, l.DataInicio, l.DataFim, l.ValorTotal, l.Status, l.ID)
+		l.IDCliente, nuloSeZero(l.IDCarro), l.DataInicio, l.DataFim, l.ValorTotal, l.Status, l.ValorCaucao, l.Categoria,
+		nuloSeZero(l.IDPlano), l.Franquia, nuloSeZero(l.IDFilialRetirada), nuloSeZero(l.IDFilialDevolucao), l.ID)
 	return err
 }
 
+// AtribuirCarro grava o carro escolhido na retirada de uma locação feita por categoria.
+// Chame com a trava das reservas, logo depois de EscolherCarro.
+func AtribuirCarro(db *sql.DB, idLocacao, idCarro int) error {
+	_, err := db.Exec("UPDATE locacoes SET id_carro = ? WHERE id_locacao = ?", idCarro, idLocacao)
+	return err
+}
+
+var ErrLocacaoNaoCancelavel = errors.New("só é possível cancelar locações ainda não retiradas")
+
+// CancelarLocacao cancela a reserva antes da retirada, liberando o período para outros clientes.
+// Valores já pagos não são estornados aqui.
+func CancelarLocacao(db *sql.DB, id int) error {
+	res, err := db.Exec("UPDATE locacoes SET status = 'cancelada' WHERE id_locacao = ? AND status IN ('pendente', 'pago')", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrLocacaoNaoCancelavel
+	}
+	return nil
+}
+
+// nuloSeZero grava NULL nas chaves estrangeiras opcionais em vez de 0
+func nuloSeZero(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
 func DeleteLocacao(db *sql.DB, id int) error {
 	_, err := db.Exec("DELETE FROM locacoes WHERE id_locacao = ?", id)
 	return err
@@ -235,7 +369,7 @@ func DeleteLocacao(db *sql.DB, id int) error {
 // --- Pagamento ---
 
 func GetAllPagamentos(db *sql.DB) ([]Pagamento, error) {
-	rows, err := db.Query("SELECT id_pagamento, id_locacao, data_pagamento, valor_pago, forma_pagamento, status_pagamento FROM pagamentos")
+	rows, err := db.Query("SELECT " + colunasPagamento + " FROM pagamentos")
 	if err != nil {
 		return nil, err
 	}
@@ -244,7 +378,7 @@ func GetAllPagamentos(db *sql.DB) ([]Pagamento, error) {
 	var pagamentos []Pagamento
 	for rows.Next() {
 		var p Pagamento
-		err := rows.Scan(&p.ID, &p.IDLocacao, &p.DataPagamento, &p.ValorPago, &p.FormaPagamento, &p.StatusPagamento)
+		err := rows.Scan(camposPagamento(&p)...)
 		if err != nil {
 			return nil, err
 		}
@@ -255,22 +389,51 @@ func GetAllPagamentos(db *sql.DB) ([]Pagamento, error) {
 
 func GetPagamentoByID(db *sql.DB, id int) (Pagamento, error) {
 	var p Pagamento
-	err := db.QueryRow("SELECT id_pagamento, id_locacao, data_pagamento, valor_pago, forma_pagamento, status_pagamento FROM pagamentos WHERE id_pagamento = ?", id).
-		Scan(&p.ID, &p.IDLocacao, &p.DataPagamento, &p.ValorPago, &p.FormaPagamento, &p.StatusPagamento)
+	err := db.QueryRow("SELECT "+colunasPagamento+" FROM pagamentos WHERE id_pagamento = ?", id).
+		Scan(camposPagamento(&p)...)
 	return p, err
 }
 
-func CreatePagamento(db *sql.DB, p Pagamento) error {
-	_, err := db.Exec(`INSERT INTO pagamentos (id_locacao, data_pagamento, valor_pago, forma_pagamento, status_pagamento)
-		VALUES (?, ?, ?, ?, ?)`,
-		p.IDLocacao, p.DataPagamento, p.ValorPago, p.FormaPagamento, p.StatusPagamento)
-	return err
+const colunasPagamento = "id_pagamento, id_locacao, data_pagamento, valor_pago, forma_pagamento, status_pagamento, tipo, referencia_gateway"
+
+// camposPagamento devolve os destinos do Scan na mesma ordem de colunasPagamento
+func camposPagamento(p *Pagamento) []any {
+	return []any{&p.ID, &p.IDLocacao, &p.DataPagamento, &p.ValorPago, &p.FormaPagamento, &p.StatusPagamento, &p.Tipo, &p.ReferenciaGateway}
+}
+
+// CreatePagamento grava o pagamento e, se for um recebimento, emite o recibo na mesma transação
+func CreatePagamento(db *sql.DB, p Pagamento) (int, error) {
+	if p.Tipo == "" {
+		p.Tipo = PagamentoLocacao
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(inserirPagamento,
+		p.IDLocacao, p.DataPagamento, p.ValorPago, p.FormaPagamento, p.StatusPagamento, p.Tipo, p.ReferenciaGateway)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := emitirReciboTx(tx, int(id), p.Tipo); err != nil {
+		return 0, err
+	}
+	return int(id), tx.Commit()
 }
 
+const inserirPagamento = `INSERT INTO pagamentos (id_locacao, data_pagamento, valor_pago, forma_pagamento, status_pagamento, tipo, referencia_gateway)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
 func UpdatePagamento(db *sql.DB, p Pagamento) error {
-	_, err := db.Exec(`UPDATE pagamentos SET id_locacao=?, data_pagamento=?, valor_pago=?, forma_pagamento=?, status_pagamento=?
+	_, err := db.Exec(`UPDATE pagamentos SET id_locacao=?, data_pagamento=?, valor_pago=?, forma_pagamento=?, status_pagamento=?, tipo=?, referencia_gateway=?
 		WHERE id_pagamento=?`,
-		p.IDLocacao, p.DataPagamento, p.ValorPago, p.FormaPagamento, p.StatusPagamento, p.ID)
+		p.IDLocacao, p.DataPagamento, p.ValorPago, p.FormaPagamento, p.StatusPagamento, p.Tipo, p.ReferenciaGateway, p.ID)
 	return err
 }
 
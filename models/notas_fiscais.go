@@ -0,0 +1,260 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Situações da nota fiscal de serviço (NFS-e) de uma locação
+const (
+	NotaPendente   = "pendente"   // RPS gerado e assinado, ainda não aceito pela prefeitura
+	NotaAutorizada = "autorizada" // a prefeitura converteu o RPS em NFS-e
+	NotaRejeitada  = "rejeitada"  // recusada; corrigir os dados e transmitir de novo
+)
+
+var (
+	ErrSemAliquotaISS       = errors.New("município sem alíquota de ISS cadastrada")
+	ErrLocacaoNaoFinalizada = errors.New("só locações finalizadas geram nota fiscal")
+)
+
+// AliquotaISS é a tributação do serviço em um município (código IBGE de 7 dígitos)
+type AliquotaISS struct {
+	CodigoMunicipio  string  `db:"codigo_municipio" json:"codigo_municipio"`
+	Cidade           string  `db:"cidade" json:"cidade"`
+	UF               string  `db:"uf" json:"uf"`
+	Aliquota         float64 `db:"aliquota" json:"aliquota"` // percentual sobre o valor do serviço
+	ItemListaServico string  `db:"item_lista_servico" json:"item_lista_servico"`
+	// Código de tributação próprio do município, quando a prefeitura exige
+	CodigoTributacao string `db:"codigo_tributacao" json:"codigo_tributacao"`
+}
+
+// NotaFiscal é o RPS (recibo provisório de serviços) de uma locação finalizada e, depois de
+// autorizado, os dados da NFS-e. O número do RPS é sequencial dentro da série.
+type NotaFiscal struct {
+	NumeroRPS         int       `db:"numero_rps" json:"numero_rps"`
+	SerieRPS          string    `db:"serie_rps" json:"serie_rps"`
+	IDLocacao         int       `db:"id_locacao" json:"id_locacao"`
+	DataEmissao       time.Time `db:"data_emissao" json:"data_emissao"`
+	ValorServicos     Dinheiro  `db:"valor_servicos" json:"valor_servicos"`
+	Aliquota          float64   `db:"aliquota" json:"aliquota"`
+	ValorISS          Dinheiro  `db:"valor_iss" json:"valor_iss"`
+	CodigoMunicipio   string    `db:"codigo_municipio" json:"codigo_municipio"`
+	Status            string    `db:"status" json:"status"`
+	NumeroNFSe        string    `db:"numero_nfse" json:"numero_nfse,omitempty"`
+	CodigoVerificacao string    `db:"codigo_verificacao" json:"codigo_verificacao,omitempty"`
+	Protocolo         string    `db:"protocolo" json:"protocolo,omitempty"`
+	Mensagem          string    `db:"mensagem" json:"mensagem,omitempty"` // motivo da rejeição
+	Arquivo           string    `db:"arquivo" json:"-"`                   // XML assinado no armazenamento privado
+}
+
+// PrestadorNFSe são os dados da locadora na nota (tabela configuracoes)
+type PrestadorNFSe struct {
+	CNPJ               string
+	InscricaoMunicipal string
+	RazaoSocial        string
+	SerieRPS           string
+}
+
+func CarregarPrestadorNFSe(db *sql.DB) (PrestadorNFSe, error) {
+	valores, err := GetConfiguracoes(db)
+	if err != nil {
+		return PrestadorNFSe{}, err
+	}
+	c := configuracao{valores: valores}
+	return PrestadorNFSe{
+		CNPJ:               c.texto(ConfigNFSeCNPJ),
+		InscricaoMunicipal: c.texto(ConfigNFSeInscricaoMunicipal),
+		RazaoSocial:        c.texto(ConfigNFSeRazaoSocial),
+		SerieRPS:           c.texto(ConfigNFSeSerieRPS),
+	}, c.err
+}
+
+// Completo informa se os dados obrigatórios do prestador foram configurados
+func (p PrestadorNFSe) Completo() bool {
+	return ValidarCNPJ(p.CNPJ) && p.InscricaoMunicipal != "" && p.RazaoSocial != ""
+}
+
+// --- Alíquotas de ISS ---
+
+const colunasAliquota = "codigo_municipio, cidade, uf, aliquota, item_lista_servico, codigo_tributacao"
+
+func camposAliquota(a *AliquotaISS) []any {
+	return []any{&a.CodigoMunicipio, &a.Cidade, &a.UF, &a.Aliquota, &a.ItemListaServico, &a.CodigoTributacao}
+}
+
+func GetAliquotasISS(db *sql.DB) ([]AliquotaISS, error) {
+	rows, err := db.Query("SELECT " + colunasAliquota + " FROM aliquotas_iss ORDER BY uf, cidade")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliquotas := []AliquotaISS{}
+	for rows.Next() {
+		var a AliquotaISS
+		if err := rows.Scan(camposAliquota(&a)...); err != nil {
+			return nil, err
+		}
+		aliquotas = append(aliquotas, a)
+	}
+	return aliquotas, rows.Err()
+}
+
+// AliquotaDoMunicipio busca a alíquota pela cidade e UF da filial (sem diferenciar maiúsculas)
+func AliquotaDoMunicipio(db *sql.DB, cidade, uf string) (AliquotaISS, error) {
+	var a AliquotaISS
+	err := db.QueryRow("SELECT "+colunasAliquota+" FROM aliquotas_iss WHERE lower(cidade) = lower(?) AND upper(uf) = upper(?)",
+		strings.TrimSpace(cidade), strings.TrimSpace(uf)).Scan(camposAliquota(&a)...)
+	if err == sql.ErrNoRows {
+		return a, ErrSemAliquotaISS
+	}
+	return a, err
+}
+
+// SalvarAliquotaISS cria ou atualiza a alíquota do município
+func SalvarAliquotaISS(db *sql.DB, a AliquotaISS) error {
+	_, err := db.Exec(`INSERT INTO aliquotas_iss (`+colunasAliquota+`) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (codigo_municipio) DO UPDATE SET cidade = excluded.cidade, uf = excluded.uf, aliquota = excluded.aliquota,
+			item_lista_servico = excluded.item_lista_servico, codigo_tributacao = excluded.codigo_tributacao`,
+		a.CodigoMunicipio, a.Cidade, strings.ToUpper(a.UF), a.Aliquota, a.ItemListaServico, a.CodigoTributacao)
+	return err
+}
+
+// ValidarAliquotaISS confere os campos antes de gravar
+func ValidarAliquotaISS(a AliquotaISS) error {
+	switch {
+	case len(SomenteDigitos(a.CodigoMunicipio)) != 7 || SomenteDigitos(a.CodigoMunicipio) != a.CodigoMunicipio:
+		return ErroValidacao{Campo: "codigo_municipio", Mensagem: "Informe o código IBGE do município (7 dígitos)"}
+	case strings.TrimSpace(a.Cidade) == "" || len(strings.TrimSpace(a.UF)) != 2:
+		return ErroValidacao{Campo: "cidade", Mensagem: "Informe cidade e UF"}
+	case a.Aliquota < 2 || a.Aliquota > 5:
+		// LC 116/2003 e LC 157/2016: o ISS fica entre 2% e 5%
+		return ErroValidacao{Campo: "aliquota", Mensagem: "A alíquota do ISS deve estar entre 2% e 5%"}
+	case strings.TrimSpace(a.ItemListaServico) == "":
+		return ErroValidacao{Campo: "item_lista_servico", Mensagem: "Informe o item da lista de serviços"}
+	}
+	return nil
+}
+
+// --- Notas fiscais ---
+
+const colunasNota = "numero_rps, serie_rps, id_locacao, data_emissao, valor_servicos, aliquota, valor_iss, codigo_municipio, status, " +
+	"numero_nfse, codigo_verificacao, protocolo, mensagem, arquivo"
+
+func camposNota(n *NotaFiscal) []any {
+	return []any{&n.NumeroRPS, &n.SerieRPS, &n.IDLocacao, &n.DataEmissao, &n.ValorServicos, &n.Aliquota, &n.ValorISS, &n.CodigoMunicipio, &n.Status,
+		&n.NumeroNFSe, &n.CodigoVerificacao, &n.Protocolo, &n.Mensagem, &n.Arquivo}
+}
+
+// GetNotasFiscais lista as notas, opcionalmente de um status, das mais recentes para as mais antigas
+func GetNotasFiscais(db *sql.DB, status string) ([]NotaFiscal, error) {
+	rows, err := db.Query("SELECT "+colunasNota+" FROM notas_fiscais WHERE ? = '' OR status = ? ORDER BY numero_rps DESC", status, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notas := []NotaFiscal{}
+	for rows.Next() {
+		var n NotaFiscal
+		if err := rows.Scan(camposNota(&n)...); err != nil {
+			return nil, err
+		}
+		notas = append(notas, n)
+	}
+	return notas, rows.Err()
+}
+
+func GetNotaFiscal(db *sql.DB, numeroRPS int) (NotaFiscal, error) {
+	var n NotaFiscal
+	err := db.QueryRow("SELECT "+colunasNota+" FROM notas_fiscais WHERE numero_rps = ?", numeroRPS).Scan(camposNota(&n)...)
+	return n, err
+}
+
+func GetNotaFiscalDaLocacao(db *sql.DB, idLocacao int) (NotaFiscal, error) {
+	var n NotaFiscal
+	err := db.QueryRow("SELECT "+colunasNota+" FROM notas_fiscais WHERE id_locacao = ?", idLocacao).Scan(camposNota(&n)...)
+	return n, err
+}
+
+// NovaNotaFiscal calcula o ISS da locação finalizada pelo município da filial de retirada
+// (onde o serviço é prestado) e reserva o próximo número de RPS. Se a locação já tem RPS,
+// ele é reaproveitado com os valores atualizados (a numeração não pode ter buracos nem
+// duplicidade); uma nota já autorizada volta sem alterações.
+func NovaNotaFiscal(db *sql.DB, l Locacao, serie string) (NotaFiscal, AliquotaISS, error) {
+	if l.Status != "finalizada" {
+		return NotaFiscal{}, AliquotaISS{}, ErrLocacaoNaoFinalizada
+	}
+	filial, err := GetFilialByID(db, l.IDFilialRetirada)
+	if err != nil {
+		return NotaFiscal{}, AliquotaISS{}, err
+	}
+	aliquota, err := AliquotaDoMunicipio(db, filial.Cidade, filial.UF)
+	if err != nil {
+		return NotaFiscal{}, aliquota, fmt.Errorf("%w: %s/%s", err, filial.Cidade, filial.UF)
+	}
+
+	n, err := GetNotaFiscalDaLocacao(db, l.ID)
+	if err == nil && n.Status == NotaAutorizada {
+		return n, aliquota, nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return n, aliquota, err
+	}
+	existente := err == nil
+
+	if !existente {
+		n.SerieRPS = serie
+	}
+	n.IDLocacao, n.DataEmissao = l.ID, time.Now().UTC()
+	n.ValorServicos, n.Aliquota, n.ValorISS = l.ValorTotal, aliquota.Aliquota, l.ValorTotal.Percentual(aliquota.Aliquota)
+	n.CodigoMunicipio, n.Status = aliquota.CodigoMunicipio, NotaPendente
+	if existente {
+		_, err := db.Exec(`UPDATE notas_fiscais SET data_emissao = ?, valor_servicos = ?, aliquota = ?, valor_iss = ?, codigo_municipio = ?, status = ?
+			WHERE numero_rps = ?`,
+			n.DataEmissao, n.ValorServicos, n.Aliquota, n.ValorISS, n.CodigoMunicipio, n.Status, n.NumeroRPS)
+		return n, aliquota, err
+	}
+	res, err := db.Exec(`INSERT INTO notas_fiscais (serie_rps, id_locacao, data_emissao, valor_servicos, aliquota, valor_iss, codigo_municipio, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		n.SerieRPS, n.IDLocacao, n.DataEmissao, n.ValorServicos, n.Aliquota, n.ValorISS, n.CodigoMunicipio, n.Status)
+	if err != nil {
+		return n, aliquota, err
+	}
+	id, err := res.LastInsertId()
+	n.NumeroRPS = int(id)
+	return n, aliquota, err
+}
+
+// AtualizarNotaFiscal grava o arquivo assinado e o retorno da prefeitura
+func AtualizarNotaFiscal(db *sql.DB, n NotaFiscal) error {
+	_, err := db.Exec(`UPDATE notas_fiscais SET status = ?, numero_nfse = ?, codigo_verificacao = ?, protocolo = ?, mensagem = ?, arquivo = ?
+		WHERE numero_rps = ?`,
+		n.Status, n.NumeroNFSe, n.CodigoVerificacao, n.Protocolo, n.Mensagem, n.Arquivo, n.NumeroRPS)
+	return err
+}
+
+// LocacoesSemNotaAutorizada lista as locações finalizadas ainda sem NFS-e autorizada
+func LocacoesSemNotaAutorizada(db *sql.DB) ([]int, error) {
+	rows, err := db.Query(`SELECT l.id_locacao FROM locacoes l
+		LEFT JOIN notas_fiscais n ON n.id_locacao = l.id_locacao
+		WHERE l.status = 'finalizada' AND (n.numero_rps IS NULL OR n.status <> ?)
+		ORDER BY l.id_locacao`, NotaAutorizada)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
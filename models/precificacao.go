@@ -0,0 +1,420 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TarifaSazonal multiplica a diária nos dias do período (alta temporada, eventos etc.)
+type TarifaSazonal struct {
+	ID            int       `db:"id_tarifa" json:"id"`
+	Nome          string    `db:"nome" json:"nome"`
+	Categoria     string    `db:"categoria" json:"categoria"`
+	DataInicio    time.Time `db:"data_inicio" json:"data_inicio"`
+	DataFim       time.Time `db:"data_fim" json:"data_fim"`
+	Multiplicador float64   `db:"multiplicador" json:"multiplicador"`
+}
+
+type Feriado struct {
+	Data      string `db:"data" json:"data"` // AAAA-MM-DD
+	Descricao string `db:"descricao" json:"descricao"`
+}
+
+// DescontoDuracao é uma faixa de desconto para locações longas (semanal, mensal...)
+type DescontoDuracao struct {
+	ID          int     `db:"id_desconto" json:"id"`
+	Categoria   string  `db:"categoria" json:"categoria"`
+	DiasMinimos int     `db:"dias_minimos" json:"dias_minimos"`
+	Percentual  float64 `db:"percentual" json:"percentual"`
+}
+
+// Tipos de item da cotação (gravados também em locacao_itens)
+const (
+	ItemDiaria      = "diaria"
+	ItemDesconto    = "desconto"
+	ItemSobretaxa   = "sobretaxa"
+	ItemExtra       = "extra"
+	ItemProtecao    = "protecao"
+	ItemTaxaRetorno = "taxa_retorno" // devolução em outra filial
+	// Cobranças lançadas na devolução
+	ItemAtraso      = "atraso"
+	ItemCombustivel = "combustivel"
+	ItemKmExcedente = "km_excedente"
+	ItemAvaria      = "avaria"
+	// Diferença cobrada pelo período acrescentado numa prorrogação
+	ItemProrrogacao = "prorrogacao"
+	// Crédito (valor negativo) pelas diárias não usadas na devolução antecipada
+	ItemDevolucaoAntecipada = "devolucao_antecipada"
+)
+
+// ItemCotacao é uma linha da cotação; descontos aparecem com valor negativo
+type ItemCotacao struct {
+	Tipo          string   `json:"tipo"`
+	IDExtra       int      `json:"id_extra,omitempty"`
+	Descricao     string   `json:"descricao"`
+	Quantidade    int      `json:"quantidade"`
+	ValorUnitario Dinheiro `json:"valor_unitario"`
+	Valor         Dinheiro `json:"valor"`
+}
+
+type Cotacao struct {
+	IDCarro    int            `json:"id_carro,omitempty"`
+	Categoria  string         `json:"categoria,omitempty"`
+	DataInicio string         `json:"data_inicio"`
+	DataFim    string         `json:"data_fim"`
+	Dias       int            `json:"dias"`
+	Plano      *PlanoProtecao `json:"plano,omitempty"`
+	Itens      []ItemCotacao  `json:"itens"`
+	Total      Dinheiro       `json:"total"`
+}
+
+// TabelaPrecos reúne tudo o que o motor precisa para cotar uma categoria
+type TabelaPrecos struct {
+	DiariaBase             Dinheiro
+	MultiplicadorFimSemana float64
+	MultiplicadorFeriado   float64
+	Temporadas             []TarifaSazonal
+	Feriados               map[string]string
+	Descontos              []DescontoDuracao
+}
+
+// CarregarTabelaPrecos lê do banco a configuração de preços da categoria.
+// Temporadas e descontos específicos da categoria substituem os gerais.
+func CarregarTabelaPrecos(db *sql.DB, categoria string, diariaBase Dinheiro) (TabelaPrecos, error) {
+	t := TabelaPrecos{DiariaBase: diariaBase, MultiplicadorFimSemana: 1, MultiplicadorFeriado: 1}
+
+	if categoria != "" {
+		cat, err := GetCategoriaByCodigo(db, categoria)
+		if err != nil {
+			return t, err
+		}
+		t.MultiplicadorFimSemana = cat.MultiplicadorFimSemana
+		t.MultiplicadorFeriado = cat.MultiplicadorFeriado
+	}
+
+	temporadas, err := GetAllTarifasSazonais(db)
+	if err != nil {
+		return t, err
+	}
+	t.Temporadas = filtrarPorCategoria(temporadas, categoria, func(ts TarifaSazonal) string { return ts.Categoria })
+
+	descontos, err := GetAllDescontosDuracao(db)
+	if err != nil {
+		return t, err
+	}
+	t.Descontos = filtrarPorCategoria(descontos, categoria, func(d DescontoDuracao) string { return d.Categoria })
+
+	feriados, err := GetAllFeriados(db)
+	if err != nil {
+		return t, err
+	}
+	t.Feriados = make(map[string]string, len(feriados))
+	for _, f := range feriados {
+		t.Feriados[f.Data] = f.Descricao
+	}
+
+	return t, nil
+}
+
+// filtrarPorCategoria devolve os itens da categoria se houver algum; senão, os gerais
+func filtrarPorCategoria[T any](itens []T, categoria string, cat func(T) string) []T {
+	var especificos, gerais []T
+	for _, it := range itens {
+		switch cat(it) {
+		case "":
+			gerais = append(gerais, it)
+		case categoria:
+			especificos = append(especificos, it)
+		}
+	}
+	if categoria != "" && len(especificos) > 0 {
+		return especificos
+	}
+	return gerais
+}
+
+// Cotar calcula as diárias da locação, uma a uma: cada período de 24 horas a partir da
+// retirada usa a diária base multiplicada pela temporada vigente e por feriado ou fim de
+// semana do dia (local) em que começa; no fim aplica a faixa de desconto por duração.
+func (t TabelaPrecos) Cotar(inicio, fim time.Time, diarias int) Cotacao {
+	c := Cotacao{DataInicio: inicio.In(Fuso).Format("2006-01-02T15:04"), DataFim: fim.In(Fuso).Format("2006-01-02T15:04")}
+
+	indice := map[string]int{}
+	adicionar := func(descricao string, unitario Dinheiro) {
+		chave := fmt.Sprintf("%s|%d", descricao, unitario)
+		if i, ok := indice[chave]; ok {
+			c.Itens[i].Quantidade++
+			c.Itens[i].Valor += unitario
+			return
+		}
+		indice[chave] = len(c.Itens)
+		c.Itens = append(c.Itens, ItemCotacao{Tipo: ItemDiaria, Descricao: descricao, Quantidade: 1, ValorUnitario: unitario, Valor: unitario})
+	}
+
+	for ; c.Dias < diarias; c.Dias++ {
+		dia := diaCivil(inicio.Add(time.Duration(c.Dias) * Diaria))
+
+		descricao := "Diária"
+		fator := 1.0
+		if feriado, ok := t.Feriados[dia.Format("2006-01-02")]; ok {
+			descricao += " feriado (" + feriado + ")"
+			fator *= t.MultiplicadorFeriado
+		} else if dia.Weekday() == time.Saturday || dia.Weekday() == time.Sunday {
+			descricao += " fim de semana"
+			fator *= t.MultiplicadorFimSemana
+		}
+		if temporada, ok := t.temporada(dia); ok {
+			descricao += " - " + temporada.Nome
+			fator *= temporada.Multiplicador
+		}
+		adicionar(descricao, t.DiariaBase.Multiplicar(fator))
+	}
+
+	var subtotal Dinheiro
+	for _, it := range c.Itens {
+		subtotal += it.Valor
+	}
+
+	if desconto, ok := t.desconto(c.Dias); ok {
+		valor := -subtotal.Percentual(desconto.Percentual)
+		c.Itens = append(c.Itens, ItemCotacao{
+			Tipo:          ItemDesconto,
+			Descricao:     fmt.Sprintf("Desconto locação longa (%d+ dias, %g%%)", desconto.DiasMinimos, desconto.Percentual),
+			Quantidade:    1,
+			ValorUnitario: valor,
+			Valor:         valor,
+		})
+	}
+
+	c.somarTotal()
+	return c
+}
+
+func (c *Cotacao) somarTotal() {
+	c.Total = 0
+	for _, it := range c.Itens {
+		c.Total += it.Valor
+	}
+}
+
+// temporada retorna a tarifa sazonal de maior multiplicador vigente no dia
+func (t TabelaPrecos) temporada(dia time.Time) (TarifaSazonal, bool) {
+	var escolhida TarifaSazonal
+	achou := false
+	for _, ts := range t.Temporadas {
+		if dia.Before(ts.DataInicio) || dia.After(ts.DataFim) {
+			continue
+		}
+		if !achou || ts.Multiplicador > escolhida.Multiplicador {
+			escolhida, achou = ts, true
+		}
+	}
+	return escolhida, achou
+}
+
+// desconto retorna a maior faixa atingida pela quantidade de dias
+func (t TabelaPrecos) desconto(dias int) (DescontoDuracao, bool) {
+	var escolhido DescontoDuracao
+	achou := false
+	for _, d := range t.Descontos {
+		if dias >= d.DiasMinimos && (!achou || d.DiasMinimos > escolhido.DiasMinimos) {
+			escolhido, achou = d, true
+		}
+	}
+	return escolhido, achou
+}
+
+// PedidoCotacao descreve o que o cliente quer alugar
+type PedidoCotacao struct {
+	IDCarro         int
+	Categoria       string
+	Inicio, Fim     time.Time
+	SobretaxaDiaria Dinheiro
+	Extras          []ExtraSolicitado
+	IDPlano         int // 0 = plano padrão (o ativo mais barato)
+	Trajeto         Trajeto
+}
+
+// DiariaBase resolve a diária de referência: a do carro, se tiver, senão a da categoria.
+// Devolve também a categoria efetiva (a do carro, quando ele é informado).
+func DiariaBase(db *sql.DB, idCarro int, categoria string) (string, Dinheiro, error) {
+	var diaria Dinheiro
+	if idCarro != 0 {
+		carro, err := GetCarroByID(db, idCarro)
+		if err != nil {
+			return "", 0, err
+		}
+		categoria = carro.Categoria
+		diaria = carro.ValorDiaria
+	}
+	if diaria == 0 && categoria != "" {
+		cat, err := GetCategoriaByCodigo(db, categoria)
+		if err != nil {
+			return "", 0, err
+		}
+		diaria = cat.ValorDiariaBase
+	}
+	return categoria, diaria, nil
+}
+
+// CotarLocacao resolve o preço base (diária do carro ou da categoria) e calcula a cotação,
+// incluindo sobretaxas e extras. É usada tanto pelo GET /cotacao quanto na gravação do
+// ValorTotal e dos itens da locação.
+func CotarLocacao(db *sql.DB, p PedidoCotacao) (Cotacao, error) {
+	categoria, diariaBase, err := DiariaBase(db, p.IDCarro, p.Categoria)
+	if err != nil {
+		return Cotacao{}, err
+	}
+
+	tabela, err := CarregarTabelaPrecos(db, categoria, diariaBase)
+	if err != nil {
+		return Cotacao{}, err
+	}
+
+	tolerancia, err := ConfigInt(db, ConfigToleranciaAtrasoMin)
+	if err != nil {
+		return Cotacao{}, err
+	}
+
+	c := tabela.Cotar(p.Inicio, p.Fim, DiariasCobradas(p.Inicio, p.Fim, time.Duration(tolerancia)*time.Minute))
+	c.IDCarro = p.IDCarro
+	c.Categoria = categoria
+
+	if p.SobretaxaDiaria > 0 {
+		c.Itens = append(c.Itens, ItemCotacao{
+			Tipo:          ItemSobretaxa,
+			Descricao:     "Sobretaxa condutor jovem",
+			Quantidade:    c.Dias,
+			ValorUnitario: p.SobretaxaDiaria,
+			Valor:         p.SobretaxaDiaria * Dinheiro(c.Dias),
+		})
+	}
+
+	plano, err := GetPlanoPadrao(db)
+	if p.IDPlano != 0 {
+		plano, err = GetPlanoByID(db, p.IDPlano)
+	}
+	switch {
+	case err == nil:
+		c.Plano = &plano
+		c.Itens = append(c.Itens, plano.ItemCotacao(c.Dias))
+	case err != sql.ErrNoRows:
+		return Cotacao{}, err
+	}
+
+	if p.Trajeto.OneWay() {
+		taxa, err := GetTaxaRetorno(db, p.Trajeto.Retirada, p.Trajeto.Devolucao)
+		if err != nil {
+			return Cotacao{}, err
+		}
+		destino, err := GetFilialByID(db, p.Trajeto.Devolucao)
+		if err != nil {
+			return Cotacao{}, err
+		}
+		c.Itens = append(c.Itens, taxa.ItemCotacao(destino.Nome))
+	}
+
+	for _, pedido := range p.Extras {
+		extra, err := GetExtraByID(db, pedido.IDExtra)
+		if err != nil {
+			return Cotacao{}, err
+		}
+		c.Itens = append(c.Itens, extra.ItemCotacao(pedido.Quantidade, c.Dias))
+	}
+
+	c.somarTotal()
+	return c, nil
+}
+
+// --- TarifaSazonal ---
+
+func GetAllTarifasSazonais(db *sql.DB) ([]TarifaSazonal, error) {
+	rows, err := db.Query("SELECT id_tarifa, nome, categoria, data_inicio, data_fim, multiplicador FROM tarifas_sazonais ORDER BY data_inicio")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tarifas []TarifaSazonal
+	for rows.Next() {
+		var t TarifaSazonal
+		if err := rows.Scan(&t.ID, &t.Nome, &t.Categoria, &t.DataInicio, &t.DataFim, &t.Multiplicador); err != nil {
+			return nil, err
+		}
+		tarifas = append(tarifas, t)
+	}
+	return tarifas, rows.Err()
+}
+
+func CreateTarifaSazonal(db *sql.DB, t TarifaSazonal) error {
+	_, err := db.Exec("INSERT INTO tarifas_sazonais (nome, categoria, data_inicio, data_fim, multiplicador) VALUES (?, ?, ?, ?, ?)",
+		t.Nome, t.Categoria, t.DataInicio, t.DataFim, t.Multiplicador)
+	return err
+}
+
+func DeleteTarifaSazonal(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM tarifas_sazonais WHERE id_tarifa = ?", id)
+	return err
+}
+
+// --- Feriado ---
+
+func GetAllFeriados(db *sql.DB) ([]Feriado, error) {
+	rows, err := db.Query("SELECT data, descricao FROM feriados ORDER BY data")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feriados []Feriado
+	for rows.Next() {
+		var f Feriado
+		if err := rows.Scan(&f.Data, &f.Descricao); err != nil {
+			return nil, err
+		}
+		feriados = append(feriados, f)
+	}
+	return feriados, rows.Err()
+}
+
+func CreateFeriado(db *sql.DB, f Feriado) error {
+	_, err := db.Exec("INSERT OR REPLACE INTO feriados (data, descricao) VALUES (?, ?)", f.Data, f.Descricao)
+	return err
+}
+
+func DeleteFeriado(db *sql.DB, data string) error {
+	_, err := db.Exec("DELETE FROM feriados WHERE data = ?", data)
+	return err
+}
+
+// --- DescontoDuracao ---
+
+func GetAllDescontosDuracao(db *sql.DB) ([]DescontoDuracao, error) {
+	rows, err := db.Query("SELECT id_desconto, categoria, dias_minimos, percentual FROM descontos_duracao ORDER BY dias_minimos")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var descontos []DescontoDuracao
+	for rows.Next() {
+		var d DescontoDuracao
+		if err := rows.Scan(&d.ID, &d.Categoria, &d.DiasMinimos, &d.Percentual); err != nil {
+			return nil, err
+		}
+		descontos = append(descontos, d)
+	}
+	return descontos, rows.Err()
+}
+
+func CreateDescontoDuracao(db *sql.DB, d DescontoDuracao) error {
+	_, err := db.Exec("INSERT INTO descontos_duracao (categoria, dias_minimos, percentual) VALUES (?, ?, ?)",
+		d.Categoria, d.DiasMinimos, d.Percentual)
+	return err
+}
+
+func DeleteDescontoDuracao(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM descontos_duracao WHERE id_desconto = ?", id)
+	return err
+}
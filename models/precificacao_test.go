@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCotar(t *testing.T) {
+	tabela := TabelaPrecos{
+		DiariaBase:             Reais(100),
+		MultiplicadorFimSemana: 1.5,
+		MultiplicadorFeriado:   2,
+		Temporadas: []TarifaSazonal{
+			{Nome: "Verão", DataInicio: time.Date(2026, 12, 20, 0, 0, 0, 0, time.UTC), DataFim: time.Date(2027, 1, 10, 0, 0, 0, 0, time.UTC), Multiplicador: 1.2},
+			{Nome: "Réveillon", DataInicio: time.Date(2026, 12, 30, 0, 0, 0, 0, time.UTC), DataFim: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), Multiplicador: 1.4},
+		},
+		Feriados: map[string]string{"2026-11-20": "Consciência Negra"},
+		Descontos: []DescontoDuracao{
+			{DiasMinimos: 7, Percentual: 10},
+			{DiasMinimos: 30, Percentual: 20},
+		},
+	}
+	local := func(dia, mes, hora int) time.Time {
+		return time.Date(2026, time.Month(mes), dia, hora, 0, 0, 0, Fuso)
+	}
+
+	casos := []struct {
+		nome     string
+		inicio   time.Time
+		diarias  int
+		total    Dinheiro
+		desconto Dinheiro // valor (negativo) do item de desconto; 0 quando não há
+	}{
+		{"dias úteis", local(16, 11, 10), 2, Reais(200), 0},
+		{"fim de semana", local(13, 11, 10), 3, Reais(100 + 150 + 150), 0},
+		// 20/11 é sexta-feira: o feriado substitui o fim de semana, não acumula
+		{"feriado", local(19, 11, 10), 3, Reais(100 + 200 + 150), 0},
+		// A diária é do dia local em que começa: 23h de sexta ainda é sexta no fuso das filiais
+		{"retirada na noite de sexta", local(13, 11, 23), 1, Reais(100), 0},
+		{"temporada sobre fim de semana", local(18, 12, 10), 3, Reais(100 + 150 + 180), 0},
+		{"temporadas sobrepostas usam a maior", local(29, 12, 10), 2, Reais(120 + 140), 0},
+		// Seg 16/11 a seg 23/11: quatro dias úteis, o feriado, sábado e domingo
+		{"desconto semanal", local(16, 11, 10), 7, Reais(900 - 90), -Reais(90)},
+		// Seg 02/11 a ter 01/12: 21 dias úteis, o feriado e 8 dias de fim de semana; vale a faixa de 30 dias
+		{"desconto da maior faixa atingida", local(2, 11, 10), 30, Reais(3500 - 700), -Reais(700)},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			cot := tabela.Cotar(c.inicio, c.inicio.Add(time.Duration(c.diarias)*Diaria), c.diarias)
+			if cot.Dias != c.diarias {
+				t.Errorf("Dias = %d, esperado %d", cot.Dias, c.diarias)
+			}
+			var soma, desconto Dinheiro
+			for _, it := range cot.Itens {
+				soma += it.Valor
+				if it.Tipo == ItemDesconto {
+					desconto += it.Valor
+				}
+			}
+			if soma != cot.Total {
+				t.Errorf("Total %s diferente da soma dos itens %s", cot.Total, soma)
+			}
+			if cot.Total != c.total {
+				t.Errorf("Total = %s, esperado %s (itens %+v)", cot.Total, c.total, cot.Itens)
+			}
+			if desconto != c.desconto {
+				t.Errorf("desconto = %s, esperado %s", desconto, c.desconto)
+			}
+		})
+	}
+}
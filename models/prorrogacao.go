@@ -0,0 +1,141 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrLocacaoNaoProrrogavel = errors.New("locação não pode ser prorrogada")
+
+// PedidoDaLocacao remonta o pedido de cotação da locação para outro período, a partir dos
+// itens gravados na reserva: mesmos extras, plano, sobretaxa e trajeto
+func PedidoDaLocacao(db *sql.DB, l Locacao, inicio, fim time.Time) (PedidoCotacao, error) {
+	p := PedidoCotacao{
+		IDCarro:   l.IDCarro,
+		Categoria: l.Categoria,
+		Inicio:    inicio,
+		Fim:       fim,
+		IDPlano:   l.IDPlano,
+		Trajeto:   l.Trajeto(),
+	}
+	itens, err := GetItensLocacao(db, l.ID)
+	if err != nil {
+		return p, err
+	}
+	for _, it := range itens {
+		switch it.Tipo {
+		case ItemExtra:
+			p.Extras = append(p.Extras, ExtraSolicitado{IDExtra: it.IDExtra, Quantidade: it.Quantidade})
+		case ItemSobretaxa:
+			p.SobretaxaDiaria = it.ValorUnitario
+		}
+	}
+	return p, nil
+}
+
+// ajusteTx registra em pagamentos a diferença de valor da locação
+func ajusteTx(tx *sql.Tx, idLocacao int, valor Dinheiro) error {
+	_, err := tx.Exec(inserirPagamento, idLocacao, time.Now().UTC(), valor, "", "confirmado", PagamentoAjuste, "")
+	return err
+}
+
+// DiferencaProrrogacao é o valor a cobrar pelo período acrescentado: a diferença entre as
+// cotações estendida e original, nunca negativa
+func DiferencaProrrogacao(original, estendida Cotacao) Dinheiro {
+	return max(estendida.Total-original.Total, 0)
+}
+
+// ProrrogarLocacao troca a devolução para novoFim cobrando só o período acrescentado. As duas
+// cotações saem do motor atual — original para o período contratado e estendida até novoFim — e
+// a diferença entre elas entra como um item de prorrogação e um ajuste, somada ao ValorTotal já
+// acertado; os itens gravados na reserva não mudam. Uma reserva já paga volta a pendente quando
+// fica valor a pagar. Devolve o item lançado (nil quando não há diferença).
+//
+// A prorrogação nunca reduz o valor: se o período estendido atinge uma faixa de desconto por
+// duração, o preço já acertado é mantido e os dias acrescentados saem sem custo.
+func ProrrogarLocacao(db *sql.DB, l Locacao, novoFim time.Time, original, estendida Cotacao) (*ItemCotacao, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	diferenca := DiferencaProrrogacao(original, estendida)
+	status := l.Status
+	if status == "pago" && diferenca > 0 {
+		status = "pendente"
+	}
+	res, err := tx.Exec("UPDATE locacoes SET data_fim = ?, valor_total = ?, status = ? WHERE id_locacao = ? AND status = ? AND valor_total = ?",
+		novoFim, l.ValorTotal+diferenca, status, l.ID, l.Status, l.ValorTotal)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrLocacaoNaoProrrogavel
+	}
+	if diferenca == 0 {
+		return nil, tx.Commit()
+	}
+
+	item := ItemCotacao{
+		Tipo: ItemProrrogacao,
+		Descricao: fmt.Sprintf("Prorrogação de %s para %s (%d diária(s) a mais)",
+			l.DataFim.In(Fuso).Format("02/01/2006 15:04"), novoFim.In(Fuso).Format("02/01/2006 15:04"), estendida.Dias-original.Dias),
+		Quantidade:    1,
+		ValorUnitario: diferenca,
+		Valor:         diferenca,
+	}
+	if err := inserirItensTx(tx, l.ID, []ItemCotacao{item}); err != nil {
+		return nil, err
+	}
+	if err := ajusteTx(tx, l.ID, diferenca); err != nil {
+		return nil, err
+	}
+	return &item, tx.Commit()
+}
+
+// CreditoDevolucaoAntecipada calcula o crédito de uma devolução antes do prazo. O período
+// contratado e o usado são cotados pelo mesmo motor atual (o usado perde, por exemplo, o
+// desconto de duração); a fração não usada vale sobre o ValorTotal acertado, para que mudanças
+// de tarifa depois da reserva não alterem o crédito, e o cliente recebe o percentual da política.
+// Devolve nil quando não sobra diária inteira sem uso ou não há o que creditar.
+func (p PoliticaDevolucao) CreditoDevolucaoAntecipada(db *sql.DB, l Locacao, devolucao time.Time) (*ItemCotacao, error) {
+	usadas := DiariasCobradas(l.DataInicio, devolucao, p.ToleranciaAtraso)
+	sobra := l.DiasContratados(p.ToleranciaAtraso) - usadas
+	if sobra <= 0 || p.ReembolsoAntecipado <= 0 {
+		return nil, nil
+	}
+
+	pedido, err := PedidoDaLocacao(db, l, l.DataInicio, l.DataFim)
+	if err != nil {
+		return nil, err
+	}
+	contratado, err := CotarLocacao(db, pedido)
+	if err != nil {
+		return nil, err
+	}
+	pedido.Fim = l.DataInicio.Add(time.Duration(usadas) * Diaria)
+	usado, err := CotarLocacao(db, pedido)
+	if err != nil {
+		return nil, err
+	}
+	if contratado.Total <= 0 {
+		return nil, nil
+	}
+	naoUsado := l.ValorTotal.Multiplicar(float64(contratado.Total-usado.Total) / float64(contratado.Total))
+	credito := naoUsado.Percentual(p.ReembolsoAntecipado)
+	if credito <= 0 {
+		return nil, nil
+	}
+	return &ItemCotacao{
+		Tipo:          ItemDevolucaoAntecipada,
+		Descricao:     fmt.Sprintf("Crédito por devolução antecipada (%d diária(s) não usada(s), %g%%)", sobra, p.ReembolsoAntecipado),
+		Quantidade:    1,
+		ValorUnitario: -credito,
+		Valor:         -credito,
+	}, nil
+}
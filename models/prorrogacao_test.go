@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiferencaProrrogacao(t *testing.T) {
+	tabela := TabelaPrecos{
+		DiariaBase:             Reais(100),
+		MultiplicadorFimSemana: 1,
+		MultiplicadorFeriado:   1,
+		Descontos:              []DescontoDuracao{{DiasMinimos: 3, Percentual: 50}, {DiasMinimos: 5, Percentual: 10}},
+	}
+	inicio := time.Date(2026, 11, 16, 10, 0, 0, 0, Fuso)
+	cotar := func(diarias int) Cotacao {
+		return tabela.Cotar(inicio, inicio.Add(time.Duration(diarias)*Diaria), diarias)
+	}
+
+	casos := []struct {
+		nome               string
+		contratadas, novas int
+		diferenca          Dinheiro
+	}{
+		{"sem faixa de desconto", 1, 2, Reais(100)},
+		// 2 diárias = 200,00; 3 diárias com 50% = 150,00: o preço acertado é mantido
+		{"prorrogação que entra numa faixa de desconto", 2, 3, 0},
+		// 3 diárias com 50% = 150,00; 5 diárias com 10% = 450,00
+		{"prorrogação que troca de faixa", 3, 5, Reais(300)},
+		{"mesmo período", 2, 2, 0},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			if got := DiferencaProrrogacao(cotar(c.contratadas), cotar(c.novas)); got != c.diferenca {
+				t.Errorf("DiferencaProrrogacao(%d → %d diárias) = %s, esperado %s", c.contratadas, c.novas, got, c.diferenca)
+			}
+		})
+	}
+}
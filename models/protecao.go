@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// PlanoProtecao cobre danos ao veículo acima da franquia, mediante uma diária
+type PlanoProtecao struct {
+	ID          int      `db:"id_plano" json:"id"`
+	Codigo      string   `db:"codigo" json:"codigo"`
+	Nome        string   `db:"nome" json:"nome"`
+	ValorDiaria Dinheiro `db:"valor_diaria" json:"valor_diaria"`
+	Franquia    Dinheiro `db:"franquia" json:"franquia"`
+	Ativo       bool     `db:"ativo" json:"ativo"`
+}
+
+// ItemCotacao monta a linha do plano para o número de dias da locação
+func (p PlanoProtecao) ItemCotacao(dias int) ItemCotacao {
+	return ItemCotacao{
+		Tipo:          ItemProtecao,
+		Descricao:     fmt.Sprintf("Proteção %s (franquia %s)", p.Nome, p.Franquia.Formatar()),
+		Quantidade:    dias,
+		ValorUnitario: p.ValorDiaria,
+		Valor:         p.ValorDiaria * Dinheiro(dias),
+	}
+}
+
+// ValorCobravelDanos limita a cobrança de danos à franquia do plano contratado.
+// Locações sem plano respondem pelo valor integral.
+func (l Locacao) ValorCobravelDanos(dano Dinheiro) Dinheiro {
+	if l.IDPlano == 0 || dano <= l.Franquia {
+		return dano
+	}
+	return l.Franquia
+}
+
+const colunasPlano = "id_plano, codigo, nome, valor_diaria, franquia, ativo"
+
+func camposPlano(p *PlanoProtecao) []any {
+	return []any{&p.ID, &p.Codigo, &p.Nome, &p.ValorDiaria, &p.Franquia, &p.Ativo}
+}
+
+func GetAllPlanos(db *sql.DB) ([]PlanoProtecao, error) {
+	rows, err := db.Query("SELECT " + colunasPlano + " FROM planos_protecao ORDER BY valor_diaria")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var planos []PlanoProtecao
+	for rows.Next() {
+		var p PlanoProtecao
+		if err := rows.Scan(camposPlano(&p)...); err != nil {
+			return nil, err
+		}
+		planos = append(planos, p)
+	}
+	return planos, rows.Err()
+}
+
+func GetPlanoByID(db *sql.DB, id int) (PlanoProtecao, error) {
+	var p PlanoProtecao
+	err := db.QueryRow("SELECT "+colunasPlano+" FROM planos_protecao WHERE id_plano = ?", id).Scan(camposPlano(&p)...)
+	return p, err
+}
+
+// GetPlanoPadrao retorna o plano ativo mais barato, usado quando o cliente não escolhe nenhum
+func GetPlanoPadrao(db *sql.DB) (PlanoProtecao, error) {
+	var p PlanoProtecao
+	err := db.QueryRow("SELECT " + colunasPlano + " FROM planos_protecao WHERE ativo = TRUE ORDER BY valor_diaria, id_plano LIMIT 1").
+		Scan(camposPlano(&p)...)
+	return p, err
+}
+
+func CreatePlano(db *sql.DB, p PlanoProtecao) error {
+	_, err := db.Exec("INSERT INTO planos_protecao (codigo, nome, valor_diaria, franquia, ativo) VALUES (?, ?, ?, ?, ?)",
+		p.Codigo, p.Nome, p.ValorDiaria, p.Franquia, p.Ativo)
+	return err
+}
+
+func UpdatePlano(db *sql.DB, p PlanoProtecao) error {
+	_, err := db.Exec("UPDATE planos_protecao SET codigo=?, nome=?, valor_diaria=?, franquia=?, ativo=? WHERE id_plano=?",
+		p.Codigo, p.Nome, p.ValorDiaria, p.Franquia, p.Ativo, p.ID)
+	return err
+}
+
+func DeletePlano(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM planos_protecao WHERE id_plano = ?", id)
+	return err
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrSemRecibo = errors.New("este lançamento não gera recibo")
+
+// Recibo comprova um valor recebido: pagamento da locação ou captura da caução. Bloqueios,
+// liberações e ajustes não são recebimentos e não geram recibo. O número é sequencial e
+// atribuído junto com a gravação do pagamento.
+type Recibo struct {
+	Numero    int           `json:"numero"`
+	EmitidoEm time.Time     `json:"emitido_em"`
+	Pagamento Pagamento     `json:"pagamento"`
+	Locacao   Locacao       `json:"locacao"`
+	Cliente   Cliente       `json:"cliente"`
+	Itens     []ItemLocacao `json:"itens"`
+	Saldo     Dinheiro      `json:"saldo"` // saldo atual da locação (negativo = crédito do cliente)
+}
+
+// GeraRecibo informa se o tipo de pagamento é um recebimento
+func GeraRecibo(tipo string) bool {
+	return tipo == PagamentoLocacao || tipo == PagamentoCaucaoCaptura
+}
+
+// Referente descreve o que foi pago
+func (r Recibo) Referente() string {
+	if r.Pagamento.Tipo == PagamentoCaucaoCaptura {
+		return fmt.Sprintf("captura da caução da locação nº %d", r.Locacao.ID)
+	}
+	return fmt.Sprintf("pagamento da locação nº %d", r.Locacao.ID)
+}
+
+func emitirReciboTx(tx *sql.Tx, idPagamento int, tipo string) error {
+	if !GeraRecibo(tipo) {
+		return nil
+	}
+	_, err := tx.Exec("INSERT INTO recibos (id_pagamento, emitido_em) VALUES (?, ?)", idPagamento, time.Now().UTC())
+	return err
+}
+
+// GetRecibo monta o recibo do pagamento com os itens e o saldo da locação. Pagamentos
+// gravados antes dos recibos recebem o número na primeira consulta.
+func GetRecibo(db *sql.DB, idPagamento int) (Recibo, error) {
+	var r Recibo
+	var err error
+	if r.Pagamento, err = GetPagamentoByID(db, idPagamento); err != nil {
+		return r, err
+	}
+	if !GeraRecibo(r.Pagamento.Tipo) {
+		return r, ErrSemRecibo
+	}
+
+	const buscar = "SELECT numero, emitido_em FROM recibos WHERE id_pagamento = ?"
+	err = db.QueryRow(buscar, idPagamento).Scan(&r.Numero, &r.EmitidoEm)
+	if err == sql.ErrNoRows {
+		var tx *sql.Tx
+		if tx, err = db.Begin(); err != nil {
+			return r, err
+		}
+		defer tx.Rollback()
+		if err = emitirReciboTx(tx, idPagamento, r.Pagamento.Tipo); err == nil {
+			err = tx.Commit()
+		}
+		if err == nil {
+			err = db.QueryRow(buscar, idPagamento).Scan(&r.Numero, &r.EmitidoEm)
+		}
+	}
+	if err != nil {
+		return r, err
+	}
+
+	if r.Locacao, err = GetLocacaoByID(db, r.Pagamento.IDLocacao); err != nil {
+		return r, err
+	}
+	if r.Cliente, err = GetClienteByID(db, r.Locacao.IDCliente); err != nil {
+		return r, err
+	}
+	if r.Itens, err = GetItensLocacao(db, r.Locacao.ID); err != nil {
+		return r, err
+	}
+	r.Saldo, err = SaldoLocacao(db, r.Locacao.ID)
+	return r, err
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var ErrTokenInvalido = errors.New("token de verificação inválido ou já utilizado")
+var ErrTokenExpirado = errors.New("token de verificação expirado")
+
+// CampoDuplicado verifica se username, e-mail, documento ou CPF/CNPJ já pertencem a outro cadastro.
+// Retorna o nome do primeiro campo repetido, ou "" se não houver conflito.
+func CampoDuplicado(db *sql.DB, c Cliente) (string, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM usuarios WHERE usuario = ?", c.Username).Scan(&n)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		err = db.QueryRow("SELECT COUNT(*) FROM clientes WHERE username = ?", c.Username).Scan(&n)
+		if err != nil {
+			return "", err
+		}
+	}
+	if n > 0 {
+		return "username", nil
+	}
+
+	if c.Email != "" {
+		err = db.QueryRow("SELECT COUNT(*) FROM clientes WHERE lower(email) = lower(?)", c.Email).Scan(&n)
+		if err != nil {
+			return "", err
+		}
+		if n > 0 {
+			return "email", nil
+		}
+	}
+
+	if c.DocumentoIdentidade != "" {
+		err = db.QueryRow("SELECT COUNT(*) FROM clientes WHERE documento_identidade = ?", c.DocumentoIdentidade).Scan(&n)
+		if err != nil {
+			return "", err
+		}
+		if n > 0 {
+			return "documento_identidade", nil
+		}
+	}
+
+	if c.CPFCNPJ != "" {
+		err = db.QueryRow("SELECT COUNT(*) FROM clientes WHERE cpf_cnpj = ?", c.CPFCNPJ).Scan(&n)
+		if err != nil {
+			return "", err
+		}
+		if n > 0 {
+			return "cpf_cnpj", nil
+		}
+	}
+
+	return "", nil
+}
+
+// CampoViolado traduz uma violação de UNIQUE ao gravar clientes/usuários no nome do campo,
+// como em CampoDuplicado. Devolve "" se err não for uma violação de unicidade.
+func CampoViolado(err error) string {
+	var e sqlite3.Error
+	if !errors.As(err, &e) || e.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return ""
+	}
+	msg := e.Error()
+	switch {
+	case strings.Contains(msg, "clientes.username"), strings.Contains(msg, "usuarios.usuario"):
+		return "username"
+	case strings.Contains(msg, "clientes_email_unico"):
+		return "email"
+	case strings.Contains(msg, "clientes.documento_identidade"):
+		return "documento_identidade"
+	case strings.Contains(msg, "clientes.cpf_cnpj"):
+		return "cpf_cnpj"
+	}
+	return ""
+}
+
+// RegistrarCliente cria cliente, usuário (ainda não verificado) e o token de verificação
+// de e-mail numa única transação
+func RegistrarCliente(db *sql.DB, c Cliente, senha, token string, expiraEm time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := criarClienteTx(tx, c, senha, false); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO verificacoes_email (token_hash, usuario, expira_em) VALUES (?, ?, ?)`,
+		hashToken(token), c.Username, expiraEm)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// VerificarEmail consome o token (uso único) e libera o login do usuário
+func VerificarEmail(db *sql.DB, token string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var usuario string
+	var expiraEm time.Time
+	err = tx.QueryRow("SELECT usuario, expira_em FROM verificacoes_email WHERE token_hash = ? AND usado = FALSE", hashToken(token)).
+		Scan(&usuario, &expiraEm)
+	if err == sql.ErrNoRows {
+		return ErrTokenInvalido
+	}
+	if err != nil {
+		return err
+	}
+	if time.Now().After(expiraEm) {
+		return ErrTokenExpirado
+	}
+
+	res, err := tx.Exec("UPDATE verificacoes_email SET usado = TRUE WHERE token_hash = ? AND usado = FALSE", hashToken(token))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTokenInvalido
+	}
+
+	_, err = tx.Exec("UPDATE usuarios SET email_verificado = TRUE WHERE usuario = ?", usuario)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Apenas o hash do token fica salvo no banco
+func hashToken(token string) string {
+	soma := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(soma[:])
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCampoViolado(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, ddl := range []string{
+		"CREATE TABLE usuarios (usuario TEXT NOT NULL UNIQUE)",
+		"CREATE TABLE clientes (username TEXT UNIQUE, email TEXT, documento_identidade TEXT, cpf_cnpj TEXT)",
+		"CREATE UNIQUE INDEX clientes_email_unico ON clientes (lower(email)) WHERE email <> ''",
+		"CREATE UNIQUE INDEX clientes_documento_unico ON clientes (documento_identidade) WHERE documento_identidade <> ''",
+		"CREATE UNIQUE INDEX clientes_cpf_cnpj_unico ON clientes (cpf_cnpj) WHERE cpf_cnpj <> ''",
+		"INSERT INTO usuarios VALUES ('ana')",
+		"INSERT INTO clientes VALUES ('ana', 'Ana@x.com', 'RG1', '52998224725')",
+	} {
+		if _, err := db.Exec(ddl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	casos := []struct {
+		nome, sql, campo string
+	}{
+		{"usuário", "INSERT INTO usuarios VALUES ('ana')", "username"},
+		{"username do cliente", "INSERT INTO clientes VALUES ('ana', '', '', '')", "username"},
+		{"e-mail sem diferenciar maiúsculas", "INSERT INTO clientes VALUES ('b', 'ana@X.com', '', '')", "email"},
+		{"documento", "INSERT INTO clientes VALUES ('c', '', 'RG1', '')", "documento_identidade"},
+		{"cpf", "INSERT INTO clientes VALUES ('d', '', '', '52998224725')", "cpf_cnpj"},
+		{"vazios não conflitam", "INSERT INTO clientes VALUES ('e', '', '', '')", ""},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			_, err := db.Exec(c.sql)
+			if got := CampoViolado(err); got != c.campo {
+				t.Errorf("CampoViolado(%v) = %q, esperado %q", err, got, c.campo)
+			}
+		})
+	}
+
+	if got := CampoViolado(errors.New("UNIQUE constraint failed: clientes.username")); got != "" {
+		t.Errorf("erro que não é do SQLite não deveria ser reconhecido, obteve %q", got)
+	}
+	if got := CampoViolado(nil); got != "" {
+		t.Errorf("CampoViolado(nil) = %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RegraLocacao define as exigências para alugar carros de uma categoria.
+// Categoria vazia faz a regra valer para todos os carros.
+type RegraLocacao struct {
+	ID                     int      `db:"id_regra" json:"id"`
+	Categoria              string   `db:"categoria" json:"categoria"`
+	IdadeMinima            int      `db:"idade_minima" json:"idade_minima"`
+	TempoHabilitacaoMinimo int      `db:"tempo_habilitacao_minimo" json:"tempo_habilitacao_minimo"` // em anos
+	IdadeCondutorJovem     int      `db:"idade_condutor_jovem" json:"idade_condutor_jovem"`         // abaixo desta idade cobra sobretaxa
+	SobretaxaJovemDiaria   Dinheiro `db:"sobretaxa_jovem_diaria" json:"sobretaxa_jovem_diaria"`
+	ValorCaucao            Dinheiro `db:"valor_caucao" json:"valor_caucao"`
+	Ativa                  bool     `db:"ativa" json:"ativa"`
+}
+
+// AvaliacaoLocacao é o resultado das regras para um cliente e uma categoria
+type AvaliacaoLocacao struct {
+	Aprovada        bool     `json:"aprovada"`
+	Motivos         []string `json:"motivos,omitempty"`
+	SobretaxaDiaria Dinheiro `json:"sobretaxa_diaria"`
+	ValorCaucao     Dinheiro `json:"valor_caucao"`
+}
+
+// AvaliarRegras aplica todas as regras ao cliente considerando a data de início da locação.
+// Quando mais de uma regra se aplica vale a exigência mais rigorosa de cada uma.
+func AvaliarRegras(regras []RegraLocacao, c Cliente, inicio time.Time) AvaliacaoLocacao {
+	var av AvaliacaoLocacao
+
+	if c.Bloqueado {
+		motivo := "Cliente bloqueado para novas locações"
+		if c.MotivoBloqueio != "" {
+			motivo += ": " + c.MotivoBloqueio
+		}
+		av.Motivos = append(av.Motivos, motivo)
+	}
+
+	var idadeMinima, habilitacaoMinima, idadeJovem int
+	for _, r := range regras {
+		idadeMinima = max(idadeMinima, r.IdadeMinima)
+		habilitacaoMinima = max(habilitacaoMinima, r.TempoHabilitacaoMinimo)
+		idadeJovem = max(idadeJovem, r.IdadeCondutorJovem)
+		av.ValorCaucao = max(av.ValorCaucao, r.ValorCaucao)
+	}
+
+	if idadeMinima > 0 || idadeJovem > 0 {
+		nascimento, err := time.Parse("2006-01-02", c.DataNascimento)
+		if err != nil {
+			av.Motivos = append(av.Motivos, "Data de nascimento não cadastrada")
+		} else {
+			idade := anosCompletos(nascimento, inicio)
+			if idade < idadeMinima {
+				av.Motivos = append(av.Motivos, fmt.Sprintf("Idade mínima para esta categoria é %d anos", idadeMinima))
+			}
+			for _, r := range regras {
+				if idade < r.IdadeCondutorJovem {
+					av.SobretaxaDiaria = max(av.SobretaxaDiaria, r.SobretaxaJovemDiaria)
+				}
+			}
+		}
+	}
+
+	if habilitacaoMinima > 0 {
+		habilitacao, err := time.Parse("2006-01-02", c.CNHPrimeiraHabilitacao)
+		if err != nil {
+			av.Motivos = append(av.Motivos, "Data da primeira habilitação não cadastrada")
+		} else if anosCompletos(habilitacao, inicio) < habilitacaoMinima {
+			av.Motivos = append(av.Motivos, fmt.Sprintf("Tempo mínimo de habilitação para esta categoria é %d anos", habilitacaoMinima))
+		}
+	}
+
+	av.Aprovada = len(av.Motivos) == 0
+	return av
+}
+
+// anosCompletos conta quantos aniversários de "desde" ocorreram até "ate"
+func anosCompletos(desde, ate time.Time) int {
+	anos := ate.Year() - desde.Year()
+	if ate.Month() < desde.Month() || (ate.Month() == desde.Month() && ate.Day() < desde.Day()) {
+		anos--
+	}
+	return anos
+}
+
+// --- RegraLocacao ---
+
+const colunasRegra = "id_regra, categoria, idade_minima, tempo_habilitacao_minimo, idade_condutor_jovem, sobretaxa_jovem_diaria, valor_caucao, ativa"
+
+func camposRegra(r *RegraLocacao) []any {
+	return []any{&r.ID, &r.Categoria, &r.IdadeMinima, &r.TempoHabilitacaoMinimo, &r.IdadeCondutorJovem,
+		&r.SobretaxaJovemDiaria, &r.ValorCaucao, &r.Ativa}
+}
+
+func GetAllRegras(db *sql.DB) ([]RegraLocacao, error) {
+	return consultarRegras(db, "SELECT "+colunasRegra+" FROM regras_locacao")
+}
+
+// GetRegrasAplicaveis retorna as regras ativas gerais e as da categoria do carro
+func GetRegrasAplicaveis(db *sql.DB, categoria string) ([]RegraLocacao, error) {
+	return consultarRegras(db, "SELECT "+colunasRegra+" FROM regras_locacao WHERE ativa = TRUE AND (categoria = '' OR categoria = ?)", categoria)
+}
+
+func GetRegraByID(db *sql.DB, id int) (RegraLocacao, error) {
+	var r RegraLocacao
+	err := db.QueryRow("SELECT "+colunasRegra+" FROM regras_locacao WHERE id_regra = ?", id).Scan(camposRegra(&r)...)
+	return r, err
+}
+
+func consultarRegras(db *sql.DB, query string, args ...any) ([]RegraLocacao, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var regras []RegraLocacao
+	for rows.Next() {
+		var r RegraLocacao
+		if err := rows.Scan(camposRegra(&r)...); err != nil {
+			return nil, err
+		}
+		regras = append(regras, r)
+	}
+	return regras, rows.Err()
+}
+
+func CreateRegra(db *sql.DB, r RegraLocacao) error {
+	_, err := db.Exec(`INSERT INTO regras_locacao (categoria, idade_minima, tempo_habilitacao_minimo, idade_condutor_jovem,
+		sobretaxa_jovem_diaria, valor_caucao, ativa) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		r.Categoria, r.IdadeMinima, r.TempoHabilitacaoMinimo, r.IdadeCondutorJovem, r.SobretaxaJovemDiaria, r.ValorCaucao, r.Ativa)
+	return err
+}
+
+func UpdateRegra(db *sql.DB, r RegraLocacao) error {
+	_, err := db.Exec(`UPDATE regras_locacao SET categoria=?, idade_minima=?, tempo_habilitacao_minimo=?, idade_condutor_jovem=?,
+		sobretaxa_jovem_diaria=?, valor_caucao=?, ativa=? WHERE id_regra=?`,
+		r.Categoria, r.IdadeMinima, r.TempoHabilitacaoMinimo, r.IdadeCondutorJovem, r.SobretaxaJovemDiaria, r.ValorCaucao, r.Ativa, r.ID)
+	return err
+}
+
+func DeleteRegra(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM regras_locacao WHERE id_regra = ?", id)
+	return err
+}
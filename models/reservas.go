@@ -0,0 +1,157 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Situações de uma reserva temporária (hold)
+const (
+	ReservaAtiva     = "ativa"
+	ReservaConsumida = "consumida" // virou locação
+	ReservaExpirada  = "expirada"
+	ReservaLiberada  = "liberada" // substituída por outra reserva do mesmo cliente
+)
+
+// ReservaTemporaria segura um carro (ou uma vaga da categoria) para o período enquanto o
+// cliente conclui a reserva
+type ReservaTemporaria struct {
+	ID                int       `db:"id_reserva" json:"id"`
+	IDCliente         int       `db:"id_cliente" json:"id_cliente"`
+	IDCarro           int       `db:"id_carro" json:"id_carro,omitempty"`
+	Categoria         string    `db:"categoria" json:"categoria"`
+	DataInicio        time.Time `db:"data_inicio" json:"data_inicio"`
+	DataFim           time.Time `db:"data_fim" json:"data_fim"`
+	IDFilialRetirada  int       `db:"id_filial_retirada" json:"id_filial_retirada"`
+	IDFilialDevolucao int       `db:"id_filial_devolucao" json:"id_filial_devolucao"`
+	Status            string    `db:"status" json:"status"`
+	CriadaEm          time.Time `db:"criada_em" json:"criada_em"`
+	ExpiraEm          time.Time `db:"expira_em" json:"expira_em"`
+	IDLocacao         int       `db:"id_locacao" json:"id_locacao,omitempty"`
+}
+
+var ErrReservaIndisponivel = errors.New("a reserva temporária não existe, expirou ou já foi usada")
+
+// Condição SQL: reserva temporária (alias "h") que ainda ocupa o período no instante (?)
+const reservaVigente = "h.status = 'ativa' AND julianday(h.expira_em) > julianday(?)"
+
+// travaReservas serializa quem verifica a disponibilidade e em seguida ocupa o período
+// (reservas temporárias, locações, ofertas da lista de espera, carro escolhido na retirada,
+// transferências entre filiais, ordens de manutenção) e a varredura das reservas vencidas:
+// duas requisições simultâneas não podem ver a mesma vaga livre.
+var travaReservas sync.Mutex
+
+// TravarReservas e DestravarReservas expõem a trava para os handlers que ocupam períodos
+func TravarReservas()    { travaReservas.Lock() }
+func DestravarReservas() { travaReservas.Unlock() }
+
+func (h ReservaTemporaria) Trajeto() Trajeto {
+	return Trajeto{Retirada: h.IDFilialRetirada, Devolucao: h.IDFilialDevolucao}
+}
+
+// Vigente informa se a reserva ainda segura o período
+func (h ReservaTemporaria) Vigente(agora time.Time) bool {
+	return h.Status == ReservaAtiva && agora.Before(h.ExpiraEm)
+}
+
+const colunasReserva = "id_reserva, id_cliente, COALESCE(id_carro, 0), categoria, data_inicio, data_fim, id_filial_retirada, id_filial_devolucao, " +
+	"status, criada_em, expira_em, COALESCE(id_locacao, 0)"
+
+func GetReservaTemporariaByID(db *sql.DB, id int) (ReservaTemporaria, error) {
+	var h ReservaTemporaria
+	err := db.QueryRow("SELECT "+colunasReserva+" FROM reservas_temporarias WHERE id_reserva = ?", id).
+		Scan(&h.ID, &h.IDCliente, &h.IDCarro, &h.Categoria, &h.DataInicio, &h.DataFim, &h.IDFilialRetirada, &h.IDFilialDevolucao,
+			&h.Status, &h.CriadaEm, &h.ExpiraEm, &h.IDLocacao)
+	return h, err
+}
+
+// CriarReservaTemporaria segura o período por validade se houver disponibilidade (ErrSemCarroDisponivel
+// se não houver). Cada cliente mantém uma reserva por vez: a atual não conta na verificação e só é
+// liberada junto com a gravação da nova; se a nova falhar, o cliente continua com a que tinha.
+func CriarReservaTemporaria(db *sql.DB, h ReservaTemporaria, validade time.Duration) (ReservaTemporaria, error) {
+	travaReservas.Lock()
+	defer travaReservas.Unlock()
+
+	var atual int
+	err := db.QueryRow("SELECT COALESCE(MAX(id_reserva), 0) FROM reservas_temporarias WHERE id_cliente = ? AND status = ?",
+		h.IDCliente, ReservaAtiva).Scan(&atual)
+	if err != nil {
+		return h, err
+	}
+	livre, err := PeriodoDisponivel(db, h.IDCarro, h.Categoria, h.DataInicio, h.DataFim, h.Trajeto(), Ignorar{Reserva: atual})
+	if err != nil {
+		return h, err
+	}
+	if !livre {
+		return h, ErrSemCarroDisponivel
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return h, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE reservas_temporarias SET status = ? WHERE id_cliente = ? AND status = ?",
+		ReservaLiberada, h.IDCliente, ReservaAtiva)
+	if err != nil {
+		return h, err
+	}
+	h.Status, h.CriadaEm = ReservaAtiva, time.Now().UTC()
+	h.ExpiraEm = h.CriadaEm.Add(validade)
+	res, err := tx.Exec(`INSERT INTO reservas_temporarias (id_cliente, id_carro, categoria, data_inicio, data_fim,
+		id_filial_retirada, id_filial_devolucao, status, criada_em, expira_em)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		h.IDCliente, nuloSeZero(h.IDCarro), h.Categoria, h.DataInicio, h.DataFim,
+		h.IDFilialRetirada, h.IDFilialDevolucao, h.Status, h.CriadaEm, h.ExpiraEm)
+	if err != nil {
+		return h, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return h, err
+	}
+	h.ID = int(id)
+	return h, tx.Commit()
+}
+
+// ConsumirReservaTemporaria liga a reserva à locação criada a partir dela. Deve ser chamada
+// com a trava de reservas, na mesma operação que verificou a reserva.
+func ConsumirReservaTemporaria(db *sql.DB, id, idLocacao int) error {
+	_, err := db.Exec("UPDATE reservas_temporarias SET status = ?, id_locacao = ? WHERE id_reserva = ?", ReservaConsumida, idLocacao, id)
+	return err
+}
+
+// ExpirarReservasTemporarias marca as reservas vencidas e devolve as categorias que tiveram
+// vaga liberada. A disponibilidade já desconsidera reservas vencidas; a varredura mantém o
+// status em dia e avisa quem precisa repassar as vagas.
+func ExpirarReservasTemporarias(db *sql.DB) ([]string, error) {
+	travaReservas.Lock()
+	defer travaReservas.Unlock()
+
+	agora := time.Now().UTC()
+	rows, err := db.Query("SELECT DISTINCT h.categoria FROM reservas_temporarias h WHERE h.status = ? AND julianday(h.expira_em) <= julianday(?)",
+		ReservaAtiva, agora)
+	if err != nil {
+		return nil, err
+	}
+	var categorias []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		categorias = append(categorias, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec("UPDATE reservas_temporarias SET status = ? WHERE status = ? AND julianday(expira_em) <= julianday(?)",
+		ReservaExpirada, ReservaAtiva, agora)
+	return categorias, err
+}
@@ -0,0 +1,250 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErroValidacao indica qual campo do cadastro está inválido
+type ErroValidacao struct {
+	Campo    string
+	Mensagem string
+}
+
+func (e ErroValidacao) Error() string {
+	return e.Mensagem
+}
+
+// Categorias de CNH aceitas pelo Detran
+var categoriasCNH = map[string]bool{
+	"A": true, "B": true, "C": true, "D": true, "E": true,
+	"AB": true, "AC": true, "AD": true, "AE": true,
+}
+
+// SomenteDigitos remove pontos, traços, barras e espaços do documento
+func SomenteDigitos(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func digitos(s string) []int {
+	d := make([]int, len(s))
+	for i, r := range s {
+		d[i] = int(r - '0')
+	}
+	return d
+}
+
+func todosIguais(d []int) bool {
+	for _, v := range d[1:] {
+		if v != d[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidarCPF confere os dois dígitos verificadores do CPF
+func ValidarCPF(cpf string) bool {
+	cpf = SomenteDigitos(cpf)
+	if len(cpf) != 11 {
+		return false
+	}
+	d := digitos(cpf)
+	if todosIguais(d) {
+		return false
+	}
+
+	for pos := 9; pos <= 10; pos++ {
+		soma := 0
+		for i := 0; i < pos; i++ {
+			soma += d[i] * (pos + 1 - i)
+		}
+		dv := soma * 10 % 11
+		if dv == 10 {
+			dv = 0
+		}
+		if dv != d[pos] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidarCNPJ confere os dois dígitos verificadores do CNPJ
+func ValidarCNPJ(cnpj string) bool {
+	cnpj = SomenteDigitos(cnpj)
+	if len(cnpj) != 14 {
+		return false
+	}
+	d := digitos(cnpj)
+	if todosIguais(d) {
+		return false
+	}
+
+	pesos := []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	for pos := 12; pos <= 13; pos++ {
+		soma := 0
+		for i := 0; i < pos; i++ {
+			soma += d[i] * pesos[i+13-pos]
+		}
+		dv := soma % 11
+		if dv < 2 {
+			dv = 0
+		} else {
+			dv = 11 - dv
+		}
+		if dv != d[pos] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidarCNH confere os dígitos verificadores do número de registro da CNH
+func ValidarCNH(numero string) bool {
+	numero = SomenteDigitos(numero)
+	if len(numero) != 11 {
+		return false
+	}
+	d := digitos(numero)
+	if todosIguais(d) {
+		return false
+	}
+
+	soma := 0
+	for i := 0; i < 9; i++ {
+		soma += d[i] * (9 - i)
+	}
+	dv1 := soma % 11
+	desconto := 0
+	if dv1 >= 10 {
+		dv1 = 0
+		desconto = 2
+	}
+
+	soma = 0
+	for i := 0; i < 9; i++ {
+		soma += d[i] * (1 + i)
+	}
+	// O desconto do primeiro dígito vem antes: resto 1 com desconto 2 dá 10, que vira 0
+	dv2 := soma%11 - desconto
+	if dv2 < 0 {
+		dv2 += 11
+	}
+	if dv2 >= 10 {
+		dv2 = 0
+	}
+
+	return dv1 == d[9] && dv2 == d[10]
+}
+
+// ValidarCategoriaCNH verifica se a categoria existe
+func ValidarCategoriaCNH(categoria string) bool {
+	return categoriasCNH[strings.ToUpper(strings.TrimSpace(categoria))]
+}
+
+// CategoriaPermiteCarro indica se a categoria habilita a dirigir automóveis (B ou superior)
+func CategoriaPermiteCarro(categoria string) bool {
+	categoria = strings.ToUpper(strings.TrimSpace(categoria))
+	return ValidarCategoriaCNH(categoria) && categoria != "A"
+}
+
+// ValidadeCNH converte a data de validade da CNH (AAAA-MM-DD), à meia-noite no fuso das filiais
+func (c Cliente) ValidadeCNH() (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", c.CNHValidade, Fuso)
+}
+
+// NormalizarDocumentos deixa apenas dígitos em CPF/CNPJ e CNH e a categoria em maiúsculas
+func NormalizarDocumentos(c *Cliente) {
+	c.CPFCNPJ = SomenteDigitos(c.CPFCNPJ)
+	c.CNHNumero = SomenteDigitos(c.CNHNumero)
+	c.CNHCategoria = strings.ToUpper(strings.TrimSpace(c.CNHCategoria))
+	c.CNHValidade = strings.TrimSpace(c.CNHValidade)
+}
+
+// ValidarDocumentosCliente valida CPF/CNPJ e os dados da CNH.
+// Com exigirCPFCNPJ falso o documento só é conferido quando informado.
+// Os dados da CNH são opcionais no cadastro, mas se um deles for informado todos são exigidos.
+func ValidarDocumentosCliente(c Cliente, exigirCPFCNPJ bool) error {
+	switch {
+	case c.CPFCNPJ == "" && exigirCPFCNPJ:
+		return ErroValidacao{"cpf_cnpj", "CPF ou CNPJ é obrigatório"}
+	case c.CPFCNPJ == "":
+	case len(c.CPFCNPJ) == 11:
+		if !ValidarCPF(c.CPFCNPJ) {
+			return ErroValidacao{"cpf_cnpj", "CPF inválido"}
+		}
+	case len(c.CPFCNPJ) == 14:
+		if !ValidarCNPJ(c.CPFCNPJ) {
+			return ErroValidacao{"cpf_cnpj", "CNPJ inválido"}
+		}
+	default:
+		return ErroValidacao{"cpf_cnpj", "CPF deve ter 11 dígitos e CNPJ 14 dígitos"}
+	}
+
+	if c.DataNascimento != "" {
+		if _, err := time.Parse("2006-01-02", c.DataNascimento); err != nil {
+			return ErroValidacao{"data_nascimento", "Data de nascimento inválida. Use o formato AAAA-MM-DD."}
+		}
+	}
+	if c.CNHPrimeiraHabilitacao != "" {
+		if _, err := time.Parse("2006-01-02", c.CNHPrimeiraHabilitacao); err != nil {
+			return ErroValidacao{"cnh_primeira_habilitacao", "Data da primeira habilitação inválida. Use o formato AAAA-MM-DD."}
+		}
+	}
+
+	if c.CNHNumero == "" && c.CNHCategoria == "" && c.CNHValidade == "" {
+		return nil
+	}
+	if !ValidarCNH(c.CNHNumero) {
+		return ErroValidacao{"cnh_numero", "Número da CNH inválido"}
+	}
+	if !ValidarCategoriaCNH(c.CNHCategoria) {
+		return ErroValidacao{"cnh_categoria", "Categoria da CNH inválida"}
+	}
+	if _, err := c.ValidadeCNH(); err != nil {
+		return ErroValidacao{"cnh_validade", "Validade da CNH inválida. Use o formato AAAA-MM-DD."}
+	}
+	return nil
+}
+
+// VerificarCNHParaLocacao confere se o cliente pode dirigir durante todo o período da locação
+func VerificarCNHParaLocacao(c Cliente, dataFim time.Time) error {
+	if c.CNHNumero == "" {
+		return ErroValidacao{"cnh_numero", "Cliente sem CNH cadastrada"}
+	}
+	if !CategoriaPermiteCarro(c.CNHCategoria) {
+		return ErroValidacao{"cnh_categoria", "Categoria da CNH não permite dirigir automóveis"}
+	}
+	validade, err := c.ValidadeCNH()
+	if err != nil {
+		return ErroValidacao{"cnh_validade", "Validade da CNH inválida"}
+	}
+	// A CNH vale até o fim do dia da validade
+	if !dataFim.Before(validade.AddDate(0, 0, 1)) {
+		return ErroValidacao{"cnh_validade", "A CNH do cliente está vencida ou vence antes do fim da locação"}
+	}
+	return nil
+}
+
+// ValidarCarro confere a ficha técnica e o estado do carro no cadastro
+func ValidarCarro(c Carro) error {
+	switch {
+	case !slices.Contains(TiposCombustivel, c.TipoCombustivel):
+		return ErroValidacao{"tipo_combustivel", "tipo_combustivel inválido (flex, gasolina, etanol, diesel, eletrico ou hibrido)"}
+	case !slices.Contains(Transmissoes, c.Transmissao):
+		return ErroValidacao{"transmissao", "transmissao deve ser 'manual' ou 'automatica'"}
+	case c.Quilometragem < 0 || c.CapacidadeTanque < 0 || c.Lugares <= 0:
+		return ErroValidacao{"quilometragem", "Quilometragem, capacidade do tanque e lugares não podem ser negativos"}
+	case c.NivelCombustivel < 0 || c.NivelCombustivel > TanqueCheio:
+		return ErroValidacao{"nivel_combustivel", "nivel_combustivel deve estar entre 0 e 8 (oitavos do tanque)"}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package models
+
+import "testing"
+
+func TestValidarCPF(t *testing.T) {
+	casos := []struct {
+		cpf    string
+		valido bool
+	}{
+		{"529.982.247-25", true},
+		{"52998224725", true},
+		{"111.444.777-35", true},
+		{"529.982.247-24", false}, // segundo dígito errado
+		{"529.982.247-15", false}, // primeiro dígito errado
+		{"111.111.111-11", false}, // dígitos repetidos passam na conta, mas não são CPF
+		{"5299822472", false},
+		{"", false},
+	}
+	for _, c := range casos {
+		if got := ValidarCPF(c.cpf); got != c.valido {
+			t.Errorf("ValidarCPF(%q) = %v, esperado %v", c.cpf, got, c.valido)
+		}
+	}
+}
+
+func TestValidarCNPJ(t *testing.T) {
+	casos := []struct {
+		cnpj   string
+		valido bool
+	}{
+		{"11.222.333/0001-81", true},
+		{"11444777000161", true},
+		{"11.222.333/0001-80", false},
+		{"11.222.333/0001-91", false},
+		{"00.000.000/0000-00", false},
+		{"11.222.333/0001", false},
+	}
+	for _, c := range casos {
+		if got := ValidarCNPJ(c.cnpj); got != c.valido {
+			t.Errorf("ValidarCNPJ(%q) = %v, esperado %v", c.cnpj, got, c.valido)
+		}
+	}
+}
+
+func TestValidarCNH(t *testing.T) {
+	casos := []struct {
+		nome   string
+		numero string
+		valido bool
+	}{
+		{"sem desconto", "12345678900", true},
+		{"desconto aplicado ao segundo dígito", "10003959500", true},
+		{"desconto com resto 5", "10015046103", true},
+		// Primeiro dígito com resto 10: o segundo desconta 2 do resto antes de qualquer ajuste
+		{"resto 10 nos dois dígitos", "10010294708", true},
+		{"resto 10 nos dois dígitos (outro)", "10338141308", true},
+		{"resto 10 com a ordem invertida", "10010294709", false},
+		// Resto 1 menos o desconto dá 10, que vira 0; na ordem invertida o prefixo não tinha CNH válida
+		{"resto 1 com desconto", "10000002800", true},
+		{"resto 1 com desconto (outro)", "10000010900", true},
+		{"resto 1 com desconto (mais um)", "10000037000", true},
+		{"segundo dígito errado", "12345678901", false},
+		{"primeiro dígito errado", "12345678910", false},
+		{"dígitos repetidos", "11111111111", false},
+		{"curto", "1234567890", false},
+	}
+	for _, c := range casos {
+		if got := ValidarCNH(c.numero); got != c.valido {
+			t.Errorf("%s: ValidarCNH(%q) = %v, esperado %v", c.nome, c.numero, got, c.valido)
+		}
+	}
+}
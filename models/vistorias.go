@@ -0,0 +1,75 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Tipos de vistoria: leituras de odômetro e combustível na retirada e na devolução
+const (
+	VistoriaRetirada  = "retirada"
+	VistoriaDevolucao = "devolucao"
+)
+
+// TanqueCheio é o nível de combustível em oitavos (como no marcador do painel)
+const TanqueCheio = 8
+
+// Valores aceitos na ficha técnica do carro
+var (
+	TiposCombustivel = []string{"flex", "gasolina", "etanol", "diesel", "eletrico", "hibrido"}
+	Transmissoes     = []string{"manual", "automatica"}
+)
+
+type Vistoria struct {
+	ID            int       `db:"id_vistoria" json:"id"`
+	IDLocacao     int       `db:"id_locacao" json:"id_locacao"`
+	IDCarro       int       `db:"id_carro" json:"id_carro"`
+	Tipo          string    `db:"tipo" json:"tipo"`
+	DataHora      time.Time `db:"data_hora" json:"data_hora"`
+	Quilometragem int       `db:"quilometragem" json:"quilometragem"`
+	Combustivel   int       `db:"combustivel" json:"combustivel"` // oitavos do tanque, 0 a 8
+}
+
+const colunasVistoria = "id_vistoria, id_locacao, id_carro, tipo, data_hora, quilometragem, combustivel"
+
+func camposVistoria(v *Vistoria) []any {
+	return []any{&v.ID, &v.IDLocacao, &v.IDCarro, &v.Tipo, &v.DataHora, &v.Quilometragem, &v.Combustivel}
+}
+
+func GetVistoria(db *sql.DB, idLocacao int, tipo string) (Vistoria, error) {
+	var v Vistoria
+	err := db.QueryRow("SELECT "+colunasVistoria+" FROM vistorias WHERE id_locacao = ? AND tipo = ?", idLocacao, tipo).
+		Scan(camposVistoria(&v)...)
+	return v, err
+}
+
+// inserirVistoriaTx grava a leitura no histórico e atualiza o estado atual do carro
+func inserirVistoriaTx(tx *sql.Tx, v Vistoria) error {
+	_, err := tx.Exec("INSERT INTO vistorias (id_locacao, id_carro, tipo, data_hora, quilometragem, combustivel) VALUES (?, ?, ?, ?, ?, ?)",
+		v.IDLocacao, v.IDCarro, v.Tipo, v.DataHora, v.Quilometragem, v.Combustivel)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE carros SET quilometragem = ?, nivel_combustivel = ? WHERE id_carro = ?",
+		v.Quilometragem, v.Combustivel, v.IDCarro)
+	return err
+}
+
+// GetHistoricoCarro lista as vistorias do carro, da mais recente para a mais antiga
+func GetHistoricoCarro(db *sql.DB, idCarro int) ([]Vistoria, error) {
+	rows, err := db.Query("SELECT "+colunasVistoria+" FROM vistorias WHERE id_carro = ? ORDER BY data_hora DESC, id_vistoria DESC", idCarro)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	historico := []Vistoria{}
+	for rows.Next() {
+		var v Vistoria
+		if err := rows.Scan(camposVistoria(&v)...); err != nil {
+			return nil, err
+		}
+		historico = append(historico, v)
+	}
+	return historico, rows.Err()
+}
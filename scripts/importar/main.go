@@ -0,0 +1,69 @@
+// Importa carros ou clientes de um arquivo CSV ou JSON direto no banco, com as mesmas
+// validações de POST /importar/{entidade}:
+//
+//	go run ./scripts/importar -entidade carros -arquivo frota-filial.csv -simular
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func main() {
+	entidade := flag.String("entidade", "", "carros ou clientes")
+	arquivo := flag.String("arquivo", "", "arquivo .csv ou .json")
+	simular := flag.Bool("simular", false, "só valida, sem gravar")
+	banco := flag.String("banco", "./aluguel_carros.db", "caminho do banco SQLite")
+	flag.Parse()
+
+	importar := models.ImportarCarros
+	switch *entidade {
+	case "carros":
+	case "clientes":
+		importar = models.ImportarClientes
+	default:
+		log.Fatal("Informe -entidade carros ou clientes")
+	}
+	formato := strings.TrimPrefix(strings.ToLower(filepath.Ext(*arquivo)), ".")
+
+	f, err := os.Open(*arquivo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	// Com o servidor no ar, espera a vez em vez de falhar quando o banco está ocupado
+	db, err := sql.Open("sqlite3", *banco+"?_busy_timeout=5000")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	res, err := importar(db, f, formato, *simular)
+	if err != nil {
+		log.Fatalf("Erro ao importar %s: %v", *entidade, err)
+	}
+	for _, e := range res.Erros {
+		if e.Campo != "" {
+			fmt.Printf("linha %d (%s): %s\n", e.Linha, e.Campo, e.Mensagem)
+		} else {
+			fmt.Printf("linha %d: %s\n", e.Linha, e.Mensagem)
+		}
+	}
+	switch {
+	case len(res.Erros) > 0:
+		fmt.Printf("%d de %d registros com erro; nada foi importado\n", len(res.Erros), res.Registros)
+		os.Exit(1)
+	case res.Simulacao:
+		fmt.Printf("Simulação: %d registros válidos, nada foi gravado\n", res.Registros)
+	default:
+		fmt.Printf("%d %s importados\n", res.Importados, *entidade)
+	}
+}
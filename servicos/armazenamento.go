@@ -0,0 +1,79 @@
+package servicos
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var ErrCaminhoInvalido = errors.New("caminho de arquivo inválido")
+
+// Armazenamento guarda arquivos enviados (fotos, documentos) e devolve a URL do arquivo.
+// A implementação pode ser o disco local ou um serviço de objetos.
+type Armazenamento interface {
+	Salvar(caminho string, conteudo io.Reader) (url string, err error)
+	Abrir(caminho string) (io.ReadCloser, error)
+	Remover(caminho string) error
+}
+
+// ArmazenamentoLocal grava em Diretorio; as URLs devolvidas começam com URLBase
+// (ex.: Diretorio "arquivos", URLBase "/arquivos"), cabendo aos handlers servir cada uma
+type ArmazenamentoLocal struct {
+	Diretorio string
+	URLBase   string
+}
+
+func (a ArmazenamentoLocal) Salvar(caminho string, conteudo io.Reader) (string, error) {
+	destino, err := a.resolver(caminho)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(destino), 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.Create(destino)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, conteudo); err != nil {
+		f.Close()
+		os.Remove(destino)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return path.Join(a.URLBase, caminho), nil
+}
+
+func (a ArmazenamentoLocal) Abrir(caminho string) (io.ReadCloser, error) {
+	origem, err := a.resolver(caminho)
+	if err != nil {
+		return nil, err
+	}
+	// Diretórios não são arquivos: nada de listagem do conteúdo
+	if info, err := os.Stat(origem); err == nil && info.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: caminho, Err: os.ErrNotExist}
+	}
+	return os.Open(origem)
+}
+
+func (a ArmazenamentoLocal) Remover(caminho string) error {
+	destino, err := a.resolver(caminho)
+	if err != nil {
+		return err
+	}
+	return os.Remove(destino)
+}
+
+// resolver impede que o caminho escape do diretório (ex.: "../../main.go")
+func (a ArmazenamentoLocal) resolver(caminho string) (string, error) {
+	limpo := path.Clean("/" + caminho)
+	if limpo == "/" || strings.Contains(caminho, "..") {
+		return "", ErrCaminhoInvalido
+	}
+	return filepath.Join(a.Diretorio, filepath.FromSlash(limpo)), nil
+}
@@ -0,0 +1,390 @@
+package servicos
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Algoritmos da assinatura XMLDSig usados pelo padrão ABRASF
+const (
+	NamespaceXMLDSig = "http://www.w3.org/2000/09/xmldsig#"
+	algoritmoC14N    = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	algoritmoRSASHA1 = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	algoritmoSHA1    = "http://www.w3.org/2000/09/xmldsig#sha1"
+	transformadaEnv  = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+)
+
+var (
+	ErrXMLNaoSuportado    = errors.New("XML com prefixos de namespace, comentários ou conteúdo misto não é suportado")
+	ErrAssinaturaAusente  = errors.New("elemento assinado ou assinatura não encontrados")
+	ErrAssinaturaRecusada = errors.New("assinatura digital inválida")
+)
+
+// NoXML é um elemento montado em memória. A serialização já sai na forma canônica (C14N 1.0
+// sem comentários): tags de fechamento explícitas, namespace antes dos atributos, atributos em
+// ordem e só os escapes obrigatórios. Assim o arquivo gravado é byte a byte o que foi assinado.
+// Só há namespace padrão (xmlns) e cada elemento tem texto ou filhos, nunca os dois.
+type NoXML struct {
+	Nome      string
+	Namespace string // xmlns declarado no elemento; vazio herda o do pai
+	Atributos [][2]string
+	Texto     string
+	Filhos    []*NoXML
+}
+
+func Elemento(nome string, filhos ...*NoXML) *NoXML {
+	return &NoXML{Nome: nome, Filhos: filhos}
+}
+
+func Texto(nome, texto string) *NoXML {
+	return &NoXML{Nome: nome, Texto: texto}
+}
+
+// Atributo define um atributo mantendo a ordem canônica (alfabética)
+func (n *NoXML) Atributo(nome, valor string) *NoXML {
+	n.Atributos = append(n.Atributos, [2]string{nome, valor})
+	sort.Slice(n.Atributos, func(i, j int) bool { return n.Atributos[i][0] < n.Atributos[j][0] })
+	return n
+}
+
+func (n *NoXML) valor(atributo string) string {
+	for _, a := range n.Atributos {
+		if a[0] == atributo {
+			return a[1]
+		}
+	}
+	return ""
+}
+
+// Bytes serializa o documento com a declaração XML
+func (n *NoXML) Bytes() []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+	n.escrever(&b, "", "")
+	return b.Bytes()
+}
+
+// canonico serializa o elemento como raiz do conjunto assinado, herdando o namespace do contexto
+func (n *NoXML) canonico(nsContexto string) []byte {
+	var b bytes.Buffer
+	n.escrever(&b, nsContexto, "")
+	return b.Bytes()
+}
+
+// escrever: herdado é o namespace padrão vigente no documento, emitido o que já foi declarado na saída
+func (n *NoXML) escrever(b *bytes.Buffer, herdado, emitido string) {
+	ns := herdado
+	if n.Namespace != "" {
+		ns = n.Namespace
+	}
+	b.WriteString("<" + n.Nome)
+	if ns != emitido {
+		fmt.Fprintf(b, ` xmlns="%s"`, escaparAtributo(ns))
+	}
+	for _, a := range n.Atributos {
+		fmt.Fprintf(b, ` %s="%s"`, a[0], escaparAtributo(a[1]))
+	}
+	b.WriteString(">")
+	if len(n.Filhos) == 0 {
+		b.WriteString(escaparTexto(n.Texto))
+	}
+	for _, f := range n.Filhos {
+		f.escrever(b, ns, ns)
+	}
+	b.WriteString("</" + n.Nome + ">")
+}
+
+func escaparTexto(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;").Replace(s)
+}
+
+func escaparAtributo(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;").Replace(s)
+}
+
+// LerXML monta a árvore a partir de um documento no mesmo subconjunto que NoXML produz.
+// Espaços entre elementos são descartados.
+func LerXML(dados []byte) (*NoXML, error) {
+	d := xml.NewDecoder(bytes.NewReader(dados))
+	var pilha []*NoXML
+	var raiz *NoXML
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space != "" {
+				return nil, ErrXMLNaoSuportado
+			}
+			no := &NoXML{Nome: t.Name.Local}
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					no.Namespace = a.Value
+				case a.Name.Space != "":
+					return nil, ErrXMLNaoSuportado
+				default:
+					no.Atributo(a.Name.Local, a.Value)
+				}
+			}
+			if len(pilha) > 0 {
+				pai := pilha[len(pilha)-1]
+				if strings.TrimSpace(pai.Texto) != "" {
+					return nil, ErrXMLNaoSuportado
+				}
+				pai.Texto = ""
+				pai.Filhos = append(pai.Filhos, no)
+			} else {
+				raiz = no
+			}
+			pilha = append(pilha, no)
+		case xml.EndElement:
+			pilha = pilha[:len(pilha)-1]
+		case xml.CharData:
+			if len(pilha) > 0 {
+				atual := pilha[len(pilha)-1]
+				if len(atual.Filhos) > 0 && strings.TrimSpace(string(t)) != "" {
+					return nil, ErrXMLNaoSuportado
+				}
+				if len(atual.Filhos) == 0 {
+					atual.Texto += string(t)
+				}
+			}
+		case xml.Comment:
+			return nil, ErrXMLNaoSuportado
+		}
+	}
+	if raiz == nil {
+		return nil, ErrXMLNaoSuportado
+	}
+	return raiz, nil
+}
+
+// Certificado é o par chave/certificado usado para assinar os documentos fiscais
+type Certificado struct {
+	Chave *rsa.PrivateKey
+	X509  *x509.Certificate
+}
+
+// CarregarCertificado lê certificado e chave RSA (PKCS#1 ou PKCS#8) em PEM
+func CarregarCertificado(arquivoCertificado, arquivoChave string) (Certificado, error) {
+	var c Certificado
+	blocoCert, err := lerPEM(arquivoCertificado)
+	if err != nil {
+		return c, err
+	}
+	if c.X509, err = x509.ParseCertificate(blocoCert.Bytes); err != nil {
+		return c, err
+	}
+	blocoChave, err := lerPEM(arquivoChave)
+	if err != nil {
+		return c, err
+	}
+	if c.Chave, err = x509.ParsePKCS1PrivateKey(blocoChave.Bytes); err == nil {
+		return c, nil
+	}
+	chave, err := x509.ParsePKCS8PrivateKey(blocoChave.Bytes)
+	if err != nil {
+		return c, err
+	}
+	rsaChave, ok := chave.(*rsa.PrivateKey)
+	if !ok {
+		return c, errors.New("a chave do certificado precisa ser RSA")
+	}
+	c.Chave = rsaChave
+	return c, nil
+}
+
+func lerPEM(arquivo string) (*pem.Block, error) {
+	dados, err := os.ReadFile(arquivo)
+	if err != nil {
+		return nil, err
+	}
+	bloco, _ := pem.Decode(dados)
+	if bloco == nil {
+		return nil, fmt.Errorf("%s não está em formato PEM", arquivo)
+	}
+	return bloco, nil
+}
+
+// CertificadoLocal usa certificado.pem e chave.pem do diretório. Se não existirem, gera um
+// certificado autoassinado para desenvolvimento; em produção, coloque ali o certificado A1
+// da empresa convertido para PEM.
+func CertificadoLocal(diretorio, titular string) (Certificado, error) {
+	arquivoCert := filepath.Join(diretorio, "certificado.pem")
+	arquivoChave := filepath.Join(diretorio, "chave.pem")
+	if _, err := os.Stat(arquivoCert); err == nil {
+		return CarregarCertificado(arquivoCert, arquivoChave)
+	}
+
+	chave, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return Certificado{}, err
+	}
+	modelo := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: titular},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, modelo, modelo, &chave.PublicKey, chave)
+	if err != nil {
+		return Certificado{}, err
+	}
+	if err := os.MkdirAll(diretorio, 0o700); err != nil {
+		return Certificado{}, err
+	}
+	err = os.WriteFile(arquivoChave, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(chave)}), 0o600)
+	if err != nil {
+		return Certificado{}, err
+	}
+	if err := os.WriteFile(arquivoCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return Certificado{}, err
+	}
+	return CarregarCertificado(arquivoCert, arquivoChave)
+}
+
+// Assinar gera a assinatura XMLDSig envelopada do elemento (que precisa ter o atributo Id).
+// nsContexto é o namespace padrão que o elemento herda no documento. O elemento Signature
+// devolvido deve ser incluído no documento fora do elemento assinado.
+func (c Certificado) Assinar(no *NoXML, nsContexto string) (*NoXML, error) {
+	id := no.valor("Id")
+	if id == "" {
+		return nil, ErrAssinaturaAusente
+	}
+	resumo := sha1.Sum(no.canonico(nsContexto))
+
+	signedInfo := Elemento("SignedInfo",
+		Elemento("CanonicalizationMethod").Atributo("Algorithm", algoritmoC14N),
+		Elemento("SignatureMethod").Atributo("Algorithm", algoritmoRSASHA1),
+		Elemento("Reference",
+			Elemento("Transforms",
+				Elemento("Transform").Atributo("Algorithm", transformadaEnv),
+				Elemento("Transform").Atributo("Algorithm", algoritmoC14N),
+			),
+			Elemento("DigestMethod").Atributo("Algorithm", algoritmoSHA1),
+			Texto("DigestValue", base64.StdEncoding.EncodeToString(resumo[:])),
+		).Atributo("URI", "#"+id),
+	)
+	resumoInfo := sha1.Sum(signedInfo.canonico(NamespaceXMLDSig))
+	valor, err := rsa.SignPKCS1v15(rand.Reader, c.Chave, crypto.SHA1, resumoInfo[:])
+	if err != nil {
+		return nil, err
+	}
+
+	assinatura := Elemento("Signature",
+		signedInfo,
+		Texto("SignatureValue", base64.StdEncoding.EncodeToString(valor)),
+		Elemento("KeyInfo", Elemento("X509Data", Texto("X509Certificate", base64.StdEncoding.EncodeToString(c.X509.Raw)))),
+	)
+	assinatura.Namespace = NamespaceXMLDSig
+	return assinatura, nil
+}
+
+// VerificarAssinatura confere a assinatura do primeiro elemento com atributo Id do documento,
+// que deve ter a Signature como irmã, e devolve o certificado de quem assinou
+func VerificarAssinatura(doc *NoXML) (*x509.Certificate, error) {
+	assinado, assinatura, nsContexto := localizarAssinado(doc, "")
+	if assinado == nil || assinatura == nil {
+		return nil, ErrAssinaturaAusente
+	}
+	signedInfo := filho(assinatura, "SignedInfo")
+	referencia := filho(signedInfo, "Reference")
+	digest := filho(referencia, "DigestValue")
+	valor := filho(assinatura, "SignatureValue")
+	certB64 := filho(filho(filho(assinatura, "KeyInfo"), "X509Data"), "X509Certificate")
+	if digest == nil || valor == nil || certB64 == nil || referencia.valor("URI") != "#"+assinado.valor("Id") {
+		return nil, ErrAssinaturaAusente
+	}
+
+	resumo := sha1.Sum(assinado.canonico(nsContexto))
+	if base64.StdEncoding.EncodeToString(resumo[:]) != strings.TrimSpace(digest.Texto) {
+		return nil, ErrAssinaturaRecusada
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(certB64.Texto))
+	if err != nil {
+		return nil, ErrAssinaturaRecusada
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, ErrAssinaturaRecusada
+	}
+	chave, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrAssinaturaRecusada
+	}
+	assinaturaBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(valor.Texto))
+	if err != nil {
+		return nil, ErrAssinaturaRecusada
+	}
+	resumoInfo := sha1.Sum(signedInfo.canonico(nsDe(assinatura, NamespaceXMLDSig)))
+	if err := rsa.VerifyPKCS1v15(chave, crypto.SHA1, resumoInfo[:], assinaturaBytes); err != nil {
+		return nil, ErrAssinaturaRecusada
+	}
+	return cert, nil
+}
+
+// localizarAssinado procura o elemento com Id e a Signature entre os seus irmãos
+func localizarAssinado(no *NoXML, herdado string) (*NoXML, *NoXML, string) {
+	ns := nsDe(no, herdado)
+	var assinado, assinatura *NoXML
+	for _, f := range no.Filhos {
+		if f.valor("Id") != "" && assinado == nil {
+			assinado = f
+		}
+		if f.Nome == "Signature" {
+			assinatura = f
+		}
+	}
+	if assinado != nil && assinatura != nil {
+		return assinado, assinatura, ns
+	}
+	for _, f := range no.Filhos {
+		if a, s, n := localizarAssinado(f, ns); a != nil {
+			return a, s, n
+		}
+	}
+	return nil, nil, ""
+}
+
+func nsDe(no *NoXML, herdado string) string {
+	if no.Namespace != "" {
+		return no.Namespace
+	}
+	return herdado
+}
+
+func filho(no *NoXML, nome string) *NoXML {
+	if no == nil {
+		return nil
+	}
+	for _, f := range no.Filhos {
+		if f.Nome == nome {
+			return f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package servicos
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestNoXMLCanonico(t *testing.T) {
+	casos := []struct {
+		nome       string
+		no         *NoXML
+		nsContexto string
+		esperado   string
+	}{
+		{"elemento vazio com fechamento explícito", Elemento("a"), "", "<a></a>"},
+		{"atributos em ordem alfabética", Elemento("a").Atributo("z", "1").Atributo("b", "2"), "", `<a b="2" z="1"></a>`},
+		{"escapes do texto", Texto("a", `x & <y> "z" 'w'`+"\r"), "", `<a>x &amp; &lt;y&gt; "z" 'w'&#xD;</a>`},
+		{"escapes do atributo", Elemento("a").Atributo("v", "<\"&\t\n\r>"), "", `<a v="&lt;&quot;&amp;&#x9;&#xA;&#xD;>"></a>`},
+		{"namespace herdado do contexto", Elemento("a", Texto("b", "1")), "urn:x", `<a xmlns="urn:x"><b>1</b></a>`},
+		{"namespace antes dos atributos", Elemento("a").Atributo("Id", "a1"), "urn:x", `<a xmlns="urn:x" Id="a1"></a>`},
+		{"filho em outro namespace", Elemento("a", &NoXML{Nome: "b", Namespace: "urn:y"}), "urn:x", `<a xmlns="urn:x"><b xmlns="urn:y"></b></a>`},
+		{"filho redeclarando o mesmo namespace", Elemento("a", &NoXML{Nome: "b", Namespace: "urn:x"}), "urn:x", `<a xmlns="urn:x"><b></b></a>`},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			if got := string(c.no.canonico(c.nsContexto)); got != c.esperado {
+				t.Errorf("canonico = %s, esperado %s", got, c.esperado)
+			}
+		})
+	}
+}
+
+func TestLerXML(t *testing.T) {
+	doc := &NoXML{Nome: "Envio", Namespace: "urn:teste", Filhos: []*NoXML{
+		Elemento("Item", Texto("Nome", "Pneu & roda"), Texto("Vazio", "")).Atributo("Id", "i1"),
+		{Nome: "Outro", Namespace: "urn:outro", Filhos: []*NoXML{Texto("Valor", "10.00")}},
+	}}
+	original := doc.Bytes()
+	lido, err := LerXML(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lido.Bytes(); !bytes.Equal(got, original) {
+		t.Errorf("ida e volta:\n%s\nesperado\n%s", got, original)
+	}
+
+	// Espaços entre elementos são descartados; o texto das folhas é mantido como está
+	indentado := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Envio xmlns=\"urn:teste\">\n  <Item Id=\"i1\">\n    <Nome> Pneu </Nome>\n  </Item>\n</Envio>\n"
+	compacto := `<?xml version="1.0" encoding="UTF-8"?><Envio xmlns="urn:teste"><Item Id="i1"><Nome> Pneu </Nome></Item></Envio>`
+	if lido, err := LerXML([]byte(indentado)); err != nil || string(lido.Bytes()) != compacto {
+		t.Errorf("documento indentado: erro %v", err)
+	}
+
+	for _, invalido := range []string{
+		`<a xmlns:p="urn:p"><p:b></p:b></a>`,
+		`<a p:x="1"></a>`,
+		`<a><!-- comentário --></a>`,
+		`<a>texto<b></b></a>`,
+		`<a><b></b>texto</a>`,
+		``,
+	} {
+		if _, err := LerXML([]byte(invalido)); !errors.Is(err, ErrXMLNaoSuportado) {
+			t.Errorf("LerXML(%q) = %v, esperado ErrXMLNaoSuportado", invalido, err)
+		}
+	}
+}
+
+func TestAssinatura(t *testing.T) {
+	cert, err := CertificadoLocal(t.TempDir(), "Locadora")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outro, err := CertificadoLocal(t.TempDir(), "Outra empresa")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assinado := func(t *testing.T) *NoXML {
+		t.Helper()
+		inf := Elemento("Inf", Texto("Valor", "100.00"), Texto("Obs", "Locação <diária> & extras")).Atributo("Id", "rps1")
+		sig, err := cert.Assinar(inf, "urn:teste")
+		if err != nil {
+			t.Fatal(err)
+		}
+		envio := Elemento("Envio", Elemento("Rps", inf, sig))
+		envio.Namespace = "urn:teste"
+		// Verificado depois de gravado e lido de volta, como na transmissão
+		doc, err := LerXML(envio.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	no := func(doc *NoXML, caminho ...string) *NoXML {
+		for _, nome := range caminho {
+			doc = filho(doc, nome)
+		}
+		return doc
+	}
+
+	casos := []struct {
+		nome     string
+		alterar  func(doc *NoXML)
+		esperado error
+	}{
+		{"documento íntegro", func(*NoXML) {}, nil},
+		{"valor alterado", func(doc *NoXML) { no(doc, "Rps", "Inf", "Valor").Texto = "100.01" }, ErrAssinaturaRecusada},
+		{"atributo incluído", func(doc *NoXML) { no(doc, "Rps", "Inf").Atributo("Extra", "1") }, ErrAssinaturaRecusada},
+		{"namespace herdado alterado", func(doc *NoXML) { doc.Namespace = "urn:outro" }, ErrAssinaturaRecusada},
+		{"resumo trocado", func(doc *NoXML) {
+			no(doc, "Rps", "Signature", "SignedInfo", "Reference", "DigestValue").Texto = base64.StdEncoding.EncodeToString(make([]byte, 20))
+		}, ErrAssinaturaRecusada},
+		{"valor da assinatura alterado", func(doc *NoXML) {
+			valor := no(doc, "Rps", "Signature", "SignatureValue")
+			b, _ := base64.StdEncoding.DecodeString(valor.Texto)
+			b[0] ^= 0xff
+			valor.Texto = base64.StdEncoding.EncodeToString(b)
+		}, ErrAssinaturaRecusada},
+		{"certificado de outro titular", func(doc *NoXML) {
+			no(doc, "Rps", "Signature", "KeyInfo", "X509Data", "X509Certificate").Texto = base64.StdEncoding.EncodeToString(outro.X509.Raw)
+		}, ErrAssinaturaRecusada},
+		{"referência para outro Id", func(doc *NoXML) {
+			no(doc, "Rps", "Inf").Atributos = [][2]string{{"Id", "rps2"}}
+		}, ErrAssinaturaAusente},
+		{"sem assinatura", func(doc *NoXML) {
+			rps := no(doc, "Rps")
+			rps.Filhos = rps.Filhos[:1]
+		}, ErrAssinaturaAusente},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			doc := assinado(t)
+			c.alterar(doc)
+			assinante, err := VerificarAssinatura(doc)
+			if !errors.Is(err, c.esperado) {
+				t.Fatalf("VerificarAssinatura = %v, esperado %v", err, c.esperado)
+			}
+			if err == nil && !assinante.Equal(cert.X509) {
+				t.Errorf("certificado devolvido não é o de quem assinou")
+			}
+		})
+	}
+
+	if _, err := cert.Assinar(Elemento("SemId"), ""); !errors.Is(err, ErrAssinaturaAusente) {
+		t.Errorf("assinar elemento sem Id: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package servicos
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+var (
+	ErrAutorizacaoInexistente = errors.New("pré-autorização não encontrada")
+	ErrAutorizacaoEncerrada   = errors.New("pré-autorização já capturada ou liberada")
+	ErrValorAcimaAutorizado   = errors.New("valor acima do pré-autorizado")
+)
+
+// GatewayPagamento abstrai o provedor de cartão usado na caução.
+// Capturar encerra a pré-autorização: a parte não capturada volta ao limite do cliente.
+type GatewayPagamento interface {
+	PreAutorizar(valor models.Dinheiro, descricao string) (referencia string, err error)
+	Capturar(referencia string, valor models.Dinheiro) error
+	Liberar(referencia string) error
+}
+
+// GatewayLocal guarda as pré-autorizações em memória e aprova tudo.
+// Serve para desenvolvimento enquanto não há um provedor real integrado.
+// A referência é aleatória e carrega o valor autorizado, para que as cauções abertas
+// antes de reiniciar o servidor ainda possam ser capturadas ou liberadas.
+type GatewayLocal struct {
+	mu           sync.Mutex
+	autorizacoes map[string]*autorizacao
+}
+
+type autorizacao struct {
+	valor     models.Dinheiro
+	encerrada bool
+}
+
+func NovoGatewayLocal() *GatewayLocal {
+	return &GatewayLocal{autorizacoes: map[string]*autorizacao{}}
+}
+
+func (g *GatewayLocal) PreAutorizar(valor models.Dinheiro, descricao string) (string, error) {
+	aleatorio := make([]byte, 8)
+	if _, err := rand.Read(aleatorio); err != nil {
+		return "", err
+	}
+	ref := fmt.Sprintf("local-%x-%d", aleatorio, valor.Centavos())
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.autorizacoes[ref] = &autorizacao{valor: valor}
+	log.Printf("[gateway] pré-autorização %s de %s (%s)", ref, valor.Formatar(), descricao)
+	return ref, nil
+}
+
+func (g *GatewayLocal) Capturar(referencia string, valor models.Dinheiro) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	a, err := g.aberta(referencia)
+	if err != nil {
+		return err
+	}
+	if valor > a.valor {
+		return ErrValorAcimaAutorizado
+	}
+	a.encerrada = true
+	log.Printf("[gateway] captura %s de %s (liberado %s)", referencia, valor.Formatar(), (a.valor - valor).Formatar())
+	return nil
+}
+
+func (g *GatewayLocal) Liberar(referencia string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	a, err := g.aberta(referencia)
+	if err != nil {
+		return err
+	}
+	a.encerrada = true
+	log.Printf("[gateway] liberação %s de %s", referencia, a.valor.Formatar())
+	return nil
+}
+
+// aberta exige g.mu travado
+func (g *GatewayLocal) aberta(referencia string) (*autorizacao, error) {
+	a, ok := g.autorizacoes[referencia]
+	if !ok {
+		// Aberta numa execução anterior: o valor vem da própria referência
+		valor, ok := valorDaReferencia(referencia)
+		if !ok {
+			return nil, ErrAutorizacaoInexistente
+		}
+		a = &autorizacao{valor: valor}
+		g.autorizacoes[referencia] = a
+	}
+	if a.encerrada {
+		return nil, ErrAutorizacaoEncerrada
+	}
+	return a, nil
+}
+
+// valorDaReferencia lê o valor em centavos de "local-<aleatório>-<centavos>"
+func valorDaReferencia(referencia string) (models.Dinheiro, bool) {
+	resto, ok := strings.CutPrefix(referencia, "local-")
+	if !ok {
+		return 0, false
+	}
+	_, centavos, ok := strings.Cut(resto, "-")
+	if !ok {
+		return 0, false
+	}
+	c, err := strconv.ParseInt(centavos, 10, 64)
+	if err != nil || c < 0 {
+		return 0, false
+	}
+	return models.Centavos(c), true
+}
@@ -0,0 +1,54 @@
+package servicos
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+func TestGatewayLocal(t *testing.T) {
+	g := NovoGatewayLocal()
+	ref1, err := g.PreAutorizar(models.Reais(500), "caução")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref2, _ := g.PreAutorizar(models.Reais(500), "caução")
+	if ref1 == ref2 {
+		t.Fatalf("referências repetidas: %s", ref1)
+	}
+
+	if err := g.Capturar(ref1, models.Reais(501)); !errors.Is(err, ErrValorAcimaAutorizado) {
+		t.Errorf("captura acima do autorizado: %v", err)
+	}
+	if err := g.Capturar(ref1, models.Reais(200)); err != nil {
+		t.Errorf("captura: %v", err)
+	}
+	if err := g.Liberar(ref1); !errors.Is(err, ErrAutorizacaoEncerrada) {
+		t.Errorf("liberar depois de capturar: %v", err)
+	}
+	if err := g.Liberar("local-000001"); !errors.Is(err, ErrAutorizacaoInexistente) {
+		t.Errorf("referência desconhecida: %v", err)
+	}
+}
+
+// Um novo GatewayLocal simula o servidor reiniciado: as cauções abertas antes continuam válidas
+func TestGatewayLocalDepoisDeReiniciar(t *testing.T) {
+	ref, _ := NovoGatewayLocal().PreAutorizar(models.Centavos(75050), "caução")
+
+	g := NovoGatewayLocal()
+	if err := g.Capturar(ref, models.Centavos(75051)); !errors.Is(err, ErrValorAcimaAutorizado) {
+		t.Errorf("o valor autorizado deveria vir da referência: %v", err)
+	}
+	if err := g.Capturar(ref, models.Centavos(75050)); err != nil {
+		t.Errorf("captura após reiniciar: %v", err)
+	}
+	if err := g.Capturar(ref, models.Centavos(1)); !errors.Is(err, ErrAutorizacaoEncerrada) {
+		t.Errorf("segunda captura: %v", err)
+	}
+
+	ref, _ = NovoGatewayLocal().PreAutorizar(models.Reais(100), "caução")
+	if err := NovoGatewayLocal().Liberar(ref); err != nil {
+		t.Errorf("liberação após reiniciar: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package servicos
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// NamespaceABRASF é o namespace do leiaute nacional de NFS-e (versão 2.04)
+const NamespaceABRASF = "http://www.abrasf.org.br/nfse.xsd"
+
+// RPS reúne o que vai no recibo provisório de serviços enviado à prefeitura
+type RPS struct {
+	Nota          models.NotaFiscal
+	Aliquota      models.AliquotaISS
+	Prestador     models.PrestadorNFSe
+	Tomador       models.Cliente
+	Discriminacao string
+}
+
+// XML monta o GerarNfseEnvio com a declaração de prestação de serviço assinada
+func (r RPS) XML(c Certificado) ([]byte, error) {
+	data := r.Nota.DataEmissao.In(models.Fuso).Format("2006-01-02")
+	codigoMunicipio := models.SomenteDigitos(r.Nota.CodigoMunicipio)
+
+	tomador := Elemento("TomadorServico")
+	switch doc := models.SomenteDigitos(r.Tomador.CPFCNPJ); len(doc) {
+	case 11:
+		tomador.Filhos = append(tomador.Filhos, Elemento("IdentificacaoTomador", Elemento("CpfCnpj", Texto("Cpf", doc))))
+	case 14:
+		tomador.Filhos = append(tomador.Filhos, Elemento("IdentificacaoTomador", Elemento("CpfCnpj", Texto("Cnpj", doc))))
+	}
+	tomador.Filhos = append(tomador.Filhos, Texto("RazaoSocial", r.Tomador.Nome))
+	contato := Elemento("Contato")
+	if tel := models.SomenteDigitos(r.Tomador.Telefone); tel != "" {
+		contato.Filhos = append(contato.Filhos, Texto("Telefone", tel))
+	}
+	if r.Tomador.Email != "" {
+		contato.Filhos = append(contato.Filhos, Texto("Email", r.Tomador.Email))
+	}
+	if len(contato.Filhos) > 0 {
+		tomador.Filhos = append(tomador.Filhos, contato)
+	}
+
+	servico := Elemento("Servico",
+		Elemento("Valores",
+			Texto("ValorServicos", r.Nota.ValorServicos.String()),
+			Texto("ValorIss", r.Nota.ValorISS.String()),
+			Texto("Aliquota", strconv.FormatFloat(r.Nota.Aliquota, 'f', 2, 64)),
+		),
+		Texto("IssRetido", "2"),
+		Texto("ItemListaServico", r.Aliquota.ItemListaServico),
+	)
+	if r.Aliquota.CodigoTributacao != "" {
+		servico.Filhos = append(servico.Filhos, Texto("CodigoTributacaoMunicipio", r.Aliquota.CodigoTributacao))
+	}
+	servico.Filhos = append(servico.Filhos,
+		Texto("Discriminacao", r.Discriminacao),
+		Texto("CodigoMunicipio", codigoMunicipio),
+		Texto("ExigibilidadeISS", "1"),
+		Texto("MunicipioIncidencia", codigoMunicipio),
+	)
+
+	inf := Elemento("InfDeclaracaoPrestacaoServico",
+		Elemento("Rps",
+			Elemento("IdentificacaoRps",
+				Texto("Numero", strconv.Itoa(r.Nota.NumeroRPS)),
+				Texto("Serie", r.Nota.SerieRPS),
+				Texto("Tipo", "1"),
+			),
+			Texto("DataEmissao", data),
+			Texto("Status", "1"),
+		),
+		Texto("Competencia", data),
+		servico,
+		Elemento("Prestador",
+			Elemento("CpfCnpj", Texto("Cnpj", models.SomenteDigitos(r.Prestador.CNPJ))),
+			Texto("InscricaoMunicipal", r.Prestador.InscricaoMunicipal),
+		),
+		tomador,
+		Texto("OptanteSimplesNacional", "2"),
+		Texto("IncentivoFiscal", "2"),
+	).Atributo("Id", fmt.Sprintf("rps%d", r.Nota.NumeroRPS))
+
+	assinatura, err := c.Assinar(inf, NamespaceABRASF)
+	if err != nil {
+		return nil, err
+	}
+	envio := Elemento("GerarNfseEnvio", Elemento("Rps", inf, assinatura))
+	envio.Namespace = NamespaceABRASF
+	return envio.Bytes(), nil
+}
+
+// RetornoNFSe são os dados da NFS-e gerada pela prefeitura
+type RetornoNFSe struct {
+	Numero            string
+	CodigoVerificacao string
+	Protocolo         string
+}
+
+// RejeicaoNFSe é a recusa do RPS pela prefeitura (dados inválidos), diferente de falha de comunicação
+type RejeicaoNFSe struct {
+	Codigo   string
+	Mensagem string
+}
+
+func (e RejeicaoNFSe) Error() string {
+	return e.Codigo + " - " + e.Mensagem
+}
+
+// AutoridadeFiscal abstrai o webservice da prefeitura que converte o RPS em NFS-e.
+// RPS já convertido volta com a mesma NFS-e, então reenviar é seguro.
+type AutoridadeFiscal interface {
+	Transmitir(xml []byte) (RetornoNFSe, error)
+}
+
+// AutoridadeLocal confere a assinatura e os campos obrigatórios e numera as notas como a
+// prefeitura faria. Serve para desenvolvimento enquanto não há integração com a prefeitura.
+// As notas emitidas ficam num arquivo do diretório, para que a numeração continue e um RPS
+// reenviado devolva a mesma NFS-e depois de reiniciar o servidor.
+type AutoridadeLocal struct {
+	mu       sync.Mutex
+	arquivo  string
+	ultimas  map[int]int            // último número emitido em cada ano
+	emitidas map[string]RetornoNFSe // prestador/série/número do RPS
+}
+
+// notaEmitidaLocal é uma linha do arquivo de notas da AutoridadeLocal
+type notaEmitidaLocal struct {
+	RPS string `json:"rps"` // prestador/série/número
+	RetornoNFSe
+}
+
+// NovaAutoridadeLocal carrega as notas já emitidas de diretorio/emitidas.jsonl (criado na
+// primeira emissão)
+func NovaAutoridadeLocal(diretorio string) (*AutoridadeLocal, error) {
+	a := &AutoridadeLocal{
+		arquivo:  filepath.Join(diretorio, "emitidas.jsonl"),
+		ultimas:  map[int]int{},
+		emitidas: map[string]RetornoNFSe{},
+	}
+	if err := os.MkdirAll(diretorio, 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(a.arquivo)
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	linhas := bufio.NewScanner(f)
+	for n := 1; linhas.Scan(); n++ {
+		var nota notaEmitidaLocal
+		if err := json.Unmarshal(linhas.Bytes(), &nota); err != nil {
+			return nil, fmt.Errorf("%s, linha %d: %w", a.arquivo, n, err)
+		}
+		ano, seq, err := partesNumeroNFSe(nota.Numero)
+		if err != nil {
+			return nil, fmt.Errorf("%s, linha %d: %w", a.arquivo, n, err)
+		}
+		a.ultimas[ano] = max(a.ultimas[ano], seq)
+		a.emitidas[nota.RPS] = nota.RetornoNFSe
+	}
+	return a, linhas.Err()
+}
+
+// partesNumeroNFSe separa o ano e o sequencial do número da NFS-e (AAAA seguido de 6 dígitos)
+func partesNumeroNFSe(numero string) (ano, seq int, err error) {
+	if len(numero) != 10 {
+		return 0, 0, fmt.Errorf("número de NFS-e inválido: %q", numero)
+	}
+	if ano, err = strconv.Atoi(numero[:4]); err != nil {
+		return 0, 0, fmt.Errorf("número de NFS-e inválido: %q", numero)
+	}
+	if seq, err = strconv.Atoi(numero[4:]); err != nil {
+		return 0, 0, fmt.Errorf("número de NFS-e inválido: %q", numero)
+	}
+	return ano, seq, nil
+}
+
+// registrar grava a nota no arquivo antes de ela ser devolvida a quem transmitiu
+func (a *AutoridadeLocal) registrar(nota notaEmitidaLocal) error {
+	linha, err := json.Marshal(nota)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(a.arquivo, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(linha, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (a *AutoridadeLocal) Transmitir(xml []byte) (RetornoNFSe, error) {
+	doc, err := LerXML(xml)
+	if err != nil {
+		return RetornoNFSe{}, RejeicaoNFSe{Codigo: "E1", Mensagem: "XML mal formado: " + err.Error()}
+	}
+	if doc.Nome != "GerarNfseEnvio" || doc.Namespace != NamespaceABRASF {
+		return RetornoNFSe{}, RejeicaoNFSe{Codigo: "E2", Mensagem: "Esperado GerarNfseEnvio no namespace ABRASF"}
+	}
+	if _, err := VerificarAssinatura(doc); err != nil {
+		return RetornoNFSe{}, RejeicaoNFSe{Codigo: "E3", Mensagem: err.Error()}
+	}
+
+	inf := filho(filho(doc, "Rps"), "InfDeclaracaoPrestacaoServico")
+	texto := func(caminho ...string) string {
+		no := inf
+		for _, nome := range caminho {
+			no = filho(no, nome)
+		}
+		if no == nil {
+			return ""
+		}
+		return strings.TrimSpace(no.Texto)
+	}
+	numero, serie := texto("Rps", "IdentificacaoRps", "Numero"), texto("Rps", "IdentificacaoRps", "Serie")
+	cnpj := texto("Prestador", "CpfCnpj", "Cnpj")
+	valor, errValor := models.ParseDinheiro(texto("Servico", "Valores", "ValorServicos"))
+	aliquota, errAliquota := strconv.ParseFloat(texto("Servico", "Valores", "Aliquota"), 64)
+	tomador := texto("TomadorServico", "IdentificacaoTomador", "CpfCnpj", "Cpf") + texto("TomadorServico", "IdentificacaoTomador", "CpfCnpj", "Cnpj")
+
+	switch {
+	case numero == "" || serie == "":
+		return RetornoNFSe{}, RejeicaoNFSe{Codigo: "E10", Mensagem: "Identificação do RPS não informada"}
+	case !models.ValidarCNPJ(cnpj) || texto("Prestador", "InscricaoMunicipal") == "":
+		return RetornoNFSe{}, RejeicaoNFSe{Codigo: "E20", Mensagem: "CNPJ ou inscrição municipal do prestador inválidos"}
+	case !models.ValidarCPF(tomador) && !models.ValidarCNPJ(tomador):
+		return RetornoNFSe{}, RejeicaoNFSe{Codigo: "E30", Mensagem: "CPF/CNPJ do tomador inválido ou não informado"}
+	case errValor != nil || valor <= 0:
+		return RetornoNFSe{}, RejeicaoNFSe{Codigo: "E40", Mensagem: "Valor dos serviços deve ser maior que zero"}
+	case errAliquota != nil || aliquota < 2 || aliquota > 5:
+		return RetornoNFSe{}, RejeicaoNFSe{Codigo: "E41", Mensagem: "Alíquota do ISS fora do intervalo de 2% a 5%"}
+	case len(texto("Servico", "CodigoMunicipio")) != 7:
+		return RetornoNFSe{}, RejeicaoNFSe{Codigo: "E50", Mensagem: "Código do município inválido"}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	chave := cnpj + "/" + serie + "/" + numero
+	if r, ok := a.emitidas[chave]; ok {
+		return r, nil
+	}
+	ano := time.Now().Year()
+	seq := a.ultimas[ano] + 1
+	codigo := make([]byte, 4)
+	rand.Read(codigo)
+	r := RetornoNFSe{
+		Numero:            fmt.Sprintf("%d%06d", ano, seq),
+		CodigoVerificacao: strings.ToUpper(hex.EncodeToString(codigo)),
+		Protocolo:         fmt.Sprintf("local-%d-%08d", ano, seq),
+	}
+	if err := a.registrar(notaEmitidaLocal{RPS: chave, RetornoNFSe: r}); err != nil {
+		return RetornoNFSe{}, fmt.Errorf("prefeitura local: %w", err)
+	}
+	a.ultimas[ano] = seq
+	a.emitidas[chave] = r
+	log.Printf("[nfse] RPS %s série %s convertido na NFS-e %s (%s)", numero, serie, r.Numero, valor.Formatar())
+	return r, nil
+}
@@ -0,0 +1,84 @@
+package servicos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// rpsDeTeste monta o XML assinado de um RPS válido para a AutoridadeLocal
+func rpsDeTeste(t *testing.T, c Certificado, numero int) []byte {
+	t.Helper()
+	rps := RPS{
+		Nota: models.NotaFiscal{
+			NumeroRPS:       numero,
+			SerieRPS:        "A",
+			DataEmissao:     time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+			ValorServicos:   models.Reais(240),
+			Aliquota:        5,
+			ValorISS:        models.Reais(12),
+			CodigoMunicipio: "3550308",
+		},
+		Aliquota:      models.AliquotaISS{ItemListaServico: "10.05"},
+		Prestador:     models.PrestadorNFSe{CNPJ: "11.222.333/0001-81", InscricaoMunicipal: "123456"},
+		Tomador:       models.Cliente{Nome: "Bia", CPFCNPJ: "529.982.247-25"},
+		Discriminacao: "Locação de veículo",
+	}
+	xml, err := rps.XML(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xml
+}
+
+// Uma nova AutoridadeLocal no mesmo diretório simula o servidor reiniciado: a numeração
+// continua e o RPS já convertido volta com a mesma NFS-e
+func TestAutoridadeLocalDepoisDeReiniciar(t *testing.T) {
+	c, err := CertificadoLocal(t.TempDir(), "Teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diretorio := t.TempDir()
+
+	a, err := NovaAutoridadeLocal(diretorio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	primeira, err := a.Transmitir(rpsDeTeste(t, c, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	segunda, err := a.Transmitir(rpsDeTeste(t, c, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primeira.Numero == segunda.Numero {
+		t.Fatalf("números repetidos: %s", primeira.Numero)
+	}
+
+	a, err = NovaAutoridadeLocal(diretorio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reenviada, err := a.Transmitir(rpsDeTeste(t, c, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reenviada != primeira {
+		t.Errorf("RPS reenviado depois de reiniciar: %+v, esperado %+v", reenviada, primeira)
+	}
+	terceira, err := a.Transmitir(rpsDeTeste(t, c, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, anterior := range []RetornoNFSe{primeira, segunda} {
+		if terceira.Numero == anterior.Numero {
+			t.Errorf("número %s emitido de novo depois de reiniciar", terceira.Numero)
+		}
+	}
+	_, seqSegunda, _ := partesNumeroNFSe(segunda.Numero)
+	if _, seq, err := partesNumeroNFSe(terceira.Numero); err != nil || seq != seqSegunda+1 {
+		t.Errorf("numeração não continuou: %s depois de %s", terceira.Numero, segunda.Numero)
+	}
+}
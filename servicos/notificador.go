@@ -0,0 +1,17 @@
+package servicos
+
+import "log"
+
+// Notificador envia mensagens aos usuários do sistema (e-mail, SMS, etc.)
+type Notificador interface {
+	Notificar(destinatario, assunto, mensagem string) error
+}
+
+// NotificadorLog apenas escreve as mensagens no log do servidor.
+// Útil em desenvolvimento, enquanto não há um serviço de e-mail configurado.
+type NotificadorLog struct{}
+
+func (NotificadorLog) Notificar(destinatario, assunto, mensagem string) error {
+	log.Printf("[notificação] para=%s assunto=%q\n%s", destinatario, assunto, mensagem)
+	return nil
+}
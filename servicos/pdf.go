@@ -0,0 +1,186 @@
+package servicos
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Documentos em PDF (contratos, recibos) gerados sem dependências externas: páginas A4 com
+// as fontes padrão Courier e Courier-Bold, que todo leitor de PDF tem. Com fonte de largura
+// fixa a quebra de linha é exata sem tabela de métricas.
+const (
+	pdfLargura    = 595 // A4 em pontos
+	pdfAltura     = 842
+	pdfMargem     = 56
+	pdfCorpo      = 10 // tamanho da fonte do texto
+	pdfTitulo     = 12
+	pdfEntrelinha = 14
+)
+
+// linhaPDF é uma linha já quebrada na largura da página
+type linhaPDF struct {
+	texto   string
+	negrito bool
+}
+
+// PDF acumula o texto e monta o arquivo em Bytes. Texto com várias linhas é escrito
+// linha a linha: "# " no início marca um título, linha em branco vira espaço e as
+// demais são quebradas por palavra na largura da página.
+type PDF struct {
+	linhas []linhaPDF
+}
+
+func NovoPDF() *PDF {
+	return &PDF{}
+}
+
+// Escrever acrescenta o texto ao documento
+func (p *PDF) Escrever(texto string) {
+	for _, linha := range strings.Split(strings.ReplaceAll(texto, "\r\n", "\n"), "\n") {
+		linha = strings.TrimRight(linha, " \t")
+		if titulo, ok := strings.CutPrefix(linha, "# "); ok {
+			for _, l := range quebrarLinha(titulo, caracteresPorLinha(pdfTitulo)) {
+				p.linhas = append(p.linhas, linhaPDF{texto: l, negrito: true})
+			}
+			continue
+		}
+		for _, l := range quebrarLinha(linha, caracteresPorLinha(pdfCorpo)) {
+			p.linhas = append(p.linhas, linhaPDF{texto: l})
+		}
+	}
+}
+
+// caracteresPorLinha: a Courier tem 600 milésimos de em por caractere
+func caracteresPorLinha(tamanho int) int {
+	return (pdfLargura - 2*pdfMargem) * 1000 / (600 * tamanho)
+}
+
+// quebrarLinha divide por palavras; o recuo inicial da linha é repetido nas continuações
+func quebrarLinha(linha string, largura int) []string {
+	if linha == "" {
+		return []string{""}
+	}
+	recuo := linha[:len(linha)-len(strings.TrimLeft(linha, " "))]
+	if len(recuo) > largura/2 {
+		recuo = ""
+	}
+	var linhas []string
+	atual := recuo
+	for _, palavra := range strings.Fields(linha) {
+		for len([]rune(recuo+palavra)) > largura { // palavra maior que a linha
+			if atual != recuo {
+				linhas = append(linhas, atual)
+			}
+			r := []rune(palavra)
+			corte := largura - len([]rune(recuo))
+			linhas = append(linhas, recuo+string(r[:corte]))
+			palavra, atual = string(r[corte:]), recuo
+		}
+		switch {
+		case atual == recuo:
+			atual += palavra
+		case len([]rune(atual))+1+len([]rune(palavra)) <= largura:
+			atual += " " + palavra
+		default:
+			linhas = append(linhas, atual)
+			atual = recuo + palavra
+		}
+	}
+	return append(linhas, atual)
+}
+
+// Bytes monta o arquivo PDF, numerando as páginas no rodapé
+func (p *PDF) Bytes() []byte {
+	var paginas [][]linhaPDF
+	var pagina []linhaPDF
+	y := pdfAltura - pdfMargem
+	for _, l := range p.linhas {
+		altura := pdfEntrelinha
+		if l.negrito {
+			altura = pdfEntrelinha + 4
+		}
+		if y-altura < pdfMargem {
+			if l.texto == "" { // linha em branco no fim da página não abre outra
+				continue
+			}
+			paginas, pagina, y = append(paginas, pagina), nil, pdfAltura-pdfMargem
+		}
+		pagina = append(pagina, l)
+		y -= altura
+	}
+	paginas = append(paginas, pagina)
+
+	// Objetos: 1 catálogo, 2 árvore de páginas, 3 e 4 fontes, depois página e conteúdo de cada página
+	var objetos []string
+	kids := make([]string, len(paginas))
+	for i := range paginas {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objetos = append(objetos,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(paginas)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+	)
+	for i, linhas := range paginas {
+		conteudo := conteudoPagina(linhas, fmt.Sprintf("Página %d de %d", i+1, len(paginas)))
+		objetos = append(objetos,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfLargura, pdfAltura, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(conteudo), conteudo),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objetos))
+	for i, obj := range objetos {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objetos)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objetos)+1, xref)
+	return buf.Bytes()
+}
+
+func conteudoPagina(linhas []linhaPDF, rodape string) string {
+	var b strings.Builder
+	y := pdfAltura - pdfMargem
+	for _, l := range linhas {
+		fonte, tamanho, altura := "F1", pdfCorpo, pdfEntrelinha
+		if l.negrito {
+			fonte, tamanho, altura = "F2", pdfTitulo, pdfEntrelinha+4
+		}
+		y -= altura
+		if l.texto != "" {
+			fmt.Fprintf(&b, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", fonte, tamanho, pdfMargem, y, textoPDF(l.texto))
+		}
+	}
+	fmt.Fprintf(&b, "BT /F1 8 Tf %d %d Td (%s) Tj ET", pdfMargem, pdfMargem/2, textoPDF(rodape))
+	return b.String()
+}
+
+// textoPDF converte para WinAnsi (cp1252) e escapa os delimitadores de string do PDF
+func textoPDF(s string) string {
+	especiais := map[rune]byte{'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			b.WriteByte(byte(r))
+		case especiais[r] != 0:
+			b.WriteByte(especiais[r])
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
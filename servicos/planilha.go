@@ -0,0 +1,226 @@
+package servicos
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/models"
+)
+
+// Planilha escreve linhas à medida que chegam; Fechar conclui o arquivo
+type Planilha interface {
+	Cabecalho(colunas []string) error
+	Linha(celulas []any) error
+	Fechar() error
+}
+
+// PlanilhaCSV gera CSV no formato que o Excel em português abre direto: UTF-8 com BOM,
+// separador ";" e vírgula decimal
+type PlanilhaCSV struct {
+	w *csv.Writer
+}
+
+func NovaPlanilhaCSV(w io.Writer) (*PlanilhaCSV, error) {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	c := csv.NewWriter(w)
+	c.Comma = ';'
+	return &PlanilhaCSV{w: c}, nil
+}
+
+func (p *PlanilhaCSV) Cabecalho(colunas []string) error {
+	return p.w.Write(colunas)
+}
+
+func (p *PlanilhaCSV) Linha(celulas []any) error {
+	campos := make([]string, len(celulas))
+	for i, c := range celulas {
+		switch v := c.(type) {
+		case models.Dinheiro:
+			campos[i] = strings.Replace(v.String(), ".", ",", 1)
+		case string:
+			campos[i] = neutralizarFormula(v)
+		default:
+			campos[i] = textoCelula(v)
+		}
+	}
+	return p.w.Write(campos)
+}
+
+func (p *PlanilhaCSV) Fechar() error {
+	p.w.Flush()
+	return p.w.Error()
+}
+
+// neutralizarFormula impede que um texto digitado pelo usuário (nome, endereço) seja
+// interpretado como fórmula ao abrir o CSV
+func neutralizarFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func textoCelula(c any) string {
+	switch v := c.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		if v {
+			return "sim"
+		}
+		return "não"
+	case models.Dinheiro:
+		return v.String()
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.In(models.Fuso).Format("02/01/2006 15:04")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// PlanilhaXLSX gera uma pasta de trabalho do Excel com uma única planilha. O zip é escrito
+// direto na saída e as linhas vão para o XML da planilha conforme chegam.
+type PlanilhaXLSX struct {
+	z     *zip.Writer
+	folha *bufio.Writer
+}
+
+// Estilos de célula definidos em estilosXLSX
+const (
+	estiloMoeda     = 1
+	estiloData      = 2
+	estiloCabecalho = 3
+)
+
+const nsPlanilha = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+
+const estilosXLSX = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="` + nsPlanilha + `"><numFmts count="1"><numFmt numFmtId="164" formatCode="dd/mm/yyyy hh:mm"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+
+func NovaPlanilhaXLSX(w io.Writer, nome string) (*PlanilhaXLSX, error) {
+	var nomeXML strings.Builder
+	xml.EscapeText(&nomeXML, []byte(nome))
+	partes := []struct{ caminho, conteudo string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="` + nsPlanilha + `" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="` + nomeXML.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`},
+		{"xl/styles.xml", estilosXLSX},
+	}
+
+	z := zip.NewWriter(w)
+	for _, p := range partes {
+		f, err := z.Create(p.caminho)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, p.conteudo); err != nil {
+			return nil, err
+		}
+	}
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	folha := bufio.NewWriter(f)
+	folha.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="` + nsPlanilha + `"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" state="frozen"/></sheetView></sheetViews><sheetData>`)
+	return &PlanilhaXLSX{z: z, folha: folha}, nil
+}
+
+func (p *PlanilhaXLSX) Cabecalho(colunas []string) error {
+	p.folha.WriteString("<row>")
+	for _, c := range colunas {
+		p.textoXLSX(c, estiloCabecalho)
+	}
+	_, err := p.folha.WriteString("</row>")
+	return err
+}
+
+func (p *PlanilhaXLSX) Linha(celulas []any) error {
+	p.folha.WriteString("<row>")
+	for _, c := range celulas {
+		switch v := c.(type) {
+		case int:
+			fmt.Fprintf(p.folha, "<c><v>%d</v></c>", v)
+		case bool:
+			if v {
+				p.folha.WriteString(`<c t="b"><v>1</v></c>`)
+			} else {
+				p.folha.WriteString(`<c t="b"><v>0</v></c>`)
+			}
+		case models.Dinheiro:
+			fmt.Fprintf(p.folha, `<c s="%d"><v>%s</v></c>`, estiloMoeda, v.String())
+		case time.Time:
+			if v.IsZero() {
+				p.folha.WriteString("<c/>")
+				continue
+			}
+			fmt.Fprintf(p.folha, `<c s="%d"><v>%s</v></c>`, estiloData, strconv.FormatFloat(serialExcel(v), 'f', -1, 64))
+		default:
+			p.textoXLSX(textoCelula(v), 0)
+		}
+	}
+	_, err := p.folha.WriteString("</row>")
+	return err
+}
+
+func (p *PlanilhaXLSX) textoXLSX(texto string, estilo int) {
+	if estilo != 0 {
+		fmt.Fprintf(p.folha, `<c s="%d" t="inlineStr"><is><t xml:space="preserve">`, estilo)
+	} else {
+		p.folha.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	}
+	xml.EscapeText(p.folha, []byte(texto))
+	p.folha.WriteString("</t></is></c>")
+}
+
+func (p *PlanilhaXLSX) Fechar() error {
+	p.folha.WriteString("</sheetData></worksheet>")
+	if err := p.folha.Flush(); err != nil {
+		return err
+	}
+	return p.z.Close()
+}
+
+// serialExcel converte para o número de dias desde 30/12/1899 usado pelo Excel, no horário local
+func serialExcel(t time.Time) float64 {
+	local := t.In(models.Fuso)
+	parede := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, time.UTC)
+	return parede.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Kyutz/aluguel-carros-go/handlers"
+	"github.com/Kyutz/aluguel-carros-go/models"
+	"github.com/Kyutz/aluguel-carros-go/servicos"
+)
+
+// iniciarTarefasDiarias roda as rotinas de manutenção do sistema na subida do servidor
+// e depois a cada 24 horas. Deve ser chamada em uma goroutine.
+func iniciarTarefasDiarias(db *sql.DB, notificador servicos.Notificador) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		alertarVencimentosDocumentos(db, notificador)
+		<-ticker.C
+	}
+}
+
+// iniciarRevisaoListaEspera expira as ofertas vencidas da lista de espera e repassa as vagas
+// para os próximos da fila. Roda a cada poucos minutos porque as ofertas valem por minutos.
+func iniciarRevisaoListaEspera(db *sql.DB, notificador servicos.Notificador) {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		handlers.OferecerVagasEspera(db, notificador, "")
+		<-ticker.C
+	}
+}
+
+// iniciarVarreduraReservas expira a cada minuto as reservas temporárias vencidas e oferece as
+// vagas liberadas à lista de espera
+func iniciarVarreduraReservas(db *sql.DB, notificador servicos.Notificador) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		categorias, err := models.ExpirarReservasTemporarias(db)
+		if err != nil {
+			log.Printf("Erro ao expirar reservas temporárias: %v", err)
+			continue
+		}
+		for _, categoria := range categorias {
+			handlers.OferecerVagasEspera(db, notificador, categoria)
+		}
+	}
+}
+
+// iniciarEmissaoNotasFiscais emite a cada hora as notas das locações finalizadas desde a última
+// rodada e reenvia os RPS que ficaram pendentes por falha de comunicação
+func iniciarEmissaoNotasFiscais(db *sql.DB, arquivos servicos.Armazenamento, autoridade servicos.AutoridadeFiscal, certificado servicos.Certificado) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		if autorizadas, erros := handlers.EmitirNotasPendentes(db, arquivos, autoridade, certificado); autorizadas > 0 || len(erros) > 0 {
+			log.Printf("Emissão de notas fiscais: %d autorizadas, %d com erro", autorizadas, len(erros))
+		}
+	}
+}
+
+// alertarVencimentosDocumentos avisa a equipe da frota sobre documentos vencidos ou a vencer.
+// Cada documento é avisado uma única vez; um documento novo do mesmo tipo gera novo ciclo.
+func alertarVencimentosDocumentos(db *sql.DB, notificador servicos.Notificador) {
+	dias, err := models.ConfigInt(db, models.ConfigDocumentosMargemDias)
+	if err != nil {
+		log.Printf("Erro ao ler configuração de documentos: %v", err)
+		return
+	}
+	email, err := models.ConfigTexto(db, models.ConfigEmailFrota)
+	if err != nil {
+		log.Printf("Erro ao ler configuração de e-mail da frota: %v", err)
+		return
+	}
+	vencimentos, err := models.VencimentosNaoAlertados(db, time.Now(), dias)
+	if err != nil {
+		log.Printf("Erro ao buscar vencimentos de documentos: %v", err)
+		return
+	}
+	if len(vencimentos) == 0 {
+		return
+	}
+
+	var linhas []string
+	for _, v := range vencimentos {
+		situacao := fmt.Sprintf("vence em %d dia(s)", v.Dias)
+		if v.Dias < 0 {
+			situacao = "VENCIDO"
+		}
+		linhas = append(linhas, fmt.Sprintf("%s - %s nº %s, validade %s (%s)",
+			v.Placa, v.Tipo, v.Numero, v.Validade.Format("02/01/2006"), situacao))
+	}
+	assunto := fmt.Sprintf("%d documento(s) de carros vencendo", len(vencimentos))
+	if err := notificador.Notificar(email, assunto, strings.Join(linhas, "\n")); err != nil {
+		log.Printf("Erro ao enviar alerta de documentos: %v", err)
+		return
+	}
+	for _, v := range vencimentos {
+		if err := models.MarcarDocumentoAlertado(db, v.ID); err != nil {
+			log.Printf("Erro ao marcar documento %d como alertado: %v", v.ID, err)
+		}
+	}
+}